@@ -0,0 +1,86 @@
+package apikey
+
+import (
+	"cernunnos/cmd/commands"
+	"cernunnos/commands/apikey"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/usecase/interactors"
+	"cernunnos/internal/usecase/repository"
+	"cernunnos/internal/usecase/repository/apikeys"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands.Register(&cli.Command{
+		Name:  "apikey",
+		Usage: "manage API keys",
+		Flags: commands.ConfigFlags(),
+		Subcommands: []*cli.Command{
+			{
+				Name:  "create",
+				Usage: "create a key. The key is printed once",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "name",
+						Usage:    "client name",
+						Required: true,
+					},
+					&cli.StringSliceFlag{
+						Name:     "scopes",
+						Usage:    "read, reserve, release or admin",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return run(c, func(command *apikey.APIKeyCommand) error {
+						return command.Create(c.Context, c.String("name"), c.StringSlice("scopes"))
+					})
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "list keys, including revoked ones",
+				Action: func(c *cli.Context) error {
+					return run(c, func(command *apikey.APIKeyCommand) error {
+						return command.List(c.Context)
+					})
+				},
+			},
+			{
+				Name:  "revoke",
+				Usage: "revoke a key",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "id",
+						Required: true,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					return run(c, func(command *apikey.APIKeyCommand) error {
+						return command.Revoke(c.Context, c.String("id"))
+					})
+				},
+			},
+		},
+	})
+}
+
+func run(c *cli.Context, action func(command *apikey.APIKeyCommand) error) error {
+	cfg, err := commands.LoadConfig(c)
+	if err != nil {
+		return err
+	}
+
+	db, cleanup, err := repository.ProvideDatabaseConnection(cfg)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	log := logger.NewLogger(logger.MapLevel(cfg.LogLevel))
+	interactor := interactors.NewAPIKeyInteractor(log, apikeys.NewRepository(db))
+
+	return action(apikey.NewAPIKeyCommand(os.Stdout, interactor))
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"cernunnos/internal/pkg/config"
+	"cernunnos/pkg/client"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ClientFlags configure commands calling a running server
+func ClientFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "server-url",
+			Usage:   "URL of a running server",
+			Value:   "http://localhost:8080",
+			EnvVars: []string{config.EnvPrefix + "SERVER_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "api-key",
+			EnvVars: []string{config.EnvPrefix + "API_KEY"},
+		},
+		&cli.StringFlag{
+			Name:    "token",
+			Usage:   "bearer token. Overrides api key",
+			EnvVars: []string{config.EnvPrefix + "TOKEN"},
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "time to complete the command, including retries",
+			Value: 30 * time.Second,
+		},
+		&cli.StringFlag{
+			Name:    "output",
+			Aliases: []string{"o"},
+			Usage:   "table or json",
+			Value:   "table",
+		},
+	}
+}
+
+// NewClient builds a client of the server passed with ClientFlags
+func NewClient(c *cli.Context) (*client.Client, error) {
+	apiClient, err := client.New(c.String("server-url"), client.Options{
+		APIKey: c.String("api-key"),
+		Token:  c.String("token"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error create client. %w", err)
+	}
+
+	return apiClient, nil
+}
@@ -0,0 +1,319 @@
+package commands
+
+import (
+	"cernunnos/internal/pkg/config"
+	"fmt"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// flagBinding overrides a config value with a flag, if the flag is set
+type flagBinding struct {
+	flag  cli.Flag
+	apply func(c *cli.Context, cfg *config.Config)
+}
+
+var bindings = []flagBinding{
+	{
+		flag:  &cli.StringFlag{Name: "address"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Address = c.String("address") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "grpc-address",
+			Usage: "gRPC API address. Empty address disables gRPC API",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.GRPC.Address = c.String("grpc-address") },
+	},
+	{
+		flag:  &cli.BoolFlag{Name: "grpc-reflection"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.GRPC.Reflection = c.Bool("grpc-reflection") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "log-level",
+			Usage: "debug, info, warn or error",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.LogLevel = c.String("log-level") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "db-host"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.DatabaseHost = c.String("db-host") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "db-user"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.DatabaseUser = c.String("db-user") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "db-password"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.DatabasePassword = c.String("db-password") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "db-name"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.Name = c.String("db-name") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "db-replica-host",
+			Usage: "read replica for listings. Uses primary credentials and options",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.ReplicaHost = c.String("db-replica-host") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "db-sslmode",
+			Usage: "disable, require, verify-ca or verify-full",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.SSLMode = c.String("db-sslmode") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "db-sslrootcert"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.SSLRootCert = c.String("db-sslrootcert") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "db-sslcert"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.SSLCert = c.String("db-sslcert") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "db-sslkey"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.SSLKey = c.String("db-sslkey") },
+	},
+	{
+		flag: &cli.StringFlag{Name: "db-application-name"},
+		apply: func(c *cli.Context, cfg *config.Config) {
+			cfg.Database.ApplicationName = c.String("db-application-name")
+		},
+	},
+	{
+		flag: &cli.DurationFlag{
+			Name:  "db-statement-timeout",
+			Usage: "0 disables timeout",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) {
+			cfg.Database.StatementTimeout = c.Duration("db-statement-timeout")
+		},
+	},
+	{
+		flag: &cli.IntFlag{
+			Name:  "db-max-open-conns",
+			Usage: "0 disables limit",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.MaxOpenConns = c.Int("db-max-open-conns") },
+	},
+	{
+		flag:  &cli.IntFlag{Name: "db-max-idle-conns"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.MaxIdleConns = c.Int("db-max-idle-conns") },
+	},
+	{
+		flag: &cli.DurationFlag{
+			Name:  "db-conn-max-lifetime",
+			Usage: "0 disables limit",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) {
+			cfg.Database.ConnMaxLifetime = c.Duration("db-conn-max-lifetime")
+		},
+	},
+	{
+		flag: &cli.IntFlag{
+			Name:  "db-connect-attempts",
+			Usage: "database pings on startup before giving up",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Database.ConnectAttempts = c.Int("db-connect-attempts") },
+	},
+	{
+		flag: &cli.DurationFlag{Name: "db-connect-retry-delay"},
+		apply: func(c *cli.Context, cfg *config.Config) {
+			cfg.Database.ConnectRetryDelay = c.Duration("db-connect-retry-delay")
+		},
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "auth",
+			Usage: "apikey, jwt or none. Keys are managed with apikey command",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.AuthMode = c.String("auth") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "jwt-jwks-file",
+			Usage: "JWKS file with token signing public keys",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.JWT.KeysFile = c.String("jwt-jwks-file") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "jwt-secret",
+			Usage: "HMAC secret of tokens. Used if no JWKS file passed",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.JWT.Secret = c.String("jwt-secret") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "jwt-issuer"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.JWT.Issuer = c.String("jwt-issuer") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "jwt-audience"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.JWT.Audience = c.String("jwt-audience") },
+	},
+	{
+		flag:  &cli.DurationFlag{Name: "read-timeout"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Timeouts.Read = c.Duration("read-timeout") },
+	},
+	{
+		flag:  &cli.DurationFlag{Name: "write-timeout"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Timeouts.Write = c.Duration("write-timeout") },
+	},
+	{
+		flag:  &cli.DurationFlag{Name: "idle-timeout"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Timeouts.Idle = c.Duration("idle-timeout") },
+	},
+	{
+		flag: &cli.DurationFlag{
+			Name:  "shutdown-timeout",
+			Usage: "time to drain in-flight requests on SIGINT or SIGTERM",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Timeouts.Shutdown = c.Duration("shutdown-timeout") },
+	},
+	{
+		flag: &cli.Float64Flag{
+			Name:  "read-rate-limit",
+			Usage: "read requests per second per client. 0 disables limit",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.RateLimits.ReadRPS = c.Float64("read-rate-limit") },
+	},
+	{
+		flag:  &cli.IntFlag{Name: "read-rate-burst"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.RateLimits.ReadBurst = c.Int("read-rate-burst") },
+	},
+	{
+		flag: &cli.Float64Flag{
+			Name:  "write-rate-limit",
+			Usage: "reservation requests per second per client. 0 disables limit",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.RateLimits.WriteRPS = c.Float64("write-rate-limit") },
+	},
+	{
+		flag:  &cli.IntFlag{Name: "write-rate-burst"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.RateLimits.WriteBurst = c.Int("write-rate-burst") },
+	},
+	{
+		flag: &cli.DurationFlag{
+			Name:  "idempotency-ttl",
+			Usage: "time to replay responses of writes retried with the same Idempotency-Key",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.IdempotencyTTL = c.Duration("idempotency-ttl") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "trace-exporter",
+			Usage: "none, stdout, file or otlp. OTLP exporter is configured with OTEL_EXPORTER_OTLP_* env",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.TraceExporter = c.String("trace-exporter") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "trace-file",
+			Usage: "spans output file for file exporter",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.TraceFile = c.String("trace-file") },
+	},
+	{
+		flag: &cli.StringFlag{
+			Name:  "outbox-publisher",
+			Usage: "none, stdout, file or webhook. Publishes domain events written to outbox",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Outbox.Publisher = c.String("outbox-publisher") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "outbox-file"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Outbox.File = c.String("outbox-file") },
+	},
+	{
+		flag:  &cli.StringFlag{Name: "outbox-webhook-url"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Outbox.WebhookURL = c.String("outbox-webhook-url") },
+	},
+	{
+		flag: &cli.DurationFlag{Name: "outbox-poll-interval"},
+		apply: func(c *cli.Context, cfg *config.Config) {
+			cfg.Outbox.PollInterval = c.Duration("outbox-poll-interval")
+		},
+	},
+	{
+		flag:  &cli.IntFlag{Name: "outbox-batch-size"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Outbox.BatchSize = c.Int("outbox-batch-size") },
+	},
+	{
+		flag: &cli.BoolFlag{
+			Name:  "webhooks",
+			Usage: "deliver events to webhook subscriptions managed with /webhooks",
+		},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Webhooks.Enabled = c.Bool("webhooks") },
+	},
+	{
+		flag:  &cli.IntFlag{Name: "webhooks-max-attempts"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Webhooks.MaxAttempts = c.Int("webhooks-max-attempts") },
+	},
+	{
+		flag: &cli.DurationFlag{Name: "webhooks-backoff-base"},
+		apply: func(c *cli.Context, cfg *config.Config) {
+			cfg.Webhooks.BackoffBase = c.Duration("webhooks-backoff-base")
+		},
+	},
+	{
+		flag: &cli.DurationFlag{Name: "webhooks-backoff-max"},
+		apply: func(c *cli.Context, cfg *config.Config) {
+			cfg.Webhooks.BackoffMax = c.Duration("webhooks-backoff-max")
+		},
+	},
+	{
+		flag:  &cli.DurationFlag{Name: "webhooks-timeout"},
+		apply: func(c *cli.Context, cfg *config.Config) { cfg.Webhooks.Timeout = c.Duration("webhooks-timeout") },
+	},
+}
+
+// ConfigFlags are flags overriding config values. Every command accepts them.
+func ConfigFlags() []cli.Flag {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "config",
+			Usage:   "YAML or TOML config file",
+			EnvVars: []string{config.EnvPrefix + "CONFIG"},
+		},
+	}
+
+	for _, binding := range bindings {
+		flags = append(flags, binding.flag)
+	}
+
+	return flags
+}
+
+// LoadConfig builds config from defaults, config file, CERNUNNOS_* environment variables and flags,
+// in that order of precedence, and validates it
+func LoadConfig(c *cli.Context) (*config.Config, error) {
+	cfg := config.Default()
+	cfg.Version = c.App.Version
+
+	if path := c.String("config"); path != "" {
+		if err := config.LoadFile(path, &cfg); err != nil {
+			return nil, fmt.Errorf("error load config file. %w", err)
+		}
+	}
+
+	if err := config.LoadEnv(os.LookupEnv, &cfg); err != nil {
+		return nil, fmt.Errorf("error load config from environment. %w", err)
+	}
+
+	for _, binding := range bindings {
+		if c.IsSet(binding.flag.Names()[0]) {
+			binding.apply(c, &cfg)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
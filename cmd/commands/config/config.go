@@ -0,0 +1,31 @@
+package config
+
+import (
+	"cernunnos/cmd/commands"
+	"cernunnos/commands/config"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands.Register(&cli.Command{
+		Name:  "config",
+		Usage: "inspect configuration",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "print",
+				Usage: "print effective config with secrets redacted",
+				Flags: commands.ConfigFlags(),
+				Action: func(c *cli.Context) error {
+					cfg, err := commands.LoadConfig(c)
+					if err != nil {
+						return err
+					}
+
+					return config.NewPrintCommand(os.Stdout).Run(cfg)
+				},
+			},
+		},
+	})
+}
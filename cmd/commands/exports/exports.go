@@ -0,0 +1,76 @@
+package exports
+
+import (
+	"cernunnos/cmd/commands"
+	"cernunnos/commands/exports"
+	"cernunnos/commands/imports"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	"cernunnos/internal/usecase/repository"
+	exportsRepo "cernunnos/internal/usecase/repository/exports"
+	"os"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands.Register(&cli.Command{
+		Name:  "export",
+		Usage: "write a snapshot of stock of products in storages to a CSV or JSONL file",
+		Flags: slices.Concat(commands.ConfigFlags(), []cli.Flag{
+			&cli.StringFlag{
+				Name:  "file",
+				Usage: "file to write. - writes to stdout",
+				Value: "-",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "csv or jsonl. Detected by the file extension, if not passed",
+			},
+			&cli.StringSliceFlag{
+				Name:  "storage",
+				Usage: "export stock of the storage only. May be repeated",
+			},
+			&cli.StringSliceFlag{
+				Name:  "product",
+				Usage: "export stock of the product only. May be repeated",
+			},
+		}),
+		Action: func(c *cli.Context) error {
+			cfg, err := commands.LoadConfig(c)
+			if err != nil {
+				return err
+			}
+
+			db, cleanup, err := repository.ProvideDatabaseConnection(cfg)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			log := logger.NewLogger(logger.MapLevel(cfg.LogLevel))
+			command := exports.NewExportCommand(
+				interactors.NewExportInteractor(log, exportsRepo.NewRepository(db, nil)),
+				presenters.NewExportPresenter(),
+			)
+
+			name, format := c.String("file"), c.String("format")
+			if format == "" {
+				format = imports.FormatOf(name)
+			}
+
+			params := interactors.ExportStockParams{
+				StorageIds: c.StringSlice("storage"),
+				ProductIds: c.StringSlice("product"),
+			}
+
+			if name == "-" {
+				return command.Stock(c.Context, os.Stdout, format, params)
+			}
+
+			return command.StockFile(c.Context, name, format, params)
+		},
+	})
+}
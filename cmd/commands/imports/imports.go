@@ -0,0 +1,82 @@
+package imports
+
+import (
+	"cernunnos/cmd/commands"
+	"cernunnos/commands/imports"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/usecase/interactors"
+	"cernunnos/internal/usecase/repository"
+	importsRepo "cernunnos/internal/usecase/repository/imports"
+	"cernunnos/internal/usecase/repository/outbox"
+	"cernunnos/internal/usecase/repository/stock"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands.Register(&cli.Command{
+		Name:  "import",
+		Usage: "load products, storages or stock from a CSV or JSONL file. Nothing is loaded, if any row is rejected",
+		Flags: slices.Concat(commands.ConfigFlags(), []cli.Flag{
+			&cli.StringFlag{
+				Name:     "kind",
+				Usage:    "products, storages or stock",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:     "file",
+				Usage:    "file to load. - reads stdin",
+				Required: true,
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "csv or jsonl. Detected by the file extension, if not passed",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "validate and load rows, then roll them back",
+			},
+		}),
+		Action: func(c *cli.Context) error {
+			cfg, err := commands.LoadConfig(c)
+			if err != nil {
+				return err
+			}
+
+			var file io.Reader = os.Stdin
+
+			if name := c.String("file"); name != "-" {
+				f, err := os.Open(name)
+				if err != nil {
+					return fmt.Errorf("error open import file. %w", err)
+				}
+				defer f.Close()
+
+				file = f
+			}
+
+			db, cleanup, err := repository.ProvideDatabaseConnection(cfg)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+
+			log := logger.NewLogger(logger.MapLevel(cfg.LogLevel))
+			repo := importsRepo.NewRepository(
+				db,
+				outbox.NewRepository(db),
+				stock.NewRepository(db, repository.DSN(cfg, cfg.DatabaseHost)),
+			)
+
+			command := imports.NewImportCommand(os.Stdout, interactors.NewImportInteractor(log, repo))
+
+			return command.Import(
+				c.Context, c.String("kind"), c.String("format"), c.String("file"), file, c.Bool("dry-run"),
+			)
+		},
+	})
+}
@@ -0,0 +1,164 @@
+package inventory
+
+import (
+	"cernunnos/cmd/commands"
+	"cernunnos/commands/inventory"
+	"cernunnos/pkg/client"
+	"context"
+	"os"
+	"slices"
+
+	"github.com/urfave/cli/v2"
+)
+
+func init() {
+	commands.Register(&cli.Command{
+		Name:  "storages",
+		Usage: "browse storages of a running server",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list storages",
+				Flags: withClientFlags(
+					&cli.StringFlag{Name: "name-prefix"},
+					&cli.StringFlag{
+						Name:  "sort",
+						Usage: "name, available, reserved, created_at or updated_at. -name sorts descending",
+					},
+					limitFlag(),
+				),
+				Action: func(c *cli.Context) error {
+					return run(c, func(ctx context.Context, command *inventory.InventoryCommand) error {
+						return command.Storages(ctx, &client.StoragesRequest{
+							StoragesFilter: client.StoragesFilter{
+								NamePrefix: c.String("name-prefix"),
+								Sort:       c.String("sort"),
+							},
+						}, c.Int("limit"))
+					})
+				},
+			},
+		},
+	})
+
+	commands.Register(&cli.Command{
+		Name:  "products",
+		Usage: "browse products of a running server",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "list products. Products of a storage are listed with their stock in the storage",
+				Flags: withClientFlags(
+					&cli.StringFlag{Name: "storage", Usage: "storage id"},
+					&cli.StringFlag{Name: "name-prefix"},
+					&cli.BoolFlag{Name: "with-unavailable", Usage: "list products out of stock too"},
+					limitFlag(),
+				),
+				Action: func(c *cli.Context) error {
+					return run(c, func(ctx context.Context, command *inventory.InventoryCommand) error {
+						filter := client.ProductsFilter{NamePrefix: c.String("name-prefix")}
+
+						if storageId := c.String("storage"); storageId != "" {
+							return command.StorageProducts(ctx, &client.StorageProductsRequest{
+								ProductsFilter:  filter,
+								StorageId:       storageId,
+								WithUnavailable: c.Bool("with-unavailable"),
+							}, c.Int("limit"))
+						}
+
+						return command.Products(ctx, &client.ProductsRequest{
+							ProductsFilter:  filter,
+							WithUnavailable: c.Bool("with-unavailable"),
+						}, c.Int("limit"))
+					})
+				},
+			},
+		},
+	})
+
+	commands.Register(&cli.Command{
+		Name:  "reserve",
+		Usage: "reserve products for a shipping",
+		Flags: withClientFlags(append(
+			reservationFlags("storage to reserve in. Reservation is distributed between storages, if not passed"),
+			&cli.Int64Flag{Name: "amount", Required: true},
+		)...),
+		Action: func(c *cli.Context) error {
+			return run(c, func(ctx context.Context, command *inventory.InventoryCommand) error {
+				return command.Reserve(ctx, &client.ReserveRequest{
+					StorageId:  c.String("storage"),
+					Products:   c.StringSlice("product"),
+					ShippingId: c.String("shipping"),
+					Amount:     c.Int64("amount"),
+				})
+			})
+		},
+	})
+
+	commands.Register(&cli.Command{
+		Name:  "cancel",
+		Usage: "cancel a reservation. Reserved products are available for reservation again",
+		Flags: withClientFlags(reservationFlags("cancel reservation in the storage only")...),
+		Action: func(c *cli.Context) error {
+			return run(c, func(ctx context.Context, command *inventory.InventoryCommand) error {
+				return command.Cancel(ctx, &client.CancelRequest{
+					StorageId:  c.String("storage"),
+					Products:   c.StringSlice("product"),
+					ShippingId: c.String("shipping"),
+				})
+			})
+		},
+	})
+
+	commands.Register(&cli.Command{
+		Name:  "release",
+		Usage: "release a reservation. Reserved products are written off from stock",
+		Flags: withClientFlags(reservationFlags("release reservation in the storage only")...),
+		Action: func(c *cli.Context) error {
+			return run(c, func(ctx context.Context, command *inventory.InventoryCommand) error {
+				return command.Release(ctx, &client.ReleaseRequest{
+					StorageId:  c.String("storage"),
+					Products:   c.StringSlice("product"),
+					ShippingId: c.String("shipping"),
+				})
+			})
+		},
+	})
+}
+
+func withClientFlags(flags ...cli.Flag) []cli.Flag {
+	return slices.Concat(flags, commands.ClientFlags())
+}
+
+func limitFlag() cli.Flag {
+	return &cli.IntFlag{
+		Name:  "limit",
+		Usage: "max items to list. 0 lists all of them",
+		Value: 100,
+	}
+}
+
+func reservationFlags(storageUsage string) []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "shipping", Usage: "shipping id", Required: true},
+		&cli.StringSliceFlag{Name: "product", Usage: "product id. May be repeated", Required: true},
+		&cli.StringFlag{Name: "storage", Usage: storageUsage},
+	}
+}
+
+func run(c *cli.Context, action func(ctx context.Context, command *inventory.InventoryCommand) error) error {
+	apiClient, err := commands.NewClient(c)
+	if err != nil {
+		return err
+	}
+
+	command, err := inventory.NewInventoryCommand(os.Stdout, apiClient, c.String("output"))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.Context, c.Duration("timeout"))
+	defer cancel()
+
+	return action(ctx, command)
+}
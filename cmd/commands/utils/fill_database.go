@@ -3,7 +3,6 @@ package utils
 import (
 	"cernunnos/cmd/commands"
 	"cernunnos/commands/utils"
-	"cernunnos/internal/pkg/config"
 	"cernunnos/internal/pkg/logger"
 	"cernunnos/internal/usecase/repository"
 
@@ -12,42 +11,21 @@ import (
 
 func init() {
 	commands.Register(&cli.Command{
-		Name: "fill-db",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:  "log-level",
-				Value: "debug",
-			},
-			&cli.StringFlag{
-				Name:  "address",
-				Value: "localhost:8080",
-			},
-			&cli.StringFlag{
-				Name: "db-host",
-			},
-			&cli.StringFlag{
-				Name: "db-user",
-			},
-			&cli.StringFlag{
-				Name: "db-password",
-			},
-		},
+		Name:  "fill-db",
+		Flags: commands.ConfigFlags(),
 		Action: func(c *cli.Context) error {
-			cfg := config.Config{
-				Address:          c.String("address"),
-				LogLevel:         c.String("log-level"),
-				DatabaseHost:     c.String("db-host"),
-				DatabaseUser:     c.String("db-user"),
-				DatabasePassword: c.String("db-password"),
+			cfg, err := commands.LoadConfig(c)
+			if err != nil {
+				return err
 			}
 
-			db, cleanup, err := repository.ProvideDatabaseConnection(&cfg)
+			db, cleanup, err := repository.ProvideDatabaseConnection(cfg)
 			if err != nil {
 				return err
 			}
 			defer cleanup()
 
-			log := logger.NewLogger(logger.MapLevel(c.String("log-level")))
+			log := logger.NewLogger(logger.MapLevel(cfg.LogLevel))
 
 			command := utils.NewFillDatabaseCommand(db, log)
 
@@ -2,13 +2,22 @@ package main
 
 import (
 	"cernunnos/cmd/commands"
-	"cernunnos/internal/pkg/config"
 	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/tracing"
 	"cernunnos/internal/server"
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	_ "cernunnos/cmd/commands/apikey"
+	_ "cernunnos/cmd/commands/config"
+	_ "cernunnos/cmd/commands/exports"
+	_ "cernunnos/cmd/commands/imports"
+	_ "cernunnos/cmd/commands/inventory"
 	_ "cernunnos/cmd/commands/utils"
 
 	"github.com/urfave/cli/v2"
@@ -19,55 +28,46 @@ func main() {
 		Name:     "cernunnos",
 		Version:  "0.0.1",
 		Commands: commands.Commands(),
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:  "log-level",
-				Value: "debug",
-			},
-			&cli.StringFlag{
-				Name:  "address",
-				Value: "localhost:8080",
-			},
-			&cli.StringFlag{
-				Name: "db-host",
-			},
-			&cli.StringFlag{
-				Name: "db-user",
-			},
-			&cli.StringFlag{
-				Name: "db-password",
-			},
-		},
+		Flags:    commands.ConfigFlags(),
 		Action: func(c *cli.Context) error {
-			log := logger.NewLogger(logger.MapLevel(c.String("log-level")))
-			log.Debug(
-				"flags passed",
-				slog.String("log-level", c.String("log-level")),
-				slog.String("address", c.String("address")),
-				slog.String("db-host", c.String("db-host")),
-				slog.String("db-user", c.String("db-user")),
-				slog.String("db-password", c.String("db-password")),
-			)
+			cfg, err := commands.LoadConfig(c)
+			if err != nil {
+				return fmt.Errorf("error load config. %w", err)
+			}
 
-			cfg := config.Config{
-				Address:          c.String("address"),
-				LogLevel:         c.String("log-level"),
-				DatabaseHost:     c.String("db-host"),
-				DatabaseUser:     c.String("db-user"),
-				DatabasePassword: c.String("db-password"),
+			log := logger.NewLogger(logger.MapLevel(cfg.LogLevel))
+			log.Debug("config loaded", slog.Any("config", cfg))
+
+			shutdownTracing, err := tracing.Setup(c.Context, cfg.TraceExporter, cfg.TraceFile)
+			if err != nil {
+				return fmt.Errorf("error setup tracing. %w", err)
 			}
 
-			server, cleanup, err := server.ProvideServer(&cfg)
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				if err := shutdownTracing(ctx); err != nil {
+					log.Error("error shutdown tracing", logger.Err(err))
+				}
+			}()
+
+			servers, cleanup, err := server.ProvideServers(cfg)
 			if err != nil {
 				return fmt.Errorf("error initialize server. %w", err)
 			}
 
 			defer cleanup()
 
-			if err = server.Start(); err != nil {
-				panic(err)
+			ctx, stop := signal.NotifyContext(c.Context, syscall.SIGINT, syscall.SIGTERM)
+			defer stop()
+
+			if err = servers.Start(ctx); err != nil {
+				return fmt.Errorf("error run server. %w", err)
 			}
 
+			log.Info("cernunnos server stopped")
+
 			return nil
 		},
 	}
@@ -0,0 +1,96 @@
+package apikey
+
+import (
+	"cernunnos/internal/usecase/interactors"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type APIKeyCommand struct {
+	out        io.Writer
+	interactor interactors.APIKeyInteractor
+}
+
+func NewAPIKeyCommand(out io.Writer, interactor interactors.APIKeyInteractor) *APIKeyCommand {
+	return &APIKeyCommand{
+		out:        out,
+		interactor: interactor,
+	}
+}
+
+// Create creates a key and prints it. The key cannot be shown again
+func (c *APIKeyCommand) Create(ctx context.Context, name string, scopes []string) error {
+	key, apiKey, err := c.interactor.Create(ctx, interactors.CreateAPIKeyParams{
+		Name:   name,
+		Scopes: scopes,
+	})
+	if err != nil {
+		return fmt.Errorf("error create api key. %w", err)
+	}
+
+	_, err = fmt.Fprintf(
+		c.out,
+		"id: %s\nname: %s\nscopes: %s\nkey: %s\n\nStore the key now. It cannot be shown again.\n",
+		apiKey.Id.String(),
+		apiKey.Name,
+		strings.Join(apiKey.Scopes, ","),
+		key,
+	)
+	if err != nil {
+		return fmt.Errorf("error print api key. %w", err)
+	}
+
+	return nil
+}
+
+// List prints all keys, including revoked ones
+func (c *APIKeyCommand) List(ctx context.Context) error {
+	keys, err := c.interactor.Keys(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetch api keys. %w", err)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "ID\tNAME\tSCOPES\tCREATED AT\tREVOKED AT")
+
+	for _, key := range keys {
+		revokedAt := "-"
+		if key.RevokedAt != nil {
+			revokedAt = key.RevokedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\n",
+			key.Id.String(),
+			key.Name,
+			strings.Join(key.Scopes, ","),
+			key.CreatedAt.Format(time.RFC3339),
+			revokedAt,
+		)
+	}
+
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("error print api keys. %w", err)
+	}
+
+	return nil
+}
+
+// Revoke revokes a key. Requests with the key are rejected right away
+func (c *APIKeyCommand) Revoke(ctx context.Context, id string) error {
+	if err := c.interactor.Revoke(ctx, id); err != nil {
+		return fmt.Errorf("error revoke api key. %w", err)
+	}
+
+	if _, err := fmt.Fprintf(c.out, "api key %s revoked\n", id); err != nil {
+		return fmt.Errorf("error print result. %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"cernunnos/internal/pkg/config"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+type PrintCommand struct {
+	out io.Writer
+}
+
+func NewPrintCommand(out io.Writer) *PrintCommand {
+	return &PrintCommand{out: out}
+}
+
+// Run prints the effective config as YAML. Secrets are redacted
+func (c *PrintCommand) Run(cfg *config.Config) error {
+	data, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return fmt.Errorf("error marshal config. %w", err)
+	}
+
+	if _, err = c.out.Write(data); err != nil {
+		return fmt.Errorf("error print config. %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package exports
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ExportCommand writes stock snapshots to files
+type ExportCommand struct {
+	interactor interactors.ExportInteractor
+	presenter  presenters.ExportPresenter
+}
+
+func NewExportCommand(interactor interactors.ExportInteractor, presenter presenters.ExportPresenter) *ExportCommand {
+	return &ExportCommand{
+		interactor: interactor,
+		presenter:  presenter,
+	}
+}
+
+// Stock writes stock of products in storages to out as it is read
+func (c *ExportCommand) Stock(
+	ctx context.Context,
+	out io.Writer,
+	format string,
+	params interactors.ExportStockParams,
+) error {
+	writer, err := c.presenter.StockWriter(out, format)
+	if err != nil {
+		return fmt.Errorf("error build stock writer. %w", err)
+	}
+
+	err = c.interactor.Stock(ctx, params, func(product *models.StorageProduct) error {
+		return writer.Write(product)
+	})
+	if err != nil {
+		return fmt.Errorf("error export stock. %w", err)
+	}
+
+	if err = writer.Flush(); err != nil {
+		return fmt.Errorf("error flush stock rows. %w", err)
+	}
+
+	return nil
+}
+
+// StockFile writes stock to a file. The file is replaced once the export is complete, so a failed
+// export leaves no truncated file.
+func (c *ExportCommand) StockFile(
+	ctx context.Context,
+	name, format string,
+	params interactors.ExportStockParams,
+) (err error) {
+	file, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error create export file. %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, os.Remove(file.Name()))
+		}
+	}()
+
+	if err = c.Stock(ctx, file, format, params); err != nil {
+		return errors.Join(err, file.Close())
+	}
+
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("error close export file. %w", err)
+	}
+
+	if err = os.Rename(file.Name(), name); err != nil {
+		return fmt.Errorf("error move export file. %w", err)
+	}
+
+	return nil
+}
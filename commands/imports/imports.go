@@ -0,0 +1,80 @@
+package imports
+
+import (
+	"cernunnos/internal/usecase/interactors"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ErrorRowsRejected is returned if an import is rolled back because of rejected rows
+var ErrorRowsRejected = errors.New("rows rejected")
+
+// ImportCommand loads a file into the database and prints a report
+type ImportCommand struct {
+	out        io.Writer
+	interactor interactors.ImportInteractor
+}
+
+func NewImportCommand(out io.Writer, interactor interactors.ImportInteractor) *ImportCommand {
+	return &ImportCommand{
+		out:        out,
+		interactor: interactor,
+	}
+}
+
+// Import loads rows of kind from file. Format is detected by the file name, if empty
+func (c *ImportCommand) Import(
+	ctx context.Context,
+	kind, format, name string,
+	file io.Reader,
+	dryRun bool,
+) error {
+	if format == "" {
+		format = FormatOf(name)
+	}
+
+	result, err := c.interactor.Import(ctx, interactors.ImportParams{
+		Kind:   kind,
+		Format: format,
+		DryRun: dryRun,
+		Source: file,
+	})
+	if err != nil {
+		return fmt.Errorf("error import %s. %w", name, err)
+	}
+
+	for _, rowError := range result.Errors {
+		fmt.Fprintf(c.out, "line %d: %s\n", rowError.Line, rowError.Error)
+	}
+
+	if hidden := result.Rejected - int64(len(result.Errors)); hidden > 0 {
+		fmt.Fprintf(c.out, "... and %d more rejected rows\n", hidden)
+	}
+
+	switch {
+	case result.Rejected > 0:
+		fmt.Fprintf(c.out, "%d of %d %s rows rejected. Nothing is imported\n", result.Rejected, result.Rows, kind)
+
+		return fmt.Errorf("error import %s. %d %w", name, result.Rejected, ErrorRowsRejected)
+	case result.DryRun:
+		fmt.Fprintf(c.out, "%d %s rows are valid. Nothing is imported, as it is a dry run\n", result.Rows, kind)
+	default:
+		fmt.Fprintf(c.out, "%d %s rows imported\n", result.Rows, kind)
+	}
+
+	return nil
+}
+
+// FormatOf detects a file format by its extension. Files are CSV unless named .jsonl or .ndjson
+func FormatOf(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jsonl", ".ndjson":
+		return interactors.ImportJSONL
+	default:
+		return interactors.ImportCSV
+	}
+}
@@ -0,0 +1,228 @@
+package inventory
+
+import (
+	"cernunnos/pkg/client"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// Page size of listings. The max one the service allows
+const pageSize = 500
+
+// InventoryCommand calls a running server on behalf of an operator and prints results
+type InventoryCommand struct {
+	out    io.Writer
+	client *client.Client
+	output string
+}
+
+func NewInventoryCommand(out io.Writer, apiClient *client.Client, output string) (*InventoryCommand, error) {
+	if output != OutputTable && output != OutputJSON {
+		return nil, fmt.Errorf("error unknown output %q. Use table or json", output)
+	}
+
+	return &InventoryCommand{
+		out:    out,
+		client: apiClient,
+		output: output,
+	}, nil
+}
+
+// Storages prints up to limit storages. Zero limit prints all of them
+func (c *InventoryCommand) Storages(ctx context.Context, request *client.StoragesRequest, limit int) error {
+	request.Limit = pageLimit(limit)
+
+	storages, err := collect(ctx, c.client.StoragesIterator(request), limit)
+	if err != nil {
+		return fmt.Errorf("error fetch storages. %w", err)
+	}
+
+	return printItems(c, storages, "ID\tNAME\tAVAILABLE\tRESERVED\tUPDATED AT", func(w io.Writer) {
+		for _, storage := range storages {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%d\t%d\t%s\n",
+				storage.Id,
+				storage.Name,
+				storage.Available,
+				storage.Reserved,
+				formatTime(storage.UpdatedAt),
+			)
+		}
+	})
+}
+
+// StorageProducts prints up to limit products of a storage. Zero limit prints all of them
+func (c *InventoryCommand) StorageProducts(
+	ctx context.Context,
+	request *client.StorageProductsRequest,
+	limit int,
+) error {
+	request.Limit = pageLimit(limit)
+
+	products, err := collect(ctx, c.client.StorageProductsIterator(request), limit)
+	if err != nil {
+		return fmt.Errorf("error fetch storage products. %w", err)
+	}
+
+	return printItems(c, products, "ID\tNAME\tSIZE\tAMOUNT\tRESERVED\tAVAILABLE", func(w io.Writer) {
+		for _, product := range products {
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%d\t%d\t%d\t%d\n",
+				product.Id,
+				product.Name,
+				product.Size,
+				product.Amount,
+				product.Reserved,
+				product.Available,
+			)
+		}
+	})
+}
+
+// Products prints up to limit products with stock summed over storages. Zero limit prints all of them
+func (c *InventoryCommand) Products(ctx context.Context, request *client.ProductsRequest, limit int) error {
+	request.Limit = pageLimit(limit)
+
+	products, err := collect(ctx, c.client.ProductsIterator(request), limit)
+	if err != nil {
+		return fmt.Errorf("error fetch products. %w", err)
+	}
+
+	return printItems(c, products, "ID\tNAME\tSIZE\tSTORAGES\tRESERVED\tAVAILABLE", func(w io.Writer) {
+		for _, product := range products {
+			var reserved, available int64
+
+			for _, distribution := range product.DestributionInfo {
+				reserved += distribution.Reserved
+				available += distribution.Available
+			}
+
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%d\t%d\t%d\t%d\n",
+				product.Id,
+				product.Name,
+				product.Size,
+				len(product.DestributionInfo),
+				reserved,
+				available,
+			)
+		}
+	})
+}
+
+// Reserve reserves products for a shipping
+func (c *InventoryCommand) Reserve(ctx context.Context, request *client.ReserveRequest) error {
+	response, err := c.client.Reserve(ctx, request)
+	if err != nil {
+		return fmt.Errorf("error reserve products. %w", err)
+	}
+
+	return c.printResult(response, "reserved %d of %s for shipping %s\n",
+		request.Amount, strings.Join(request.Products, ","), request.ShippingId)
+}
+
+// Cancel cancels a reservation of a shipping
+func (c *InventoryCommand) Cancel(ctx context.Context, request *client.CancelRequest) error {
+	response, err := c.client.Cancel(ctx, request)
+	if err != nil {
+		return fmt.Errorf("error cancel reservation. %w", err)
+	}
+
+	return c.printResult(response, "reservation of %s for shipping %s cancelled\n",
+		strings.Join(request.Products, ","), request.ShippingId)
+}
+
+// Release writes off reserved products of a shipping
+func (c *InventoryCommand) Release(ctx context.Context, request *client.ReleaseRequest) error {
+	response, err := c.client.Release(ctx, request)
+	if err != nil {
+		return fmt.Errorf("error release reservation. %w", err)
+	}
+
+	return c.printResult(response, "reservation of %s for shipping %s released\n",
+		strings.Join(request.Products, ","), request.ShippingId)
+}
+
+// printItems writes items as a JSON array or as a table with the header and rows written by rows
+func printItems[T any](c *InventoryCommand, items []T, header string, rows func(w io.Writer)) error {
+	if c.output == OutputJSON {
+		return c.printJSON(items)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, header)
+	rows(w)
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error print table. %w", err)
+	}
+
+	return nil
+}
+
+func (c *InventoryCommand) printResult(response any, format string, args ...any) error {
+	if c.output == OutputJSON {
+		return c.printJSON(response)
+	}
+
+	if _, err := fmt.Fprintf(c.out, format, args...); err != nil {
+		return fmt.Errorf("error print result. %w", err)
+	}
+
+	return nil
+}
+
+func (c *InventoryCommand) printJSON(v any) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("error print json. %w", err)
+	}
+
+	return nil
+}
+
+// collect reads up to limit items of an iterator. Zero limit reads all of them
+func collect[T any](ctx context.Context, it *client.Iterator[T], limit int) ([]T, error) {
+	items := make([]T, 0)
+
+	for (limit == 0 || len(items) < limit) && it.Next(ctx) {
+		items = append(items, it.Item())
+	}
+
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// pageLimit returns a page size fetching limit items at once, if possible
+func pageLimit(limit int) uint32 {
+	if limit <= 0 || limit > pageSize {
+		return pageSize
+	}
+
+	return uint32(limit)
+}
+
+// formatTime formats unix milli time of the service
+func formatTime(unixMilli uint64) string {
+	return time.UnixMilli(int64(unixMilli)).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,47 @@
+package grpcserver
+
+import (
+	errs "cernunnos/internal/pkg/errors"
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// httpCodes maps API error codes to gRPC status codes
+var httpCodes = map[uint16]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.FailedPrecondition,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusInsufficientStorage: codes.FailedPrecondition,
+	http.StatusInternalServerError: codes.Internal,
+}
+
+// statusError maps a domain error to a gRPC status with the same details HTTP API responds with
+func statusError(errorsHandler errs.ErrorHandler, err error) error {
+	if s, ok := status.FromError(err); ok {
+		return s.Err()
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "Deadline Exceeded!")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "Request Cancelled!")
+	}
+
+	apiErr := errorsHandler.Handle(err)
+
+	code, ok := httpCodes[apiErr.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	return status.Error(code, apiErr.Details)
+}
@@ -0,0 +1,248 @@
+package grpcserver
+
+import (
+	"cernunnos/internal/middleware"
+	"cernunnos/internal/pkg/auth"
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/ratelimit"
+	"cernunnos/internal/pkg/tracing"
+	pb "cernunnos/pkg/pb/cernunnos/v1"
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodRule is an access scope and a rate limit class of a method
+type methodRule struct {
+	scope string
+	class string
+}
+
+// methodRules are rules of API methods. Methods not listed, e.g. health checks, are public and not
+// limited.
+var methodRules = map[string]methodRule{
+	pb.StorageService_ListStorages_FullMethodName:           {auth.ScopeRead, "read"},
+	pb.ProductService_ListProducts_FullMethodName:           {auth.ScopeRead, "read"},
+	pb.ProductService_ListStorageProducts_FullMethodName:    {auth.ScopeRead, "read"},
+	pb.ProductService_SearchProducts_FullMethodName:         {auth.ScopeRead, "read"},
+	pb.ReservationService_ListReservations_FullMethodName:   {auth.ScopeRead, "read"},
+	pb.ReservationService_Reserve_FullMethodName:            {auth.ScopeReserve, "write"},
+	pb.ReservationService_CancelReservation_FullMethodName:  {auth.ScopeReserve, "write"},
+	pb.ReservationService_ReleaseReservation_FullMethodName: {auth.ScopeRelease, "write"},
+}
+
+type interceptors struct {
+	log           *slog.Logger
+	errorsHandler errs.ErrorHandler
+	authenticator middleware.Authenticator // nil if authentication is disabled
+	readLimit     *ratelimit.Limiter
+	writeLimit    *ratelimit.Limiter
+}
+
+// observe traces, logs and measures requests, and maps returned errors to gRPC statuses
+func (i *interceptors) observe(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	startedAt := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	ctx = logger.WithRequestAttrs(ctx)
+	ctx, span := tracing.StartServer(ctx, mapHeader(md), info.FullMethod,
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.method", info.FullMethod),
+	)
+
+	if spanContext := span.SpanContext(); spanContext.IsValid() {
+		logger.AddAttrs(ctx, slog.String("trace_id", spanContext.TraceID().String()))
+	}
+
+	observe := metrics.ObserveRPC(info.FullMethod)
+
+	resp, err := handler(ctx, req)
+	if err != nil {
+		i.log.ErrorContext(ctx, "error", slog.String("method", info.FullMethod), logger.Err(err))
+
+		err = statusError(i.errorsHandler, err)
+	}
+
+	code := status.Code(err)
+
+	observe(code.String())
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+	tracing.End(span, err)
+
+	i.log.InfoContext(
+		ctx,
+		"request",
+		slog.String("method", info.FullMethod),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(startedAt)),
+		slog.String("remote_addr", remoteAddr(ctx)),
+	)
+
+	return resp, err
+}
+
+// recovery turns panics into internal errors
+func (i *interceptors) recovery(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp any, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			i.log.ErrorContext(
+				ctx,
+				"recovered from panic",
+				slog.String("method", info.FullMethod),
+				slog.Any("error", slog.AnyValue(recovered)),
+			)
+
+			err = errs.ErrorInternalServerError
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+// authenticate rejects requests without valid credentials or granted scope and passes an
+// authenticated client through context. Does nothing if authentication is disabled.
+func (i *interceptors) authenticate(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	rule, ok := methodRules[info.FullMethod]
+	if !ok || i.authenticator == nil {
+		return handler(ctx, req)
+	}
+
+	principal, err := i.authenticator.Authenticate(ctx, credential(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.AddAttrs(ctx, slog.String("client", principal.Name))
+
+	if !principal.Has(rule.scope) {
+		i.log.WarnContext(
+			ctx,
+			"scope is not granted",
+			slog.String("client", principal.Name),
+			slog.String("scope", rule.scope),
+		)
+
+		return nil, auth.ErrorForbidden
+	}
+
+	return handler(auth.WithPrincipal(ctx, principal), req)
+}
+
+// rateLimit rejects requests exceeding the client limit and passes retry-after header in seconds.
+// Read and write methods share limits with HTTP routes of the same class.
+func (i *interceptors) rateLimit(
+	ctx context.Context,
+	req any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	rule, ok := methodRules[info.FullMethod]
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	limiter := i.readLimit
+	if rule.class == "write" {
+		limiter = i.writeLimit
+	}
+
+	client := clientKey(ctx)
+
+	if ok, retryAfter := limiter.Allow(client); !ok {
+		metrics.RateLimited(rule.class)
+		i.log.WarnContext(
+			ctx,
+			"rate limit exceeded",
+			slog.String("rate_limit_key", client),
+			slog.String("class", rule.class),
+		)
+
+		header := metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		if err := grpc.SetHeader(ctx, header); err != nil {
+			i.log.WarnContext(ctx, "error set retry-after header", logger.Err(err))
+		}
+
+		return nil, ratelimit.ErrorRateLimited
+	}
+
+	return handler(ctx, req)
+}
+
+// credential returns a bearer token, if passed, or an API key
+func credential(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get("authorization"); len(values) > 0 {
+		if token, ok := strings.CutPrefix(values[0], "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+
+	if values := md.Get(middleware.APIKeyHeader); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+func clientKey(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return "client:" + principal.Id
+	}
+
+	host, _, err := net.SplitHostPort(remoteAddr(ctx))
+	if err != nil {
+		return "ip:" + remoteAddr(ctx)
+	}
+
+	return "ip:" + host
+}
+
+func remoteAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+
+	return ""
+}
+
+// mapHeader maps incoming metadata to HTTP header, so trace context is extracted as from HTTP requests
+func mapHeader(md metadata.MD) http.Header {
+	header := make(http.Header, len(md))
+
+	for key, values := range md {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
+
+	return header
+}
@@ -0,0 +1,123 @@
+package grpcserver
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/usecase/interactors"
+	pb "cernunnos/pkg/pb/cernunnos/v1"
+	"time"
+)
+
+func mapPageInfo[T any](page *models.Page[T]) *pb.PageInfo {
+	return &pb.PageInfo{
+		NextOffset: uint32(page.NextOffset),
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+}
+
+func mapStorage(storage *models.Storage) *pb.Storage {
+	return &pb.Storage{
+		Id:        storage.Id.String(),
+		Name:      storage.Name,
+		Reserved:  storage.Reserved,
+		Available: storage.Available,
+		CreatedAt: storage.CreatedAt.UnixMilli(),
+		UpdatedAt: storage.UpdatedAt.UnixMilli(),
+	}
+}
+
+func mapDistribution(distribution *models.ProductDestribution) *pb.ProductDistribution {
+	return &pb.ProductDistribution{
+		StorageId: distribution.Storage.Id.String(),
+		Amount:    distribution.Amount,
+		Reserved:  distribution.Reserved,
+		Available: distribution.Available,
+	}
+}
+
+func mapProduct(product *models.ProductInfo) *pb.Product {
+	distribution := make([]*pb.ProductDistribution, len(product.DestributionInfo))
+
+	for i, info := range product.DestributionInfo {
+		distribution[i] = mapDistribution(info)
+	}
+
+	return &pb.Product{
+		Id:           product.Id.String(),
+		Name:         product.Name,
+		Size:         product.Size,
+		CreatedAt:    product.CreatedAt.UnixMilli(),
+		UpdatedAt:    product.UpdatedAt.UnixMilli(),
+		Distribution: distribution,
+	}
+}
+
+func mapProducts(products []*models.ProductInfo) []*pb.Product {
+	mapped := make([]*pb.Product, len(products))
+
+	for i, product := range products {
+		mapped[i] = mapProduct(product)
+	}
+
+	return mapped
+}
+
+func mapStorageProduct(product *models.StorageProduct) *pb.StorageProduct {
+	return &pb.StorageProduct{
+		Product:      mapProduct(&product.ProductInfo),
+		Distribution: mapDistribution(&product.ProductDestribution),
+	}
+}
+
+func mapReservation(reservation *models.Reservation) *pb.Reservation {
+	return &pb.Reservation{
+		Id:         reservation.Id.String(),
+		StorageId:  reservation.StorageId.String(),
+		ProductId:  reservation.ReservedProduct.Id.String(),
+		ShippingId: reservation.ShippingId.String(),
+		Reserved:   reservation.Reserved,
+		CreatedAt:  reservation.CreatedAt.UnixMilli(),
+		UpdatedAt:  reservation.UpdatedAt.UnixMilli(),
+	}
+}
+
+func mapStoragesFilter(filter *pb.StoragesFilter) interactors.StoragesFilter {
+	if filter == nil {
+		return interactors.StoragesFilter{}
+	}
+
+	return interactors.StoragesFilter{
+		Sort:         filter.Sort,
+		NamePrefix:   filter.NamePrefix,
+		NameContains: filter.NameContains,
+		AvailableGt:  filter.AvailableGt,
+		UpdatedSince: mapUnixMilli(filter.UpdatedSince),
+	}
+}
+
+func mapProductsFilter(filter *pb.ProductsFilter) interactors.ProductsFilter {
+	if filter == nil {
+		return interactors.ProductsFilter{}
+	}
+
+	return interactors.ProductsFilter{
+		Sort:         filter.Sort,
+		NamePrefix:   filter.NamePrefix,
+		NameContains: filter.NameContains,
+		SizeMin:      filter.SizeMin,
+		SizeMax:      filter.SizeMax,
+		AvailableGt:  filter.AvailableGt,
+		UpdatedSince: mapUnixMilli(filter.UpdatedSince),
+	}
+}
+
+// mapUnixMilli maps optional unix milli timestamp to time
+func mapUnixMilli(milli *int64) *time.Time {
+	if milli == nil {
+		return nil
+	}
+
+	t := time.UnixMilli(*milli)
+
+	return &t
+}
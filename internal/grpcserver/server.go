@@ -0,0 +1,169 @@
+package grpcserver
+
+import (
+	"cernunnos/internal/middleware"
+	"cernunnos/internal/pkg/config"
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/pkg/ratelimit"
+	"cernunnos/internal/usecase/interactors"
+	pb "cernunnos/pkg/pb/cernunnos/v1"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// healthInterval is a period of readiness checks reported by the health service
+const healthInterval = 10 * time.Second
+
+// Server serves gRPC API. Services call the same interactors as HTTP handlers, so both APIs share
+// business logic, authentication and rate limits configuration.
+type Server struct {
+	address         string
+	shutdownTimeout time.Duration
+	log             *slog.Logger
+	server          *grpc.Server
+	health          *grpchealth.Server
+	checker         *health.Checker
+}
+
+type Options struct {
+	Config        *config.Config
+	Authenticator middleware.Authenticator // nil if authentication is disabled
+	Checker       *health.Checker
+}
+
+func New(
+	log *slog.Logger,
+	options Options,
+	storageInteractor interactors.StorageInteractor,
+	productInteractor interactors.ProductInteractor,
+	reservationInteractor interactors.ReservationInteractor,
+) *Server {
+	log = log.WithGroup("grpc")
+
+	interceptors := &interceptors{
+		log:           log,
+		errorsHandler: errs.NewErrorHandler(),
+		authenticator: options.Authenticator,
+		readLimit:     ratelimit.New(options.Config.RateLimits.ReadRPS, options.Config.RateLimits.ReadBurst),
+		writeLimit:    ratelimit.New(options.Config.RateLimits.WriteRPS, options.Config.RateLimits.WriteBurst),
+	}
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		interceptors.observe,
+		interceptors.recovery,
+		interceptors.authenticate,
+		interceptors.rateLimit,
+	))
+
+	pb.RegisterStorageServiceServer(server, &storageService{interactor: storageInteractor})
+	pb.RegisterProductServiceServer(server, &productService{interactor: productInteractor})
+	pb.RegisterReservationServiceServer(server, &reservationService{interactor: reservationInteractor})
+
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	if options.Config.GRPC.Reflection {
+		reflection.Register(server)
+	}
+
+	return &Server{
+		address:         options.Config.GRPC.Address,
+		shutdownTimeout: options.Config.Timeouts.Shutdown,
+		log:             log,
+		server:          server,
+		health:          healthServer,
+		checker:         options.Checker,
+	}
+}
+
+// Start serves gRPC requests until ctx is cancelled. On shutdown in-flight requests are drained
+// within shutdown timeout.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("error listen to %s. %w", s.address, err)
+	}
+
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves gRPC requests accepted by listener until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	s.log.Info("starting cernunnos grpc server", slog.String("address", listener.Addr().String()))
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- s.server.Serve(listener)
+	}()
+
+	healthCtx, stopHealth := context.WithCancel(ctx)
+	defer stopHealth()
+
+	go s.reportHealth(healthCtx)
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("error serve grpc on %s. %w", listener.Addr(), err)
+	case <-ctx.Done():
+	}
+
+	s.log.Info("shutting down cernunnos grpc server", slog.Duration("timeout", s.shutdownTimeout))
+
+	s.health.Shutdown()
+
+	stopped := make(chan struct{})
+
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(s.shutdownTimeout):
+		s.server.Stop()
+
+		return errors.New("error drain in-flight grpc requests. shutdown timeout exceeded")
+	}
+
+	return nil
+}
+
+// reportHealth sets overall serving status by readiness checks, as HTTP readyz does
+func (s *Server) reportHealth(ctx context.Context) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+
+		if report := s.checker.Run(ctx); report.Status != health.StatusOk {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+
+			s.log.WarnContext(ctx, "service is not ready", slog.Any("checks", report.Checks))
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.health.SetServingStatus("", status)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package grpcserver
+
+import (
+	"cernunnos/internal/usecase/interactors"
+	pb "cernunnos/pkg/pb/cernunnos/v1"
+	"context"
+	"fmt"
+)
+
+type storageService struct {
+	pb.UnimplementedStorageServiceServer
+
+	interactor interactors.StorageInteractor
+}
+
+func (s *storageService) ListStorages(
+	ctx context.Context,
+	req *pb.ListStoragesRequest,
+) (*pb.ListStoragesResponse, error) {
+	page := req.GetPage()
+
+	storages, err := s.interactor.Storages(ctx, interactors.StoragesParams{
+		StoragesFilter: mapStoragesFilter(req.GetFilter()),
+		Ids:            req.GetIds(),
+		Limit:          page.GetLimit(),
+		Offset:         page.GetOffset(),
+		Cursor:         page.GetCursor(),
+		WithTotal:      page.GetWithTotal(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetch storages. %w", err)
+	}
+
+	response := &pb.ListStoragesResponse{
+		Storages: make([]*pb.Storage, len(storages.Items)),
+		Page:     mapPageInfo(storages),
+	}
+
+	for i, storage := range storages.Items {
+		response.Storages[i] = mapStorage(storage)
+	}
+
+	return response, nil
+}
+
+type productService struct {
+	pb.UnimplementedProductServiceServer
+
+	interactor interactors.ProductInteractor
+}
+
+func (s *productService) ListProducts(
+	ctx context.Context,
+	req *pb.ListProductsRequest,
+) (*pb.ListProductsResponse, error) {
+	page := req.GetPage()
+
+	products, err := s.interactor.Products(ctx, interactors.ProductsParams{
+		ProductsFilter:  mapProductsFilter(req.GetFilter()),
+		Ids:             req.GetIds(),
+		StorageId:       req.GetStorageId(),
+		WithUnavailable: req.GetWithUnavailable(),
+		Limit:           page.GetLimit(),
+		Offset:          page.GetOffset(),
+		Cursor:          page.GetCursor(),
+		WithTotal:       page.GetWithTotal(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetch products. %w", err)
+	}
+
+	return &pb.ListProductsResponse{
+		Products: mapProducts(products.Items),
+		Page:     mapPageInfo(products),
+	}, nil
+}
+
+func (s *productService) ListStorageProducts(
+	ctx context.Context,
+	req *pb.ListStorageProductsRequest,
+) (*pb.ListStorageProductsResponse, error) {
+	page := req.GetPage()
+
+	products, err := s.interactor.StorageProducts(ctx, interactors.StorageProductsParams{
+		ProductsFilter:  mapProductsFilter(req.GetFilter()),
+		Ids:             req.GetIds(),
+		StorageId:       req.GetStorageId(),
+		WithUnavailable: req.GetWithUnavailable(),
+		Limit:           uint64(page.GetLimit()),
+		Offset:          uint64(page.GetOffset()),
+		Cursor:          page.GetCursor(),
+		WithTotal:       page.GetWithTotal(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetch storage products. %w", err)
+	}
+
+	response := &pb.ListStorageProductsResponse{
+		Products: make([]*pb.StorageProduct, len(products.Items)),
+		Page:     mapPageInfo(products),
+	}
+
+	for i, product := range products.Items {
+		response.Products[i] = mapStorageProduct(product)
+	}
+
+	return response, nil
+}
+
+func (s *productService) SearchProducts(
+	ctx context.Context,
+	req *pb.SearchProductsRequest,
+) (*pb.SearchProductsResponse, error) {
+	products, err := s.interactor.SearchProducts(ctx, interactors.SearchProductsParams{
+		Query:     req.GetQuery(),
+		StorageId: req.GetStorageId(),
+		Limit:     uint64(req.GetLimit()),
+		Offset:    uint64(req.GetOffset()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error search products. %w", err)
+	}
+
+	return &pb.SearchProductsResponse{
+		Products: mapProducts(products.Items),
+		Page:     mapPageInfo(products),
+	}, nil
+}
+
+type reservationService struct {
+	pb.UnimplementedReservationServiceServer
+
+	interactor interactors.ReservationInteractor
+}
+
+func (s *reservationService) ListReservations(
+	ctx context.Context,
+	req *pb.ListReservationsRequest,
+) (*pb.ListReservationsResponse, error) {
+	page := req.GetPage()
+
+	reservations, err := s.interactor.Reservations(ctx, interactors.ReservationsParams{
+		StorageId:  req.GetStorageId(),
+		ProductId:  req.GetProductId(),
+		ShippingId: req.GetShippingId(),
+		Limit:      uint64(page.GetLimit()),
+		Offset:     uint64(page.GetOffset()),
+		Cursor:     page.GetCursor(),
+		WithTotal:  page.GetWithTotal(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetch reservations. %w", err)
+	}
+
+	response := &pb.ListReservationsResponse{
+		Reservations: make([]*pb.Reservation, len(reservations.Items)),
+		Page:         mapPageInfo(reservations),
+	}
+
+	for i, reservation := range reservations.Items {
+		response.Reservations[i] = mapReservation(reservation)
+	}
+
+	return response, nil
+}
+
+func (s *reservationService) Reserve(ctx context.Context, req *pb.ReserveRequest) (*pb.ReserveResponse, error) {
+	err := s.interactor.Reserve(ctx, interactors.ReserveParams{
+		ProductIds: req.GetProductIds(),
+		StorageId:  req.GetStorageId(),
+		ShippingId: req.GetShippingId(),
+		Amount:     req.GetAmount(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reserve products. %w", err)
+	}
+
+	return &pb.ReserveResponse{}, nil
+}
+
+func (s *reservationService) CancelReservation(
+	ctx context.Context,
+	req *pb.CancelReservationRequest,
+) (*pb.CancelReservationResponse, error) {
+	err := s.interactor.Cancel(ctx, interactors.CancelParams{
+		ProductIds: req.GetProductIds(),
+		StorageId:  req.GetStorageId(),
+		ShippingId: req.GetShippingId(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error cancel reservation. %w", err)
+	}
+
+	return &pb.CancelReservationResponse{}, nil
+}
+
+func (s *reservationService) ReleaseReservation(
+	ctx context.Context,
+	req *pb.ReleaseReservationRequest,
+) (*pb.ReleaseReservationResponse, error) {
+	err := s.interactor.Release(ctx, interactors.ReleaseParams{
+		ProductIds: req.GetProductIds(),
+		StorageId:  req.GetStorageId(),
+		ShippingId: req.GetShippingId(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error release reservation. %w", err)
+	}
+
+	return &pb.ReleaseReservationResponse{}, nil
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"cernunnos/internal/pkg/logger"
+	"log/slog"
+	"net/http"
+	"time"
+
+	chi "github.com/go-chi/chi/v5/middleware"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+// AccessLog logs every request with its status, latency and response size. Must be installed
+// after chi RequestID middleware. The request id is echoed in X-Request-Id response header.
+func (m *middlewareBuilder) AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startedAt := time.Now()
+
+		if requestId, err := requestId(r.Context()); err == nil {
+			w.Header().Set(RequestIdHeader, requestId)
+		}
+
+		ctx := logger.WithRequestAttrs(r.Context())
+		ww := chi.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.log.InfoContext(
+			ctx,
+			"request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(startedAt)),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+	})
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"cernunnos/internal/pkg/auth"
+	"cernunnos/internal/pkg/logger"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// Authenticator resolves a client by credentials passed with a request
+type Authenticator interface {
+	Authenticate(ctx context.Context, credential string) (*auth.Principal, error)
+}
+
+// Authentication rejects requests without valid credentials and passes an authenticated client
+// through request context. Does nothing if authentication is disabled.
+func (m *middlewareBuilder) Authentication(next http.Handler) http.Handler {
+	if m.authenticator == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := m.authenticator.Authenticate(r.Context(), credential(r))
+		if err != nil {
+			if !errors.Is(err, auth.ErrorUnauthorized) {
+				m.log.ErrorContext(r.Context(), "error authenticate request", logger.Err(err))
+			}
+
+			m.responseError(w, err)
+
+			return
+		}
+
+		logger.AddAttrs(r.Context(), slog.String("client", principal.Name))
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// credential returns a bearer token, if passed, or an API key
+func credential(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	return r.Header.Get(APIKeyHeader)
+}
+
+// RequireScope rejects requests of clients not granted a scope. Does nothing if authentication
+// is disabled.
+func (m *middlewareBuilder) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if m.authenticator == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				m.responseError(w, auth.ErrorUnauthorized)
+
+				return
+			}
+
+			if !principal.Has(scope) {
+				m.log.WarnContext(
+					r.Context(),
+					"scope is not granted",
+					slog.String("client", principal.Name),
+					slog.String("scope", scope),
+				)
+				m.responseError(w, auth.ErrorForbidden)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (m *middlewareBuilder) responseError(w http.ResponseWriter, e error) {
+	apiErr := m.errorsHandler.Handle(e)
+
+	out, err := json.Marshal(&apiErr)
+	if err != nil {
+		m.log.Error("error marshal api error", logger.Err(err))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(int(apiErr.Code))
+
+	if _, err = w.Write(out); err != nil {
+		m.log.Error("error write api error", logger.Err(err))
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"cernunnos/internal/pkg/sqltools"
+	"net/http"
+	"strings"
+)
+
+// ConsistencyHeader set to "strong" makes the request read from primary database. Listings read
+// from replica otherwise and may miss writes made just before.
+const ConsistencyHeader = "X-Consistency"
+
+// Consistency routes reads of requests asking for strong consistency to primary database
+func (m *middlewareBuilder) Consistency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get(ConsistencyHeader), "strong") {
+			r = r.WithContext(sqltools.WithPrimary(r.Context()))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	chi "github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyStoreTimeout   = 5 * time.Second
+	idempotentResponseMaxSize = 1 << 20
+)
+
+// Requests are done within the request timeout, so keys in progress for longer are left by crashed
+// requests and may be claimed again
+const idempotencyClaimLease = time.Minute
+
+// IdempotencyStore keeps responses of writes made with idempotency keys
+type IdempotencyStore interface {
+	Claim(
+		ctx context.Context,
+		request *models.IdempotentRequest,
+		expiredBefore, abandonedBefore time.Time,
+	) (*models.IdempotentRequest, error)
+	Complete(ctx context.Context, claim *models.IdempotentRequest, statusCode int, response []byte) error
+	Release(ctx context.Context, claim *models.IdempotentRequest) error
+}
+
+// Idempotency makes writes with Idempotency-Key header safe to retry. The first request with a key
+// is handled and its response is stored, retries with the key get the stored response until it
+// expires. Keys are scoped by client. Server errors are not stored, so such requests may be
+// retried with the same key. Keys of requests crashed in progress are freed after a short lease.
+// Reads and requests without the header are handled as usual.
+func (m *middlewareBuilder) Idempotency(next http.Handler) http.Handler {
+	if m.idempotency == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			m.responseError(w, fmt.Errorf(
+				"idempotency key exceeds %d characters. %w", maxIdempotencyKeyLength, errs.ErrorBadRequest,
+			))
+
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			m.responseError(w, fmt.Errorf("error read request body. %w", errs.ErrorBadRequest))
+
+			return
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		request := &models.IdempotentRequest{
+			Client:      clientKey(r),
+			Key:         key,
+			RequestHash: requestHash(r, body),
+			// Claims are matched by creation time, which database stores in microseconds
+			CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		}
+
+		stored, err := m.idempotency.Claim(
+			r.Context(),
+			request,
+			request.CreatedAt.Add(-m.idempotencyTTL),
+			request.CreatedAt.Add(-idempotencyClaimLease),
+		)
+		if err != nil {
+			m.log.ErrorContext(r.Context(), "error claim idempotency key", logger.Err(err))
+			m.responseError(w, err)
+
+			return
+		}
+
+		if stored != nil {
+			m.replay(w, r, request, stored)
+
+			return
+		}
+
+		m.handleIdempotent(w, r, request, next)
+	})
+}
+
+// replay writes a stored response, if the request matches the stored one and is completed
+func (m *middlewareBuilder) replay(
+	w http.ResponseWriter,
+	r *http.Request,
+	request, stored *models.IdempotentRequest,
+) {
+	switch {
+	case stored.RequestHash != request.RequestHash:
+		m.responseError(w, errs.ErrorIdempotencyKeyMismatch)
+	case stored.StatusCode == 0:
+		m.responseError(w, errs.ErrorIdempotencyKeyInProgress)
+	default:
+		logger.AddAttrs(r.Context(), slog.Bool("idempotent_replay", true))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(IdempotentReplayedHeader, strconv.FormatBool(true))
+		w.WriteHeader(stored.StatusCode)
+		_, _ = w.Write(stored.Response)
+	}
+}
+
+// handleIdempotent handles a request with a claimed key and stores its response. The response is
+// stored even if the client is gone, as a retry is expected then.
+func (m *middlewareBuilder) handleIdempotent(
+	w http.ResponseWriter,
+	r *http.Request,
+	request *models.IdempotentRequest,
+	next http.Handler,
+) {
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), idempotencyStoreTimeout)
+	defer cancel()
+
+	completed := false
+
+	defer func() {
+		if completed {
+			return
+		}
+
+		// Panicked requests may be retried
+		m.logClaimError(r.Context(), "error release idempotency key", m.idempotency.Release(storeCtx, request))
+	}()
+
+	var response bytes.Buffer
+
+	ww := chi.NewWrapResponseWriter(w, r.ProtoMajor)
+	ww.Tee(&response)
+
+	next.ServeHTTP(ww, r)
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// Server errors and rate limited requests are not final, so they are not replayed
+	if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests ||
+		response.Len() > idempotentResponseMaxSize {
+		return
+	}
+
+	err := m.idempotency.Complete(storeCtx, request, status, response.Bytes())
+
+	m.logClaimError(r.Context(), "error store idempotent response", err)
+
+	// A key claimed by a later request is not released
+	completed = err == nil || errors.Is(err, errs.ErrorIdempotencyClaimLost)
+}
+
+// logClaimError logs a failure to store or release a claimed key. Keys claimed again by later
+// requests are only warned of, as the request outlived its lease and the later request owns the key.
+func (m *middlewareBuilder) logClaimError(ctx context.Context, msg string, err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, errs.ErrorIdempotencyClaimLost):
+		m.log.WarnContext(ctx, "idempotency key claim lost", logger.Err(err))
+	default:
+		m.log.ErrorContext(ctx, msg, logger.Err(err))
+	}
+}
+
+// requestHash identifies a request by method, path with query and body
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -1,33 +1,58 @@
 package middleware
 
 import (
+	errs "cernunnos/internal/pkg/errors"
 	"cernunnos/internal/pkg/logger"
 	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	chi "github.com/go-chi/chi/v5/middleware"
 )
 
 type middlewareBuilder struct {
-	log *slog.Logger
+	log           *slog.Logger
+	errorsHandler errs.ErrorHandler
+	authenticator Authenticator // nil if authentication is disabled
+
+	idempotency    IdempotencyStore // nil if idempotency keys are ignored
+	idempotencyTTL time.Duration
 }
 
-func NewMiddlewareBuilder(log *slog.Logger) *middlewareBuilder {
-	return &middlewareBuilder{log: log}
+func NewMiddlewareBuilder(
+	log *slog.Logger,
+	errorsHandler errs.ErrorHandler,
+	authenticator Authenticator,
+	idempotency IdempotencyStore,
+	idempotencyTTL time.Duration,
+) *middlewareBuilder {
+	return &middlewareBuilder{
+		log:            log,
+		errorsHandler:  errorsHandler,
+		authenticator:  authenticator,
+		idempotency:    idempotency,
+		idempotencyTTL: idempotencyTTL,
+	}
 }
 
 func (m *middlewareBuilder) Recovery(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				// Handlers abort responses they can not complete, like streamed files
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				reqID, ctxErr := requestId(r.Context())
 				if ctxErr != nil {
 					m.log.Error("incomplete request context", logger.Err(ctxErr))
 				}
 
-				m.log.Error(
+				m.log.ErrorContext(
+					r.Context(),
 					"recovered from panic",
 					slog.String("request id", reqID),
 					slog.Any("error", slog.AnyValue(err)),
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"cernunnos/internal/pkg/auth"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/ratelimit"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// RateLimit rejects requests exceeding the client limit with 429 and Retry-After header. Clients
+// are identified by credentials, if authenticated, or by IP address. class names a group of routes
+// sharing the limit in metrics.
+func (m *middlewareBuilder) RateLimit(limiter *ratelimit.Limiter, class string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limiter.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientKey(r)
+
+			if ok, retryAfter := limiter.Allow(client); !ok {
+				metrics.RateLimited(class)
+				m.log.WarnContext(
+					r.Context(),
+					"rate limit exceeded",
+					slog.String("rate_limit_key", client),
+					slog.String("class", class),
+				)
+
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				m.responseError(w, ratelimit.ErrorRateLimited)
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFromContext(r.Context()); ok {
+		return "client:" + principal.Id
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrorUnauthorized = errors.New("unauthorized")
+	ErrorForbidden    = errors.New("forbidden")
+	ErrorInvalidScope = errors.New("invalid scope")
+)
+
+// Access scopes
+const (
+	ScopeRead    = "read"    // Listing storages, products and reservations
+	ScopeReserve = "reserve" // Creating and cancelling reservations
+	ScopeRelease = "release" // Writing reserved products off from stock
+	ScopeAdmin   = "admin"   // Implies all scopes
+)
+
+var scopes = []string{ScopeRead, ScopeReserve, ScopeRelease, ScopeAdmin}
+
+// ValidateScopes checks all scopes are known
+func ValidateScopes(values []string) error {
+	for _, scope := range values {
+		if !slices.Contains(scopes, scope) {
+			return fmt.Errorf("error unknown scope %s. %w", scope, ErrorInvalidScope)
+		}
+	}
+
+	return nil
+}
+
+// Principal is an authenticated client
+type Principal struct {
+	Id          string
+	Name        string
+	Scopes      []string
+	AllStorages bool       // Client may act in any storage
+	Storages    uuid.UUIDs // Storages client may act in, unless AllStorages is set
+}
+
+// Has reports whether the principal is granted a scope
+func (p *Principal) Has(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// StorageAllowed reports whether the principal may act in a storage
+func (p *Principal) StorageAllowed(storageId uuid.UUID) bool {
+	return p.AllStorages || slices.Contains(p.Storages, storageId)
+}
+
+type principalCtxKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalCtxKey{}, principal)
+}
+
+// PrincipalFromContext returns an authenticated client. ok is false when authentication is disabled.
+func PrincipalFromContext(ctx context.Context) (principal *Principal, ok bool) {
+	principal, ok = ctx.Value(principalCtxKey{}).(*Principal)
+
+	return principal, ok
+}
+
+const keyPrefix = "cern_"
+
+// GenerateKey returns a new random API key
+func GenerateKey() (string, error) {
+	secret := make([]byte, 32)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("error generate key. %w", err)
+	}
+
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// HashKey returns a hex encoded SHA-256 hash of an API key. Keys are random, so no salt is needed.
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Claims of operator tokens
+type Claims struct {
+	jwt.RegisteredClaims
+
+	Scope    string   `json:"scope"`    // Space separated scopes
+	Storages []string `json:"storages"` // Storages the operator may act in. Ignored with admin scope
+}
+
+type JWTOptions struct {
+	KeysFile string // JWKS file with public keys of asymmetric signatures
+	Secret   string // HMAC secret. Used if no KeysFile passed
+	Issuer   string // Required iss claim, if passed
+	Audience string // Required aud claim, if passed
+}
+
+// JWTAuthenticator authenticates clients by bearer tokens
+type JWTAuthenticator struct {
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+}
+
+func NewJWTAuthenticator(opts JWTOptions) (*JWTAuthenticator, error) {
+	parserOpts := []jwt.ParserOption{jwt.WithExpirationRequired()}
+
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+
+	authenticator := new(JWTAuthenticator)
+
+	switch {
+	case opts.KeysFile != "":
+		keys, err := LoadJWKS(opts.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("error load jwks. %w", err)
+		}
+
+		parserOpts = append(parserOpts, jwt.WithValidMethods([]string{
+			"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512",
+		}))
+		authenticator.keyFunc = jwksKeyFunc(keys)
+	case opts.Secret != "":
+		parserOpts = append(parserOpts, jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))
+		authenticator.keyFunc = func(*jwt.Token) (any, error) {
+			return []byte(opts.Secret), nil
+		}
+	default:
+		return nil, errors.New("error neither jwks file nor secret is passed")
+	}
+
+	authenticator.parser = jwt.NewParser(parserOpts...)
+
+	return authenticator, nil
+}
+
+func jwksKeyFunc(keys map[string]crypto.PublicKey) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		if key, ok := keys[kid]; ok {
+			return key, nil
+		}
+
+		if kid == "" && len(keys) == 1 {
+			for _, key := range keys {
+				return key, nil
+			}
+		}
+
+		return nil, fmt.Errorf("error unknown key id %s", kid)
+	}
+}
+
+func (a *JWTAuthenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if token == "" {
+		return nil, fmt.Errorf("error bearer token is not passed. %w", ErrorUnauthorized)
+	}
+
+	claims := new(Claims)
+
+	if _, err := a.parser.ParseWithClaims(token, claims, a.keyFunc); err != nil {
+		return nil, errors.Join(fmt.Errorf("error parse token. %w", err), ErrorUnauthorized)
+	}
+
+	principal := &Principal{
+		Id:     claims.Subject,
+		Name:   claims.Subject,
+		Scopes: strings.Fields(claims.Scope),
+	}
+
+	principal.AllStorages = principal.Has(ScopeAdmin)
+
+	for _, id := range claims.Storages {
+		storageId, err := uuid.Parse(id)
+		if err != nil {
+			return nil, errors.Join(fmt.Errorf("error parse storage id claim. %w", err), ErrorUnauthorized)
+		}
+
+		principal.Storages = append(principal.Storages, storageId)
+	}
+
+	return principal, nil
+}
@@ -1,9 +1,312 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+var ErrorInvalidConfig = errors.New("invalid config")
+
+// Config is filled from defaults, then a config file, then CERNUNNOS_* environment variables, then
+// CLI flags. Each source overrides values set by previous ones. Environment variable names are
+// built from yaml keys: timeouts.read is CERNUNNOS_TIMEOUTS_READ.
 type Config struct {
-	Address          string
-	LogLevel         string
-	DatabaseHost     string
-	DatabaseUser     string
-	DatabasePassword string
+	Version          string     `yaml:"-" toml:"-"` // Build version reported by status endpoint
+	Address          string     `yaml:"address" toml:"address"`
+	GRPC             GRPC       `yaml:"grpc" toml:"grpc"`
+	LogLevel         string     `yaml:"log_level" toml:"log_level"`
+	DatabaseHost     string     `yaml:"database_host" toml:"database_host"`
+	DatabaseUser     string     `yaml:"database_user" toml:"database_user"`
+	DatabasePassword string     `yaml:"database_password" toml:"database_password" secret:"true"`
+	Database         Database   `yaml:"database" toml:"database"`
+	AuthMode         string     `yaml:"auth_mode" toml:"auth_mode"`           // none, apikey or jwt
+	TraceExporter    string     `yaml:"trace_exporter" toml:"trace_exporter"` // none, stdout, file or otlp
+	TraceFile        string     `yaml:"trace_file" toml:"trace_file"`         // Spans output for file exporter
+	JWT              JWT        `yaml:"jwt" toml:"jwt"`
+	Timeouts         Timeouts   `yaml:"timeouts" toml:"timeouts"`
+	RateLimits       RateLimits `yaml:"rate_limits" toml:"rate_limits"`
+	Outbox           Outbox     `yaml:"outbox" toml:"outbox"`
+	Webhooks         Webhooks   `yaml:"webhooks" toml:"webhooks"`
+
+	// Responses of writes with Idempotency-Key header are replayed for retries within this time
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl" toml:"idempotency_ttl"`
+}
+
+// GRPC configures gRPC API served along with HTTP API
+type GRPC struct {
+	Address    string `yaml:"address" toml:"address"`       // Empty address disables gRPC API
+	Reflection bool   `yaml:"reflection" toml:"reflection"` // Serve reflection service for grpcurl and alike
+}
+
+// Webhooks configures delivery of events to webhook subscriptions
+type Webhooks struct {
+	Enabled      bool          `yaml:"enabled" toml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size"`
+	Timeout      time.Duration `yaml:"timeout" toml:"timeout"`           // Delivery request timeout
+	MaxAttempts  int           `yaml:"max_attempts" toml:"max_attempts"` // Failed deliveries are dead after
+	BackoffBase  time.Duration `yaml:"backoff_base" toml:"backoff_base"` // Doubled after every failed attempt
+	BackoffMax   time.Duration `yaml:"backoff_max" toml:"backoff_max"`
+}
+
+// Outbox configures publishing of domain events
+type Outbox struct {
+	Publisher    string        `yaml:"publisher" toml:"publisher"` // none, stdout, file or webhook. none disables relay
+	File         string        `yaml:"file" toml:"file"`           // Events output for file publisher
+	WebhookURL   string        `yaml:"webhook_url" toml:"webhook_url" secret:"true"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size" toml:"batch_size"`
+}
+
+// Database connection options besides host and credentials. Zero timeouts and limits mean no limit
+type Database struct {
+	Name              string        `yaml:"name" toml:"name"`
+	SSLMode           string        `yaml:"sslmode" toml:"sslmode"`         // disable, require, verify-ca, verify-full
+	SSLRootCert       string        `yaml:"sslrootcert" toml:"sslrootcert"` // CA certificate file
+	SSLCert           string        `yaml:"sslcert" toml:"sslcert"`         // Client certificate file
+	SSLKey            string        `yaml:"sslkey" toml:"sslkey"`           // Client key file
+	ApplicationName   string        `yaml:"application_name" toml:"application_name"`
+	ReplicaHost       string        `yaml:"replica_host" toml:"replica_host"` // Listings run here, if passed
+	StatementTimeout  time.Duration `yaml:"statement_timeout" toml:"statement_timeout"`
+	MaxOpenConns      int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns      int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime   time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnectAttempts   int           `yaml:"connect_attempts" toml:"connect_attempts"` // Startup ping attempts
+	ConnectRetryDelay time.Duration `yaml:"connect_retry_delay" toml:"connect_retry_delay"`
+}
+
+// RateLimits are per client. Zero requests per second disables limiting
+type RateLimits struct {
+	ReadRPS    float64 `yaml:"read_rps" toml:"read_rps"`
+	ReadBurst  int     `yaml:"read_burst" toml:"read_burst"`
+	WriteRPS   float64 `yaml:"write_rps" toml:"write_rps"`
+	WriteBurst int     `yaml:"write_burst" toml:"write_burst"`
+}
+
+type Timeouts struct {
+	Read     time.Duration `yaml:"read" toml:"read"`         // Reading an entire request, including body
+	Write    time.Duration `yaml:"write" toml:"write"`       // Writing a response. Must exceed request handling timeout
+	Idle     time.Duration `yaml:"idle" toml:"idle"`         // Keep-alive connection idle time
+	Shutdown time.Duration `yaml:"shutdown" toml:"shutdown"` // Draining in-flight requests on shutdown
+}
+
+// Authentication modes
+const (
+	AuthNone   = "none"
+	AuthAPIKey = "apikey"
+	AuthJWT    = "jwt"
+)
+
+type JWT struct {
+	KeysFile string `yaml:"keys_file" toml:"keys_file"`         // JWKS file with token signing public keys
+	Secret   string `yaml:"secret" toml:"secret" secret:"true"` // HMAC secret. Used if no KeysFile passed
+	Issuer   string `yaml:"issuer" toml:"issuer"`
+	Audience string `yaml:"audience" toml:"audience"`
+}
+
+// Default returns config with default values
+func Default() Config {
+	return Config{
+		Address:  "localhost:8080",
+		LogLevel: "debug",
+		AuthMode: AuthAPIKey,
+		GRPC: GRPC{
+			Address:    "localhost:9090",
+			Reflection: true,
+		},
+		Database: Database{
+			Name:              "cernunnos",
+			SSLMode:           "disable",
+			ApplicationName:   "cernunnos",
+			MaxOpenConns:      20,
+			MaxIdleConns:      10,
+			ConnMaxLifetime:   30 * time.Minute,
+			ConnectAttempts:   5,
+			ConnectRetryDelay: 2 * time.Second,
+		},
+		TraceExporter: "none",
+		TraceFile:     "traces.json",
+		Timeouts: Timeouts{
+			Read:     10 * time.Second,
+			Write:    20 * time.Second,
+			Idle:     60 * time.Second,
+			Shutdown: 30 * time.Second,
+		},
+		RateLimits: RateLimits{
+			ReadRPS:    50,
+			ReadBurst:  100,
+			WriteRPS:   10,
+			WriteBurst: 20,
+		},
+		Outbox: Outbox{
+			Publisher:    "none",
+			File:         "events.jsonl",
+			PollInterval: time.Second,
+			BatchSize:    100,
+		},
+		Webhooks: Webhooks{
+			PollInterval: time.Second,
+			BatchSize:    20,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  10,
+			BackoffBase:  10 * time.Second,
+			BackoffMax:   time.Hour,
+		},
+		IdempotencyTTL: 24 * time.Hour,
+	}
+}
+
+// Validate reports all invalid values at once
+func (c *Config) Validate() error {
+	var errs []error
+
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Address == "" {
+		invalid("address is required")
+	}
+
+	if c.GRPC.Address != "" && c.GRPC.Address == c.Address {
+		invalid("grpc.address must differ from address")
+	}
+
+	if !slices.Contains([]string{"debug", "info", "warn", "warning", "error", "err"}, c.LogLevel) {
+		invalid("log_level %q is unknown. Use debug, info, warn or error", c.LogLevel)
+	}
+
+	if c.DatabaseHost == "" {
+		invalid("database_host is required")
+	}
+
+	if c.DatabaseUser == "" {
+		invalid("database_user is required")
+	}
+
+	if c.Database.Name == "" {
+		invalid("database.name is required")
+	}
+
+	if !slices.Contains([]string{"disable", "require", "verify-ca", "verify-full"}, c.Database.SSLMode) {
+		invalid("database.sslmode %q is unknown. Use disable, require, verify-ca or verify-full", c.Database.SSLMode)
+	}
+
+	if c.Database.StatementTimeout < 0 || c.Database.ConnMaxLifetime < 0 || c.Database.ConnectRetryDelay < 0 {
+		invalid("database timeouts must not be negative")
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		invalid("database connections limits must not be negative")
+	}
+
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		invalid("database.max_idle_conns must not exceed database.max_open_conns")
+	}
+
+	if c.Database.ConnectAttempts < 1 {
+		invalid("database.connect_attempts must be positive")
+	}
+
+	switch c.AuthMode {
+	case AuthNone, AuthAPIKey:
+	case AuthJWT:
+		if c.JWT.KeysFile == "" && c.JWT.Secret == "" {
+			invalid("jwt.keys_file or jwt.secret is required with jwt auth_mode")
+		}
+	default:
+		invalid("auth_mode %q is unknown. Use none, apikey or jwt", c.AuthMode)
+	}
+
+	switch c.TraceExporter {
+	case "none", "stdout", "otlp":
+	case "file":
+		if c.TraceFile == "" {
+			invalid("trace_file is required with file trace_exporter")
+		}
+	default:
+		invalid("trace_exporter %q is unknown. Use none, stdout, file or otlp", c.TraceExporter)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"timeouts.read", c.Timeouts.Read},
+		{"timeouts.write", c.Timeouts.Write},
+		{"timeouts.idle", c.Timeouts.Idle},
+		{"timeouts.shutdown", c.Timeouts.Shutdown},
+	}
+
+	for _, timeout := range timeouts {
+		if timeout.value <= 0 {
+			invalid("%s must be positive", timeout.name)
+		}
+	}
+
+	if c.RateLimits.ReadRPS < 0 || c.RateLimits.WriteRPS < 0 {
+		invalid("rate_limits rps must not be negative")
+	}
+
+	if c.RateLimits.ReadBurst < 0 || c.RateLimits.WriteBurst < 0 {
+		invalid("rate_limits burst must not be negative")
+	}
+
+	switch c.Outbox.Publisher {
+	case "none", "stdout":
+	case "file":
+		if c.Outbox.File == "" {
+			invalid("outbox.file is required with file outbox.publisher")
+		}
+	case "webhook":
+		if u, err := url.Parse(c.Outbox.WebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+			// The URL is not printed, as it may carry credentials
+			invalid("outbox.webhook_url must be an absolute URL with webhook outbox.publisher")
+		}
+	default:
+		invalid("outbox.publisher %q is unknown. Use none, stdout, file or webhook", c.Outbox.Publisher)
+	}
+
+	if c.Outbox.PollInterval <= 0 {
+		invalid("outbox.poll_interval must be positive")
+	}
+
+	if c.Outbox.BatchSize < 1 {
+		invalid("outbox.batch_size must be positive")
+	}
+
+	if c.Webhooks.Enabled {
+		webhookDurations := []struct {
+			name  string
+			value time.Duration
+		}{
+			{"webhooks.poll_interval", c.Webhooks.PollInterval},
+			{"webhooks.timeout", c.Webhooks.Timeout},
+			{"webhooks.backoff_base", c.Webhooks.BackoffBase},
+			{"webhooks.backoff_max", c.Webhooks.BackoffMax},
+		}
+
+		for _, duration := range webhookDurations {
+			if duration.value <= 0 {
+				invalid("%s must be positive", duration.name)
+			}
+		}
+
+		if c.Webhooks.BatchSize < 1 || c.Webhooks.MaxAttempts < 1 {
+			invalid("webhooks.batch_size and webhooks.max_attempts must be positive")
+		}
+	}
+
+	if c.IdempotencyTTL <= 0 {
+		invalid("idempotency_ttl must be positive")
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(append([]error{ErrorInvalidConfig}, errs...)...)
+	}
+
+	return nil
 }
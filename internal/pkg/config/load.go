@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const EnvPrefix = "CERNUNNOS_"
+
+// LoadFile overrides config values with values set in a YAML or TOML file. Format is chosen by
+// the file extension.
+func LoadFile(path string, c *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error read config file %s. %w", path, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+
+		if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error decode config file %s. %w. %w", path, err, ErrorInvalidConfig)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("error decode config file %s. %w. %w", path, err, ErrorInvalidConfig)
+		}
+
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("error unknown config file keys %v. %w", undecoded, ErrorInvalidConfig)
+		}
+	default:
+		return fmt.Errorf("error config file %s must be .yaml, .yml or .toml. %w", path, ErrorInvalidConfig)
+	}
+
+	return nil
+}
+
+// LoadEnv overrides config values with CERNUNNOS_* environment variables
+func LoadEnv(lookup func(key string) (string, bool), c *Config) error {
+	fields := reflect.ValueOf(c).Elem()
+
+	return walk(fields, nil, func(path []string, field reflect.Value, _ reflect.StructField) error {
+		key := EnvPrefix + strings.ToUpper(strings.Join(path, "_"))
+
+		raw, ok := lookup(key)
+		if !ok {
+			return nil
+		}
+
+		if err := setValue(field, raw); err != nil {
+			return fmt.Errorf("error parse %s. %w. %w", key, err, ErrorInvalidConfig)
+		}
+
+		return nil
+	})
+}
+
+// Redacted returns a copy of config with secrets hidden. Use it to log or print config
+func (c Config) Redacted() Config {
+	redacted := c
+
+	fields := reflect.ValueOf(&redacted).Elem()
+
+	_ = walk(fields, nil, func(_ []string, field reflect.Value, f reflect.StructField) error {
+		if f.Tag.Get("secret") == "true" && field.String() != "" {
+			field.SetString("******")
+		}
+
+		return nil
+	})
+
+	return redacted
+}
+
+// LogValue logs config by yaml keys with secrets hidden
+func (c Config) LogValue() slog.Value {
+	redacted := c.Redacted()
+	attrs := make([]slog.Attr, 0)
+
+	fields := reflect.ValueOf(&redacted).Elem()
+
+	_ = walk(fields, nil, func(path []string, field reflect.Value, _ reflect.StructField) error {
+		value := field.Interface()
+
+		if duration, ok := value.(time.Duration); ok {
+			value = duration.String()
+		}
+
+		attrs = append(attrs, slog.Any(strings.Join(path, "."), value))
+
+		return nil
+	})
+
+	return slog.GroupValue(attrs...)
+}
+
+// walk calls fn for every leaf field having a yaml key. path is a list of yaml keys to the field
+func walk(
+	v reflect.Value,
+	path []string,
+	fn func(path []string, field reflect.Value, f reflect.StructField) error,
+) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		fieldPath := append(append([]string(nil), path...), name)
+
+		if f.Type.Kind() == reflect.Struct {
+			if err := walk(v.Field(i), fieldPath, fn); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := fn(fieldPath, v.Field(i), f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setValue(field reflect.Value, raw string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(int64(value))
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+
+		field.SetBool(value)
+	case reflect.Int, reflect.Int64:
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(value)
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(value)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
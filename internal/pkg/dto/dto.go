@@ -1,14 +1,31 @@
 package dto
 
+// StoragesFilter narrows and orders storages listing
+type StoragesFilter struct {
+	// Sort field: name, available, reserved, created_at or updated_at.
+	// Descending order: "-name" or "name:desc"
+	Sort         string  `json:"sort,omitempty"`
+	NamePrefix   string  `json:"name_prefix,omitempty"`
+	NameContains string  `json:"name_contains,omitempty"`
+	AvailableGt  *int64  `json:"available_gt,omitempty"`
+	UpdatedSince *uint64 `json:"updated_since,omitempty"` // unix milli
+}
+
 type StoragesRequest struct {
-	Ids    []string `json:"ids,omitempty"`
-	Limit  uint32   `json:"limit,omitempty"`
-	Offset uint32   `json:"offset,omitempty"`
+	StoragesFilter
+
+	Ids       []string `json:"ids,omitempty"`
+	Limit     uint32   `json:"limit,omitempty"`
+	Offset    uint32   `json:"offset,omitempty"`
+	Cursor    string   `json:"cursor,omitempty"`     // next_cursor from a previous page. Overrides offset
+	WithTotal bool     `json:"with_total,omitempty"` // Count storages matching the filter
 }
 
 type StoragesResponse struct {
-	Storages []*Storage `json:"storages"`
-	Offset   uint32     `json:"offset"`
+	Storages   []*Storage `json:"storages"`
+	Offset     uint32     `json:"offset"`                // Offset of the next page
+	NextCursor string     `json:"next_cursor,omitempty"` // Empty if the page is the last one
+	Total      *int64     `json:"total,omitempty"`
 }
 
 // Storage DTO object
@@ -22,16 +39,23 @@ type Storage struct {
 }
 
 type StorageProductsRequest struct {
+	ProductsFilter
+
 	StorageId       string   // Fetched from URL params
 	ProductsIds     []string `json:"ids,omitempty"`
+	AsOf            *uint64  `json:"as_of,omitempty"` // unix milli. Amounts at the point in time
 	WithUnavailable bool     `json:"with_unavailable,omitempty"`
 	Limit           uint32   `json:"limit,omitempty"`
 	Offset          uint32   `json:"offset,omitempty"`
+	Cursor          string   `json:"cursor,omitempty"`     // next_cursor from a previous page. Overrides offset
+	WithTotal       bool     `json:"with_total,omitempty"` // Count products matching the filter
 }
 
 type StorageProductsResponse struct {
-	Products []*StorageProduct `json:"products"`
-	Offset   uint32            `json:"offset"`
+	Products   []*StorageProduct `json:"products"`
+	Offset     uint32            `json:"offset"`                // Offset of the next page
+	NextCursor string            `json:"next_cursor,omitempty"` // Empty if the page is the last one
+	Total      *int64            `json:"total,omitempty"`
 }
 
 type StorageProduct struct {
@@ -48,17 +72,46 @@ type ProductInfo struct {
 	DestributionInfo []*ProductDestribution `json:"destribution_info,omitempty"`
 }
 
+// ProductsFilter narrows and orders products listing
+type ProductsFilter struct {
+	// Sort field: name, size, created_at, updated_at or available.
+	// Descending order: "-name" or "name:desc"
+	Sort         string  `json:"sort,omitempty"`
+	NamePrefix   string  `json:"name_prefix,omitempty"`
+	NameContains string  `json:"name_contains,omitempty"`
+	SizeMin      *int64  `json:"size_min,omitempty"`
+	SizeMax      *int64  `json:"size_max,omitempty"`
+	AvailableGt  *int64  `json:"available_gt,omitempty"`
+	UpdatedSince *uint64 `json:"updated_since,omitempty"` // unix milli
+}
+
 type ProductsRequest struct {
+	ProductsFilter
+
 	Ids             []string `json:"ids,omitempty"`
 	StorageId       string   `json:"storage_id,omitempty"`
+	AsOf            *uint64  `json:"as_of,omitempty"` // unix milli. Amounts at the point in time
 	WithUnavailable bool     `json:"with_unavailable,omitempty"`
-	Limit           uint32   `json:"limit"`  // Amount of items to fetch. Default and max 500
-	Offset          uint32   `json:"offset"` // Pagination
+	Limit           uint32   `json:"limit"`                // Amount of items to fetch. Default and max 500
+	Offset          uint32   `json:"offset"`               // Pagination
+	Cursor          string   `json:"cursor,omitempty"`     // next_cursor from a previous page. Overrides offset
+	WithTotal       bool     `json:"with_total,omitempty"` // Count products matching the filter
+}
+
+type SearchProductsRequest struct {
+	Query     string `json:"q"`
+	StorageId string `json:"storage_id,omitempty"` // Search products available in the storage only
+	Limit     uint32 `json:"limit,omitempty"`      // Default and max 500
+	Offset    uint32 `json:"offset,omitempty"`
+	Cursor    string `json:"cursor,omitempty"`     // next_cursor from a previous page. Overrides offset
+	WithTotal bool   `json:"with_total,omitempty"` // Count products matching the query
 }
 
 type ProductsResponse struct {
-	Products []*ProductInfo `json:"products"`
-	Offset   uint32         `json:"offset"`
+	Products   []*ProductInfo `json:"products"`
+	Offset     uint32         `json:"offset"`                // Offset of the next page
+	NextCursor string         `json:"next_cursor,omitempty"` // Empty if the page is the last one
+	Total      *int64         `json:"total,omitempty"`
 }
 
 type ProductDestribution struct {
@@ -69,6 +122,7 @@ type ProductDestribution struct {
 }
 
 type Reservation struct {
+	Id         string `json:"id"`
 	StorageId  string `json:"storage_id"`
 	ProductId  string `json:"product_id"`
 	ShippingId string `json:"shipping_id"`
@@ -83,11 +137,15 @@ type ReservationsRequest struct {
 	ShippingId string `json:"shipping_id,omitempty"`
 	Limit      uint32 `json:"limit,omitempty"`
 	Offset     uint32 `json:"offset,omitempty"`
+	Cursor     string `json:"cursor,omitempty"`     // next_cursor from a previous page. Overrides offset
+	WithTotal  bool   `json:"with_total,omitempty"` // Count reservations matching the filter
 }
 
 type ReservationsResponse struct {
 	Reservations []*Reservation `json:"reservations"`
-	Offset       uint32         `json:"offset"`
+	Offset       uint32         `json:"offset"`                // Offset of the next page
+	NextCursor   string         `json:"next_cursor,omitempty"` // Empty if the page is the last one
+	Total        *int64         `json:"total,omitempty"`
 }
 
 type ReserveRequest struct {
@@ -120,3 +178,124 @@ type CancelRequest struct {
 type CancelResponse struct {
 	Ok bool `json:"ok"`
 }
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`      // Generated, if not passed
+	EventTypes []string `json:"event_types,omitempty"` // All events, if empty
+	ProductId  string   `json:"product_id,omitempty"`  // Deliver events of the product only
+	StorageId  string   `json:"storage_id,omitempty"`  // Deliver events in the storage only
+}
+
+// CreateWebhookResponse is the only response carrying the subscription secret
+type CreateWebhookResponse struct {
+	Webhook *Webhook `json:"webhook"`
+	Secret  string   `json:"secret"`
+}
+
+type WebhooksResponse struct {
+	Webhooks []*Webhook `json:"webhooks"`
+}
+
+type Webhook struct {
+	Id         string   `json:"id"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	ProductId  string   `json:"product_id,omitempty"`
+	StorageId  string   `json:"storage_id,omitempty"`
+	CreatedAt  uint64   `json:"created_at"` // unix milli
+}
+
+type DeleteWebhookResponse struct {
+	Ok bool `json:"ok"`
+}
+
+type WebhookDeliveriesRequest struct {
+	WebhookId string // Fetched from URL params
+	Status    string `json:"status,omitempty"` // pending, delivered or dead
+	Limit     uint32 `json:"limit,omitempty"`
+	Offset    uint32 `json:"offset,omitempty"`
+	Cursor    string `json:"cursor,omitempty"` // next_cursor from a previous page. Overrides offset
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	Offset     uint32             `json:"offset"`                // Offset of the next page
+	NextCursor string             `json:"next_cursor,omitempty"` // Empty if the page is the last one
+}
+
+type WebhookDelivery struct {
+	Id             string  `json:"id"`
+	WebhookId      string  `json:"webhook_id"`
+	EventId        string  `json:"event_id"`
+	EventType      string  `json:"event_type"`
+	Status         string  `json:"status"`
+	Attempts       int     `json:"attempts"`
+	NextAttemptAt  uint64  `json:"next_attempt_at"` // unix milli. Meaningful for pending deliveries only
+	LastStatusCode int     `json:"last_status_code,omitempty"`
+	LastError      string  `json:"last_error,omitempty"`
+	CreatedAt      uint64  `json:"created_at"`             // unix milli
+	UpdatedAt      uint64  `json:"updated_at"`             // unix milli
+	DeliveredAt    *uint64 `json:"delivered_at,omitempty"` // unix milli
+}
+
+type ReplayWebhookDeliveryResponse struct {
+	Ok bool `json:"ok"`
+}
+
+type StockStreamRequest struct {
+	ProductId string `json:"product_id,omitempty"`
+	StorageId string `json:"storage_id,omitempty"`
+	// Id of the last received event. Last-Event-ID header overrides it
+	LastEventId string `json:"last_event_id,omitempty"`
+}
+
+// StockChange is data of stock stream events
+type StockChange struct {
+	ProductId string `json:"product_id"`
+	StorageId string `json:"storage_id"`
+	Amount    int64  `json:"amount"`
+	Reserved  int64  `json:"reserved"`
+	Available int64  `json:"available"`
+	ChangedAt uint64 `json:"changed_at"` // unix milli
+}
+
+// ImportRequest describes an uploaded import file. The request body is the file itself
+type ImportRequest struct {
+	Kind   string // Fetched from URL params
+	Format string `json:"format,omitempty"`  // csv or jsonl. Default: csv
+	DryRun bool   `json:"dry_run,omitempty"` // Validate and load rows, then roll them back
+}
+
+// ImportResponse reports an import. Rows are committed only if none of them is rejected
+type ImportResponse struct {
+	Kind      string            `json:"kind"`
+	DryRun    bool              `json:"dry_run"`
+	Rows      int64             `json:"rows"`
+	Rejected  int64             `json:"rejected"`
+	Errors    []*ImportRowError `json:"errors"` // First 100 rejected rows
+	Committed bool              `json:"committed"`
+}
+
+type ImportRowError struct {
+	Line  int64  `json:"line"`
+	Error string `json:"error"`
+}
+
+type ExportStockRequest struct {
+	Format     string   `json:"format,omitempty"`     // csv or jsonl. Default: csv
+	StorageIds []string `json:"storage_id,omitempty"` // Any storage if empty
+	ProductIds []string `json:"product_id,omitempty"` // Any product if empty
+}
+
+// StockRow is a row of stock exports. CSV columns are named as JSON fields
+type StockRow struct {
+	StorageId   string `json:"storage_id"`
+	StorageName string `json:"storage_name"`
+	ProductId   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	ProductSize int64  `json:"product_size"`
+	Amount      int64  `json:"amount"`
+	Reserved    int64  `json:"reserved"`
+	Available   int64  `json:"available"`
+}
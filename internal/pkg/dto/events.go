@@ -0,0 +1,29 @@
+package dto
+
+import "encoding/json"
+
+// Event is an envelope of published domain events
+type Event struct {
+	Id         string          `json:"id"` // Unique. Use it to deduplicate redelivered events
+	Type       string          `json:"type"`
+	OccurredAt uint64          `json:"occurred_at"` // unix milli
+	Data       json.RawMessage `json:"data"`
+}
+
+// ReservationEvent is data of ReservationCreated, ReservationCancelled and ReservationReleased
+// events. An event is published for every storage the products are reserved in.
+type ReservationEvent struct {
+	ShippingId string `json:"shipping_id"`
+	ProductId  string `json:"product_id"`
+	StorageId  string `json:"storage_id"`
+	Amount     int64  `json:"amount"`
+}
+
+// StockLevelEvent is data of StockLevelChanged and StockDepleted events. Levels are ones after the change.
+type StockLevelEvent struct {
+	ProductId string `json:"product_id"`
+	StorageId string `json:"storage_id"`
+	Amount    int64  `json:"amount"`
+	Reserved  int64  `json:"reserved"`
+	Available int64  `json:"available"`
+}
@@ -3,6 +3,7 @@ package dto
 import (
 	"cernunnos/internal/pkg/models"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -41,6 +42,17 @@ func MapStorageFromModel(model *models.Storage) (*Storage, error) {
 	}, nil
 }
 
+// MapUnixMilliToTime maps optional unix milli timestamp to time
+func MapUnixMilliToTime(milli *uint64) *time.Time {
+	if milli == nil {
+		return nil
+	}
+
+	t := time.UnixMilli(int64(*milli))
+
+	return &t
+}
+
 func MapIdsToUUIDs(ids []string) (uuid.UUIDs, error) {
 	uuids := make(uuid.UUIDs, len(ids))
 
@@ -199,6 +211,7 @@ func MapReservationFromModel(model *models.Reservation) (*Reservation, error) {
 	}
 
 	return &Reservation{
+		Id:         model.Id.String(),
 		StorageId:  model.StorageId.String(),
 		ProductId:  model.ReservedProduct.Id.String(),
 		ShippingId: model.ShippingId.String(),
@@ -207,3 +220,145 @@ func MapReservationFromModel(model *models.Reservation) (*Reservation, error) {
 		UpdatedAt:  uint64(model.UpdatedAt.UnixMilli()),
 	}, nil
 }
+
+func MapEventFromModel(event *models.Event) *Event {
+	return &Event{
+		Id:         event.Id.String(),
+		Type:       event.Type,
+		OccurredAt: uint64(event.CreatedAt.UnixMilli()),
+		Data:       event.Payload,
+	}
+}
+
+func MapWebhooksFromModels(models []*models.WebhookSubscription) ([]*Webhook, error) {
+	mapped := make([]*Webhook, len(models))
+
+	for i, model := range models {
+		dto, err := MapWebhookFromModel(model)
+		if err != nil {
+			return nil, fmt.Errorf("error map webhook to dto. %w", err)
+		}
+
+		mapped[i] = dto
+	}
+
+	return mapped, nil
+}
+
+func MapWebhookFromModel(model *models.WebhookSubscription) (*Webhook, error) {
+	if model == nil {
+		return nil, fmt.Errorf("error nil webhook subscription model")
+	}
+
+	webhook := &Webhook{
+		Id:         model.Id.String(),
+		URL:        model.URL,
+		EventTypes: model.EventTypes,
+		CreatedAt:  uint64(model.CreatedAt.UnixMilli()),
+	}
+
+	if model.ProductId != uuid.Nil {
+		webhook.ProductId = model.ProductId.String()
+	}
+
+	if model.StorageId != uuid.Nil {
+		webhook.StorageId = model.StorageId.String()
+	}
+
+	return webhook, nil
+}
+
+func MapWebhookDeliveriesFromModels(models []*models.WebhookDelivery) ([]*WebhookDelivery, error) {
+	mapped := make([]*WebhookDelivery, len(models))
+
+	for i, model := range models {
+		dto, err := MapWebhookDeliveryFromModel(model)
+		if err != nil {
+			return nil, fmt.Errorf("error map webhook delivery to dto. %w", err)
+		}
+
+		mapped[i] = dto
+	}
+
+	return mapped, nil
+}
+
+func MapWebhookDeliveryFromModel(model *models.WebhookDelivery) (*WebhookDelivery, error) {
+	if model == nil {
+		return nil, fmt.Errorf("error nil webhook delivery model")
+	}
+
+	delivery := &WebhookDelivery{
+		Id:             model.Id.String(),
+		WebhookId:      model.SubscriptionId.String(),
+		EventId:        model.EventId.String(),
+		EventType:      model.EventType,
+		Status:         model.Status,
+		Attempts:       model.Attempts,
+		NextAttemptAt:  uint64(model.NextAttemptAt.UnixMilli()),
+		LastStatusCode: model.LastStatusCode,
+		LastError:      model.LastError,
+		CreatedAt:      uint64(model.CreatedAt.UnixMilli()),
+		UpdatedAt:      uint64(model.UpdatedAt.UnixMilli()),
+	}
+
+	if model.DeliveredAt != nil {
+		deliveredAt := uint64(model.DeliveredAt.UnixMilli())
+		delivery.DeliveredAt = &deliveredAt
+	}
+
+	return delivery, nil
+}
+
+func MapStockChangeFromModel(model *models.StockChange) (*StockChange, error) {
+	if model == nil {
+		return nil, fmt.Errorf("error nil stock change model")
+	}
+
+	return &StockChange{
+		ProductId: model.ProductId.String(),
+		StorageId: model.StorageId.String(),
+		Amount:    model.Amount,
+		Reserved:  model.Reserved,
+		Available: model.Available,
+		ChangedAt: uint64(model.ChangedAt.UnixMilli()),
+	}, nil
+}
+
+func MapImportResultFromModel(model *models.ImportResult) (*ImportResponse, error) {
+	if model == nil {
+		return nil, fmt.Errorf("error nil import result model")
+	}
+
+	response := &ImportResponse{
+		Kind:      model.Kind,
+		DryRun:    model.DryRun,
+		Rows:      model.Rows,
+		Rejected:  model.Rejected,
+		Errors:    make([]*ImportRowError, len(model.Errors)),
+		Committed: model.Committed,
+	}
+
+	for i, rowError := range model.Errors {
+		response.Errors[i] = &ImportRowError{Line: rowError.Line, Error: rowError.Error}
+	}
+
+	return response, nil
+}
+
+func MapStockRowFromModel(model *models.StorageProduct) (*StockRow, error) {
+	if model == nil || model.Storage == nil {
+		return nil, fmt.Errorf("error nil storage product model")
+	}
+
+	return &StockRow{
+		StorageId:   model.Storage.Id.String(),
+		StorageName: model.Storage.Name,
+		ProductId:   model.Id.String(),
+		ProductName: model.Name,
+		ProductSize: model.Size,
+		Amount:      model.Amount,
+		Reserved:    model.Reserved,
+		Available:   model.Available,
+	}, nil
+}
@@ -0,0 +1,227 @@
+package dto
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var ErrorInvalidQuery = errors.New("invalid query parameter")
+
+// DecodeQuery fills request struct fields from URL query values. Fields are matched by their json
+// tag names. Slices can be passed either as comma separated values or as repeated parameters:
+// ?ids=a,b or ?ids=a&ids=b. Fields without json tag are skipped.
+func DecodeQuery(values url.Values, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("error decode query into %T. pointer to struct expected", dst)
+	}
+
+	return decodeQueryStruct(values, v.Elem())
+}
+
+func decodeQueryStruct(values url.Values, v reflect.Value) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, hasTag := field.Tag.Lookup("json")
+
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+			if err := decodeQueryStruct(values, v.Field(i)); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		raw, ok := values[name]
+		if !ok || len(raw) == 0 {
+			continue
+		}
+
+		if err := setQueryValue(v.Field(i), raw); err != nil {
+			return errors.Join(fmt.Errorf("error decode %s parameter. %w", name, err), ErrorInvalidQuery)
+		}
+	}
+
+	return nil
+}
+
+func setQueryValue(field reflect.Value, raw []string) error {
+	if field.Kind() == reflect.Slice {
+		items := make([]string, 0, len(raw))
+
+		for _, value := range raw {
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+
+		for i, item := range items {
+			if err := setScalarValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+
+		field.Set(slice)
+
+		return nil
+	}
+
+	return setScalarValue(field, raw[len(raw)-1])
+}
+
+func setScalarValue(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.Pointer:
+		value := reflect.New(field.Type().Elem())
+		if err := setScalarValue(value.Elem(), raw); err != nil {
+			return err
+		}
+
+		field.Set(value)
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		if raw == "" {
+			field.SetBool(true)
+
+			return nil
+		}
+
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("error parse bool. %w", err)
+		}
+
+		field.SetBool(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("error parse int. %w", err)
+		}
+
+		field.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("error parse uint. %w", err)
+		}
+
+		field.SetUint(value)
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("error parse float. %w", err)
+		}
+
+		field.SetFloat(value)
+	default:
+		return fmt.Errorf("error unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
+
+// EncodeQuery encodes request struct fields into URL query values, as DecodeQuery expects them.
+// Zero values of omitempty fields and fields without json tag are skipped. Slices are encoded as
+// repeated parameters.
+func EncodeQuery(src any) url.Values {
+	values := url.Values{}
+
+	v := reflect.ValueOf(src)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return values
+		}
+
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Struct {
+		encodeQueryStruct(values, v)
+	}
+
+	return values
+}
+
+func encodeQueryStruct(values url.Values, v reflect.Value) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, hasTag := field.Tag.Lookup("json")
+
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+			encodeQueryStruct(values, v.Field(i))
+
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		value := v.Field(i)
+		if value.IsZero() && strings.Contains(options, "omitempty") {
+			continue
+		}
+
+		if value.Kind() == reflect.Slice {
+			for j := 0; j < value.Len(); j++ {
+				values.Add(name, scalarValue(value.Index(j)))
+			}
+
+			continue
+		}
+
+		if value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				continue
+			}
+
+			value = value.Elem()
+		}
+
+		values.Set(name, scalarValue(value))
+	}
+}
+
+func scalarValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
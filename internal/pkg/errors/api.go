@@ -1,19 +1,42 @@
 package errors
 
 import (
+	"cernunnos/internal/pkg/auth"
+	"cernunnos/internal/pkg/ratelimit"
+	"cernunnos/internal/pkg/sqltools"
 	"cernunnos/internal/usecase/interactors"
 	"cernunnos/internal/usecase/repository/reservations"
+	"cernunnos/internal/usecase/repository/webhooks"
 	"errors"
 	"fmt"
 )
 
+// Reasons are stable machine readable error identifiers. Details are meant for humans and may change
+const (
+	ReasonBadRequest          = "bad_request"
+	ReasonInvalidCursor       = "invalid_cursor"
+	ReasonInvalidFilter       = "invalid_filter"
+	ReasonInvalidField        = "invalid_field"
+	ReasonFieldRequired       = "field_required"
+	ReasonUnauthorized        = "unauthorized"
+	ReasonForbidden           = "forbidden"
+	ReasonNotFound            = "not_found"
+	ReasonRateLimited         = "rate_limited"
+	ReasonNotEnoughProducts   = "not_enough_products"
+	ReasonNotEnoughSpace      = "not_enough_space"
+	ReasonIdempotencyMismatch = "idempotency_key_mismatch"
+	ReasonIdempotencyPending  = "idempotency_key_in_progress"
+	ReasonInternal            = "internal"
+)
+
 type APIError struct {
 	Code    uint16 `json:"code"`
+	Reason  string `json:"reason"`
 	Details string `json:"details"`
 }
 
 func (e APIError) Error() string {
-	return fmt.Sprintf("code: %v details: %s", e.Code, e.Details)
+	return fmt.Sprintf("code: %v reason: %s details: %s", e.Code, e.Reason, e.Details)
 }
 
 type ErrorHandler interface {
@@ -33,27 +56,68 @@ func NewErrorHandler() ErrorHandler {
 func (e *errorHandler) Handle(err error) APIError {
 	switch {
 	case errors.Is(err, ErrorBadRequest):
-		return e.errorBuilder.Build(400, "Bad Request!")
+		return e.errorBuilder.Build(400, ReasonBadRequest, "Bad Request!")
 	case errors.Is(err, ErrorInternalServerError):
-		return e.errorBuilder.Build(500, "Internal Server Error!")
+		return e.errorBuilder.Build(500, ReasonInternal, "Internal Server Error!")
 	case errors.Is(err, ErrorInvalidRequestPath):
-		return e.errorBuilder.Build(400, "Invalid Path!")
+		return e.errorBuilder.Build(400, ReasonBadRequest, "Invalid Path!")
 	case errors.Is(err, ErrorUnexpectedData):
-		return e.errorBuilder.Build(400, "Invalid Or Unexpected Request Data!")
+		return e.errorBuilder.Build(400, ReasonBadRequest, "Invalid Or Unexpected Request Data!")
+	case errors.Is(err, ErrorIdempotencyKeyMismatch):
+		return e.errorBuilder.Build(
+			422,
+			ReasonIdempotencyMismatch,
+			"Idempotency Key Is Already Used With Another Request! Generate a new key for every request",
+		)
+	case errors.Is(err, ErrorIdempotencyKeyInProgress):
+		return e.errorBuilder.Build(
+			409,
+			ReasonIdempotencyPending,
+			"Request With The Idempotency Key Is In Progress! Retry later",
+		)
+	case errors.Is(err, sqltools.ErrorInvalidCursor):
+		return e.errorBuilder.Build(400, ReasonInvalidCursor, "Invalid Cursor! Pass next_cursor from a previous page")
+	case errors.Is(err, sqltools.ErrorInvalidFilter):
+		return e.errorBuilder.Build(
+			400,
+			ReasonInvalidFilter,
+			"Invalid Filter Or Sort Field! See API Documentation for more info",
+		)
+	case errors.Is(err, auth.ErrorUnauthorized):
+		return e.errorBuilder.Build(401, ReasonUnauthorized, "Unauthorized! Pass a valid API key or bearer token")
+	case errors.Is(err, auth.ErrorForbidden):
+		return e.errorBuilder.Build(403, ReasonForbidden, "Forbidden! Operation is not allowed for the client")
+	case errors.Is(err, auth.ErrorInvalidScope):
+		return e.errorBuilder.Build(400, ReasonInvalidField, "Invalid Scope! Use read, reserve, release or admin")
+	case errors.Is(err, ratelimit.ErrorRateLimited):
+		return e.errorBuilder.Build(
+			429,
+			ReasonRateLimited,
+			"Too Many Requests! Retry after the time passed in Retry-After header",
+		)
 	case errors.Is(err, reservations.ErrorNotEnoughSpace):
-		return e.errorBuilder.Build(507, "Not Enough Space In Storage(s)!")
+		return e.errorBuilder.Build(507, ReasonNotEnoughSpace, "Not Enough Space In Storage(s)!")
+	case errors.Is(err, reservations.ErrorNotEnoughProducts):
+		return e.errorBuilder.Build(409, ReasonNotEnoughProducts, "Not Enough Products Available For Reservation!")
 	case errors.Is(err, interactors.ErrorFieldRequired):
 		return e.errorBuilder.Build(
 			400,
+			ReasonFieldRequired,
 			"Not All Required Fields Provided! See API Documentation for more info",
 		)
+	case errors.Is(err, interactors.ErrorInvalidField):
+		return e.errorBuilder.Build(400, ReasonInvalidField, "Invalid Field Value! See API Documentation for more info")
+	case errors.Is(err, webhooks.ErrorSubscriptionNotFound):
+		return e.errorBuilder.Build(404, ReasonNotFound, "Webhook Not Found!")
+	case errors.Is(err, webhooks.ErrorDeliveryNotFound):
+		return e.errorBuilder.Build(404, ReasonNotFound, "Webhook Delivery Not Found!")
 	default:
-		return e.errorBuilder.Build(500, "Oops! Something went wrong!")
+		return e.errorBuilder.Build(500, ReasonInternal, "Oops! Something went wrong!")
 	}
 }
 
 type ErrorBuilder interface {
-	Build(code uint16, details string) APIError
+	Build(code uint16, reason, details string) APIError
 }
 
 func NewErrorBuilder() ErrorBuilder {
@@ -62,9 +126,10 @@ func NewErrorBuilder() ErrorBuilder {
 
 type errorBuilder struct{}
 
-func (e *errorBuilder) Build(code uint16, details string) APIError {
+func (e *errorBuilder) Build(code uint16, reason, details string) APIError {
 	return APIError{
 		Code:    code,
+		Reason:  reason,
 		Details: details,
 	}
 }
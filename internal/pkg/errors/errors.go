@@ -7,4 +7,8 @@ var (
 	ErrorUnexpectedData      = errors.New("UNEXPECTED_DATA")
 	ErrorInvalidRequestPath  = errors.New("INVALID_REQUEST_PATH")
 	ErrorBadRequest          = errors.New("BAD_REQUEST")
+
+	ErrorIdempotencyKeyMismatch   = errors.New("IDEMPOTENCY_KEY_MISMATCH")
+	ErrorIdempotencyKeyInProgress = errors.New("IDEMPOTENCY_KEY_IN_PROGRESS")
+	ErrorIdempotencyClaimLost     = errors.New("IDEMPOTENCY_CLAIM_LOST") // Key is claimed again by a later request
 )
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOk      = "ok"
+	StatusFailing = "failing"
+)
+
+const checkTimeout time.Duration = 2 * time.Second
+
+// Check reports a dependency failure with a non nil error
+type Check func(ctx context.Context) error
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status  string                 `json:"status"`
+	Version string                 `json:"version"`
+	Uptime  string                 `json:"uptime"`
+	Checks  map[string]CheckResult `json:"checks"`
+}
+
+// Checker runs readiness checks of service dependencies
+type Checker struct {
+	version   string
+	startedAt time.Time
+	names     []string
+	checks    []Check
+	serial    []bool // Checks run alone before the concurrent ones
+}
+
+func NewChecker(version string) *Checker {
+	return &Checker{
+		version:   version,
+		startedAt: time.Now(),
+	}
+}
+
+// Add registers a named check. Checks must be added before the checker is used.
+func (c *Checker) Add(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks = append(c.checks, check)
+	c.serial = append(c.serial, false)
+}
+
+// AddSerial registers a named check run alone, before the other checks. For checks observing
+// resources other checks use, like connections of a pool.
+func (c *Checker) AddSerial(name string, check Check) {
+	c.names = append(c.names, name)
+	c.checks = append(c.checks, check)
+	c.serial = append(c.serial, true)
+}
+
+// Run runs serial checks one by one, then the others concurrently. Report status is failing if any
+// of checks failed.
+func (c *Checker) Run(ctx context.Context) *Report {
+	report := &Report{
+		Status:  StatusOk,
+		Version: c.version,
+		Uptime:  time.Since(c.startedAt).Round(time.Second).String(),
+		Checks:  make(map[string]CheckResult, len(c.checks)),
+	}
+
+	results := make([]CheckResult, len(c.checks))
+
+	for i, check := range c.checks {
+		if c.serial[i] {
+			results[i] = runCheck(ctx, check)
+		}
+	}
+
+	var wg sync.WaitGroup
+
+	for i, check := range c.checks {
+		if c.serial[i] {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			results[i] = runCheck(ctx, check)
+		}()
+	}
+
+	wg.Wait()
+
+	for i, result := range results {
+		if result.Status != StatusOk {
+			report.Status = StatusFailing
+		}
+
+		report.Checks[c.names[i]] = result
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	result := CheckResult{Status: StatusOk}
+
+	if err := check(ctx); err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+
+	result.Duration = time.Since(startedAt).String()
+
+	return result
+}
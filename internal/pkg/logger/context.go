@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestAttrs are attributes collected while a request passes through middlewares. They are
+// shared by all contexts derived from the request one, so attributes added by inner middlewares
+// are seen by outer ones, e.g. an access log.
+type requestAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+type requestAttrsCtxKey struct{}
+
+// WithRequestAttrs prepares ctx to collect attributes with AddAttrs
+func WithRequestAttrs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestAttrsCtxKey{}, new(requestAttrs))
+}
+
+// AddAttrs adds attributes to every record logged with ctx or any context of the same request.
+// Does nothing if ctx was not prepared with WithRequestAttrs.
+func AddAttrs(ctx context.Context, attrs ...slog.Attr) {
+	holder, ok := ctx.Value(requestAttrsCtxKey{}).(*requestAttrs)
+	if !ok {
+		return
+	}
+
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+
+	holder.attrs = append(holder.attrs, attrs...)
+}
+
+// ContextHandler adds request id, trace id and request attributes to records logged with a context.
+// They are added at the top level, even if the logger has groups, so every record of a request has
+// the same keys.
+type ContextHandler struct {
+	root    slog.Handler // Handler without groups and attributes added to the context handler
+	handler slog.Handler // root with the groups and attributes
+	scopes  []handlerScope
+}
+
+// handlerScope is either a group or attributes added to a handler, in the order they were added
+type handlerScope struct {
+	group string
+	attrs []slog.Attr
+}
+
+func NewContextHandler(handler slog.Handler) *ContextHandler {
+	return &ContextHandler{root: handler, handler: handler}
+}
+
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil {
+		return h.handler.Handle(ctx, r)
+	}
+
+	attrs := contextAttrs(ctx)
+	if len(attrs) == 0 {
+		return h.handler.Handle(ctx, r)
+	}
+
+	handler := h.root.WithAttrs(attrs)
+
+	for _, scope := range h.scopes {
+		if scope.group != "" {
+			handler = handler.WithGroup(scope.group)
+		} else {
+			handler = handler.WithAttrs(scope.attrs)
+		}
+	}
+
+	return handler.Handle(ctx, r)
+}
+
+// contextAttrs returns request id, trace id and request attributes of ctx
+func contextAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+
+	seen := make(map[string]struct{})
+
+	add := func(attr slog.Attr) {
+		if _, ok := seen[attr.Key]; ok {
+			return
+		}
+
+		seen[attr.Key] = struct{}{}
+
+		attrs = append(attrs, attr)
+	}
+
+	if requestId, ok := ctx.Value(chimw.RequestIDKey).(string); ok && requestId != "" {
+		add(slog.String("request_id", requestId))
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		add(slog.String("trace_id", span.TraceID().String()))
+		add(slog.String("span_id", span.SpanID().String()))
+	}
+
+	if holder, ok := ctx.Value(requestAttrsCtxKey{}).(*requestAttrs); ok {
+		holder.mu.Lock()
+		requestAttrs := append([]slog.Attr(nil), holder.attrs...)
+		holder.mu.Unlock()
+
+		for _, attr := range requestAttrs {
+			add(attr)
+		}
+	}
+
+	return attrs
+}
+
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	return &ContextHandler{
+		root:    h.root,
+		handler: h.handler.WithAttrs(attrs),
+		scopes:  append(slices.Clip(h.scopes), handlerScope{attrs: attrs}),
+	}
+}
+
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &ContextHandler{
+		root:    h.root,
+		handler: h.handler.WithGroup(name),
+		scopes:  append(slices.Clip(h.scopes), handlerScope{group: name}),
+	}
+}
@@ -15,14 +15,15 @@ func NewLocalLogger() *slog.Logger {
 
 	handler := opts.NewPrettyHandler(os.Stdout)
 
-	return slog.New(handler)
+	return slog.New(NewContextHandler(handler))
 }
 
-// NewLogger returns slog Logger with JSONHandler
+// NewLogger returns slog Logger with JSONHandler. Records logged with a context carry request
+// id, trace id and client identity.
 func NewLogger(lvl slog.Level) *slog.Logger {
-	return slog.New(
+	return slog.New(NewContextHandler(
 		slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: lvl}),
-	)
+	))
 }
 
 func Err(err error) slog.Attr {
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Transaction outcomes
+const (
+	TransactionCommitted  = "commit"
+	TransactionRolledBack = "rollback"
+	TransactionFailed     = "error" // Transaction could not be started or committed
+)
+
+var (
+	transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transactions_total",
+		Help:      "Total amount of database transactions by outcome.",
+	}, []string{"outcome"})
+
+	transactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "transaction_duration_seconds",
+		Help:      "Database transactions duration by outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+)
+
+func init() {
+	registry.MustRegister(transactions, transactionDuration)
+}
+
+// ObserveTransaction records a finished transaction
+func ObserveTransaction(outcome string, duration time.Duration) {
+	transactions.WithLabelValues(outcome).Inc()
+	transactionDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
+
+// RegisterDatabase exposes sql.DB connection pool stats. The returned function unregisters them.
+func RegisterDatabase(db *sql.DB, name string) (func(), error) {
+	collector := collectors.NewDBStatsCollector(db, name)
+
+	if err := registry.Register(collector); err != nil {
+		return func() {}, err
+	}
+
+	return func() {
+		registry.Unregister(collector)
+	}, nil
+}
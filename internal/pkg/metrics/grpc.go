@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Total amount of handled gRPC requests by method and status code.",
+	}, []string{"method", "code"})
+
+	grpcRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "gRPC request handling latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+func init() {
+	registry.MustRegister(grpcRequests, grpcRequestDuration)
+}
+
+// ObserveRPC starts observing a gRPC request. The returned function must be called with the
+// status code name when the request is handled.
+func ObserveRPC(method string) func(code string) {
+	startedAt := time.Now()
+
+	return func(code string) {
+		grpcRequestDuration.WithLabelValues(method).Observe(time.Since(startedAt).Seconds())
+		grpcRequests.WithLabelValues(method, code).Inc()
+	}
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	unitsReserved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "inventory",
+		Name:      "units_reserved_total",
+		Help:      "Total amount of product units reserved by storage.",
+	}, []string{"storage_id"})
+
+	unitsCancelled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "inventory",
+		Name:      "units_cancelled_total",
+		Help:      "Total amount of reserved product units returned to stock by storage.",
+	}, []string{"storage_id"})
+
+	unitsReleased = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "inventory",
+		Name:      "units_released_total",
+		Help:      "Total amount of reserved product units written off from stock by storage.",
+	}, []string{"storage_id"})
+
+	outOfStockRejections = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "inventory",
+		Name:      "out_of_stock_rejections_total",
+		Help:      "Total amount of reservations rejected because of not enough products.",
+	})
+)
+
+func init() {
+	registry.MustRegister(unitsReserved, unitsCancelled, unitsReleased, outOfStockRejections)
+}
+
+// UnitsReserved records product units reserved in a storage
+func UnitsReserved(storageId string, amount int64) {
+	unitsReserved.WithLabelValues(storageId).Add(float64(amount))
+}
+
+// UnitsCancelled records reserved product units returned to stock in a storage
+func UnitsCancelled(storageId string, amount int64) {
+	unitsCancelled.WithLabelValues(storageId).Add(float64(amount))
+}
+
+// UnitsReleased records reserved product units written off from stock in a storage
+func UnitsReleased(storageId string, amount int64) {
+	unitsReleased.WithLabelValues(storageId).Add(float64(amount))
+}
+
+// OutOfStockRejected records a reservation rejected because of not enough products
+func OutOfStockRejected() {
+	outOfStockRejections.Inc()
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cernunnos"
+
+// registry holds all service metrics. A dedicated registry is used instead of the global one
+// so only the metrics described here are exposed.
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Total amount of handled HTTP requests by method name and response code.",
+	}, []string{"method", "code"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request handling latency by method name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	httpRequestsInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "Amount of HTTP requests being handled by method name.",
+	}, []string{"method"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		httpRequestsInFlight,
+	)
+}
+
+// Handler serves metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest starts observing an HTTP request. The returned function must be called with
+// the response code when the request is handled.
+func ObserveRequest(method string) func(code int) {
+	startedAt := time.Now()
+
+	httpRequestsInFlight.WithLabelValues(method).Inc()
+
+	return func(code int) {
+		httpRequestsInFlight.WithLabelValues(method).Dec()
+		httpRequestDuration.WithLabelValues(method).Observe(time.Since(startedAt).Seconds())
+		httpRequests.WithLabelValues(method, strconv.Itoa(code)).Inc()
+	}
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	eventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "events_published_total",
+		Help:      "Total amount of domain events published by event type.",
+	}, []string{"type"})
+
+	eventPublishFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "outbox",
+		Name:      "publish_failures_total",
+		Help:      "Total amount of failed domain event deliveries by event type. Failed events are retried.",
+	}, []string{"type"})
+)
+
+func init() {
+	registry.MustRegister(eventsPublished, eventPublishFailures)
+}
+
+// EventPublished records a delivered domain event
+func EventPublished(eventType string) {
+	eventsPublished.WithLabelValues(eventType).Inc()
+}
+
+// EventPublishFailed records a failed domain event delivery
+func EventPublishFailed(eventType string) {
+	eventPublishFailures.WithLabelValues(eventType).Inc()
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	rateLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limit_requests_per_second",
+		Help:      "Configured per client rate limit by routes class. Zero means no limit.",
+	}, []string{"class"})
+
+	rateLimitBurst = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limit_burst",
+		Help:      "Configured per client burst by routes class.",
+	}, []string{"class"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_requests_total",
+		Help:      "Total amount of requests rejected by rate limit by routes class.",
+	}, []string{"class"})
+)
+
+func init() {
+	registry.MustRegister(rateLimit, rateLimitBurst, rateLimited)
+}
+
+// RateLimitConfigured exposes configured limits of a routes class
+func RateLimitConfigured(class string, rps float64, burst int) {
+	rateLimit.WithLabelValues(class).Set(rps)
+	rateLimitBurst.WithLabelValues(class).Set(float64(burst))
+}
+
+// RateLimited records a request rejected by rate limit
+func RateLimited(class string) {
+	rateLimited.WithLabelValues(class).Inc()
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Webhook delivery attempt outcomes
+const (
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed" // Will be retried
+	WebhookDead      = "dead"   // Attempts are exhausted
+)
+
+var webhookAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "webhooks",
+	Name:      "delivery_attempts_total",
+	Help:      "Total amount of webhook delivery attempts by outcome.",
+}, []string{"outcome"})
+
+func init() {
+	registry.MustRegister(webhookAttempts)
+}
+
+// WebhookAttempted records a webhook delivery attempt
+func WebhookAttempted(outcome string) {
+	webhookAttempts.WithLabelValues(outcome).Inc()
+}
@@ -1,6 +1,12 @@
 package models
 
-import "fmt"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 func MapStorageProductsToProductInfos(storageProducts []*StorageProduct) ([]*ProductInfo, error) {
 	infos := make([]*ProductInfo, len(storageProducts))
@@ -37,3 +43,18 @@ func MapStorageProductToProductInfo(storageProduct *StorageProduct) (*ProductInf
 		},
 	}, nil
 }
+
+// NewEvent encodes data as an event payload
+func NewEvent(eventType string, data any) (*Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal %s event data. %w", eventType, err)
+	}
+
+	return &Event{
+		Id:        uuid.New(),
+		Type:      eventType,
+		Payload:   payload,
+		CreatedAt: time.Now(),
+	}, nil
+}
@@ -30,6 +30,7 @@ type StorageProduct struct {
 }
 
 type Reservation struct {
+	Id              uuid.UUID
 	StorageId       uuid.UUID
 	ReservedProduct *StorageProduct
 	ShippingId      uuid.UUID
@@ -44,3 +45,112 @@ type ProductDestribution struct {
 	Reserved  int64
 	Available int64
 }
+
+// Page is a fetched part of a list
+type Page[T any] struct {
+	Items      []T
+	NextCursor string // Opaque cursor of the next page. Empty if the page is the last one
+	NextOffset uint64 // Offset of the next page
+	Total      *int64 // Amount of items matching the filter. Passed only if requested
+}
+
+// APIKey is a client credential. Only a hash of the key itself is stored.
+type APIKey struct {
+	Id        uuid.UUID
+	Name      string
+	Scopes    []string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// Domain event types
+const (
+	EventReservationCreated   = "ReservationCreated"
+	EventReservationCancelled = "ReservationCancelled"
+	EventReservationReleased  = "ReservationReleased"
+	EventStockLevelChanged    = "StockLevelChanged"
+	EventStockDepleted        = "StockDepleted" // Available amount of a product in a storage dropped to zero
+)
+
+// Event is a domain event written to outbox in the transaction changing the state
+type Event struct {
+	Position  int64 // Outbox position. Events are published in position order
+	Id        uuid.UUID
+	Type      string
+	Payload   []byte // JSON encoded event data
+	CreatedAt time.Time
+}
+
+// WebhookSubscription receives events matching all of its filters. Empty filters match any event.
+type WebhookSubscription struct {
+	Id         uuid.UUID
+	URL        string
+	Secret     string // HMAC key of delivery signatures
+	EventTypes []string
+	ProductId  uuid.UUID
+	StorageId  uuid.UUID
+	CreatedAt  time.Time
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead" // Attempts are exhausted. Can be replayed
+)
+
+// WebhookDelivery is an event delivery to a subscription and the outcome of its latest attempt
+type WebhookDelivery struct {
+	Id             uuid.UUID
+	SubscriptionId uuid.UUID
+	URL            string
+	Secret         string
+	EventId        uuid.UUID
+	EventType      string
+	Payload        []byte // Published event JSON
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// StockChange is a product level in a storage after a change
+type StockChange struct {
+	Position  int64 // Outbox position of the StockLevelChanged event. Changes are ordered by it
+	ProductId uuid.UUID
+	StorageId uuid.UUID
+	Amount    int64
+	Reserved  int64
+	Available int64
+	ChangedAt time.Time
+}
+
+// IdempotentRequest is a write request made with an idempotency key and its stored response
+type IdempotentRequest struct {
+	Client      string // Authenticated client id or IP address
+	Key         string
+	RequestHash string // Hex SHA-256 of method, path and body. Key reuse with another request is rejected
+	StatusCode  int    // Zero while the request is in progress
+	Response    []byte
+	CreatedAt   time.Time
+}
+
+// ImportResult is an outcome of a bulk import. Rows are committed only if none of them is rejected
+type ImportResult struct {
+	Kind      string
+	DryRun    bool
+	Rows      int64 // Rows read, CSV header excluded
+	Rejected  int64
+	Errors    []*ImportRowError // Errors of first rejected rows
+	Committed bool
+}
+
+// ImportRowError is a reason a row of an import file is rejected
+type ImportRowError struct {
+	Line  int64 // Line of the file, starting from 1
+	Error string
+}
@@ -0,0 +1,251 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+const Version = "3.1.0"
+
+// DocsPage renders the document served at /openapi.json with Swagger UI
+//
+//go:embed docs.html
+var DocsPage []byte
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds operations of a path by lowercase HTTP method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationId string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // query, path or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+	Explode     *bool   `json:"explode,omitempty"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Name         string `json:"name,omitempty"`
+	In           string `json:"in,omitempty"`
+}
+
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+	}
+}
+
+// Add documents an operation of a path. Path parameters are written in braces, as in chi patterns
+func (d *Document) Add(method, path string, operation *Operation) {
+	item, ok := d.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[path] = item
+	}
+
+	(*item)[strings.ToLower(method)] = operation
+}
+
+// Operations lists method and path of every documented operation, e.g. "GET /storages"
+func (d *Document) Operations() []string {
+	operations := make([]string, 0, len(d.Paths))
+
+	for path, item := range d.Paths {
+		for method := range *item {
+			operations = append(operations, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	return operations
+}
+
+// Schema returns a schema of v type. Structs are added to components and referenced by their
+// type name. Properties are named by json tags, as the type is marshalled, and fields without
+// omitempty are required.
+func (d *Document) Schema(v any) *Schema {
+	return d.schema(reflect.TypeOf(v))
+}
+
+// QueryParameters returns query parameters decoded into a request struct by dto.DecodeQuery.
+// Fields without json tag, e.g. path parameters, are skipped.
+func (d *Document) QueryParameters(v any) []*Parameter {
+	var parameters []*Parameter
+
+	explode := false
+
+	for _, field := range fields(reflect.TypeOf(v)) {
+		parameter := &Parameter{Name: field.name, In: "query", Schema: d.schema(field.typ)}
+
+		// Slices are passed as comma separated values, repeated parameters are accepted too
+		if field.typ.Kind() == reflect.Slice {
+			parameter.Explode = &explode
+		}
+
+		parameters = append(parameters, parameter)
+	}
+
+	return parameters
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(json.RawMessage{}) {
+		return &Schema{Description: "Any JSON value"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		return d.component(t)
+	default:
+		return &Schema{}
+	}
+}
+
+func (d *Document) component(t reflect.Type) *Schema {
+	ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+
+	if _, ok := d.Components.Schemas[t.Name()]; ok {
+		return ref
+	}
+
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	// Registered before properties, so recursive types refer to themselves
+	d.Components.Schemas[t.Name()] = schema
+
+	for _, field := range fields(t) {
+		schema.Properties[field.name] = d.schema(field.typ)
+
+		if !field.omitempty {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+
+	return ref
+}
+
+type structField struct {
+	name      string
+	typ       reflect.Type
+	omitempty bool
+}
+
+// fields lists json fields of a struct type. Fields of embedded structs are promoted, as
+// encoding/json does.
+func fields(t reflect.Type) []structField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var result []structField
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, hasTag := field.Tag.Lookup("json")
+
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+			result = append(result, fields(field.Type)...)
+
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		result = append(result, structField{
+			name:      name,
+			typ:       field.Type,
+			omitempty: strings.Contains(options, "omitempty"),
+		})
+	}
+
+	return result
+}
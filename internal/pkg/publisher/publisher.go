@@ -0,0 +1,152 @@
+package publisher
+
+import (
+	"bytes"
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Publishers
+const (
+	PublisherNone    = "none"
+	PublisherStdout  = "stdout"
+	PublisherFile    = "file"
+	PublisherWebhook = "webhook"
+)
+
+var ErrorDeliveryFailed = errors.New("event delivery failed")
+
+// Publisher delivers domain events to downstream services. Delivery is at least once: an event
+// may be published again, if the relay fails before marking it published.
+type Publisher interface {
+	Publish(ctx context.Context, event *models.Event) error
+}
+
+// New creates a publisher by name. The returned function releases publisher resources.
+// Returns nil publisher for none.
+func New(name string, filePath string, webhookURL string) (Publisher, func(), error) {
+	switch name {
+	case PublisherNone, "":
+		return nil, func() {}, nil
+	case PublisherStdout:
+		return NewWriterPublisher(os.Stdout), func() {}, nil
+	case PublisherFile:
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, func() {}, fmt.Errorf("error open events file. %w", err)
+		}
+
+		return NewWriterPublisher(file), func() { file.Close() }, nil
+	case PublisherWebhook:
+		return NewWebhookPublisher(webhookURL, &http.Client{Timeout: 10 * time.Second}), func() {}, nil
+	default:
+		return nil, func() {}, fmt.Errorf("error unknown publisher %s", name)
+	}
+}
+
+type writerPublisher struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewWriterPublisher writes events as JSON lines
+func NewWriterPublisher(out io.Writer) Publisher {
+	return &writerPublisher{out: out}
+}
+
+func (p *writerPublisher) Publish(_ context.Context, event *models.Event) error {
+	data, err := json.Marshal(dto.MapEventFromModel(event))
+	if err != nil {
+		return fmt.Errorf("error marshal event. %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err = p.out.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error write event. %w", err)
+	}
+
+	return nil
+}
+
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher posts events as JSON to url. Any response status but 2xx fails delivery.
+func NewWebhookPublisher(url string, client *http.Client) Publisher {
+	return &webhookPublisher{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, event *models.Event) error {
+	data, err := json.Marshal(dto.MapEventFromModel(event))
+	if err != nil {
+		return fmt.Errorf("error marshal event. %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error create request. %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Event-Id", event.Id.String())
+	request.Header.Set("X-Event-Type", event.Type)
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error post event. %w. %w", err, ErrorDeliveryFailed)
+	}
+
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("error webhook responded %d. %w", response.StatusCode, ErrorDeliveryFailed)
+	}
+
+	return nil
+}
+
+type fanoutPublisher struct {
+	publishers []Publisher
+}
+
+// Fanout publishes events to every publisher passed in order. nil publishers are skipped. If
+// a publisher fails, the event is published to all of them again, so each must tolerate duplicates.
+func Fanout(publishers ...Publisher) Publisher {
+	fanout := &fanoutPublisher{}
+
+	for _, publisher := range publishers {
+		if publisher != nil {
+			fanout.publishers = append(fanout.publishers, publisher)
+		}
+	}
+
+	return fanout
+}
+
+func (p *fanoutPublisher) Publish(ctx context.Context, event *models.Event) error {
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
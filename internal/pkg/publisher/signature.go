@@ -0,0 +1,29 @@
+package publisher
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Webhook delivery headers
+const (
+	SignatureHeader = "X-Webhook-Signature" // sha256=<hex HMAC of "<timestamp>.<body>">
+	TimestampHeader = "X-Webhook-Timestamp" // unix seconds. Reject old timestamps to prevent replays
+)
+
+// Sign signs a webhook delivery body with the subscription secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a webhook delivery signature. Receivers can use it with the secret returned on
+// subscription creation.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var ErrorRateLimited = errors.New("rate limited")
+
+const (
+	sweepInterval = time.Minute
+	idleTimeout   = 3 * time.Minute // Buckets of clients idle longer are dropped
+)
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter keeps a token bucket per client
+type Limiter struct {
+	limit rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New returns a limiter allowing rps requests per second with bursts of burst requests per client.
+// Zero rps disables limiting.
+func New(rps float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		limit:     rate.Limit(rps),
+		burst:     burst,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Enabled reports whether requests are limited at all
+func (l *Limiter) Enabled() bool {
+	return l.limit > 0
+}
+
+// Allow takes a token from the client bucket. If the bucket is empty, returns false and the time
+// until a token is available.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	if !l.Enabled() {
+		return true, 0
+	}
+
+	now := time.Now()
+
+	l.mu.Lock()
+
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[client] = b
+	}
+
+	b.lastSeen = now
+
+	l.mu.Unlock()
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+
+		return false, delay
+	}
+
+	return true, 0
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if now.Sub(b.lastSeen) > idleTimeout {
+			delete(l.buckets, client)
+		}
+	}
+
+	l.lastSweep = now
+}
@@ -0,0 +1,194 @@
+package sqltools
+
+import (
+	"cernunnos/internal/pkg/models"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+var ErrorInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a keyset pagination position. Rows are ordered by (sort key, created_at, id),
+// so the position is the key of the last row of a previous page.
+type Cursor struct {
+	Field     string    `json:"f,omitempty"` // Sort field the cursor was issued for
+	Value     string    `json:"v,omitempty"` // Sort key of the last row
+	CreatedAt time.Time `json:"c"`
+	Id        uuid.UUID `json:"i"`
+}
+
+// String encodes the cursor into an opaque url-safe token
+func (c *Cursor) String() string {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseCursor decodes a token produced by Cursor.String. Empty token means no cursor.
+func ParseCursor(token string) (*Cursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error decode cursor. %w", err), ErrorInvalidCursor)
+	}
+
+	cursor := new(Cursor)
+
+	if err = json.Unmarshal(raw, cursor); err != nil {
+		return nil, errors.Join(fmt.Errorf("error unmarshal cursor. %w", err), ErrorInvalidCursor)
+	}
+
+	if cursor.Id == uuid.Nil {
+		return nil, fmt.Errorf("error cursor without id. %w", ErrorInvalidCursor)
+	}
+
+	return cursor, nil
+}
+
+// Pagination describes a page to fetch. If Cursor is passed, Offset is ignored.
+type Pagination struct {
+	Cursor    *Cursor
+	Sort      *Sort  // Default: created_at ascending
+	Limit     uint64 // Default and max: DefaultLimit
+	Offset    uint64
+	WithTotal bool // Count rows matching the filter
+}
+
+// Size returns an effective page size
+func (p Pagination) Size() uint64 {
+	if p.Limit > 0 && p.Limit < uint64(DefaultLimit) {
+		return p.Limit
+	}
+
+	return uint64(DefaultLimit)
+}
+
+// Apply adds keyset condition, ordering and limits to a query. The sort key of a row is appended
+// to the selected columns as text, so it must be scanned after the other columns. One row more
+// than the page size is fetched to tell whether a next page exists, NewPage trims it.
+func (p Pagination) Apply(query sq.SelectBuilder, spec FilterSpec) (sq.SelectBuilder, error) {
+	var (
+		sortField  string
+		sortColumn = spec.CreatedAt
+		direction  = "ASC"
+		comparison = ">"
+	)
+
+	if p.Sort != nil {
+		column, err := spec.Column(p.Sort.Field)
+		if err != nil {
+			return query, fmt.Errorf("error resolve sort field. %w", err)
+		}
+
+		sortField, sortColumn = p.Sort.Field, column
+
+		if p.Sort.Desc {
+			direction, comparison = "DESC", "<"
+		}
+	}
+
+	query = query.Column(fmt.Sprintf("(%s)::text", sortColumn))
+
+	if p.Cursor != nil {
+		if p.Cursor.Field != sortField {
+			return query, fmt.Errorf("error cursor issued for another sort. %w", ErrorInvalidCursor)
+		}
+
+		if sortField == "" {
+			query = query.Where(sq.Expr(
+				fmt.Sprintf("(%s, %s) %s (?, ?)", spec.CreatedAt, spec.Id, comparison),
+				p.Cursor.CreatedAt,
+				p.Cursor.Id,
+			))
+		} else {
+			query = query.Where(sq.Expr(
+				fmt.Sprintf("(%s, %s, %s) %s (?, ?, ?)", sortColumn, spec.CreatedAt, spec.Id, comparison),
+				p.Cursor.Value,
+				p.Cursor.CreatedAt,
+				p.Cursor.Id,
+			))
+		}
+	} else if p.Offset > 0 {
+		query = query.Offset(p.Offset)
+	}
+
+	if sortField != "" {
+		query = query.OrderBy(sortColumn + " " + direction)
+	}
+
+	return query.
+		OrderBy(spec.CreatedAt+" "+direction, spec.Id+" "+direction).
+		Limit(p.Size() + 1), nil
+}
+
+// Next returns a cursor pointing to the page following the fetched one, or an empty string if
+// the fetched page was the last one. fetched includes the extra row requested by Apply, last is
+// the key of the last row of the page.
+func (p Pagination) Next(fetched int, last Cursor) string {
+	if uint64(fetched) <= p.Size() {
+		return ""
+	}
+
+	if p.Sort != nil {
+		last.Field = p.Sort.Field
+	} else {
+		last.Field, last.Value = "", ""
+	}
+
+	return last.String()
+}
+
+// NextOffset returns an offset of the page following the fetched one. Pages fetched by a cursor
+// have no offset, 0 is returned for them.
+func (p Pagination) NextOffset(fetched int) uint64 {
+	if p.Cursor != nil {
+		return 0
+	}
+
+	return p.Offset + uint64(fetched)
+}
+
+// CountQuery builds a query counting rows matched by a filtered query without pagination
+func CountQuery(query sq.SelectBuilder) sq.SelectBuilder {
+	return sq.Select("count(*)").FromSelect(query, "q").PlaceholderFormat(sq.Dollar)
+}
+
+// Count runs a count query built with CountQuery
+func Count(ctx context.Context, conn DBTX, query sq.SelectBuilder) (int64, error) {
+	var total int64
+
+	if err := QueryRow(ctx, conn, CountQuery(query), &total); err != nil {
+		return 0, fmt.Errorf("error count rows. %w", err)
+	}
+
+	return total, nil
+}
+
+// NewPage builds a page of items fetched by a query paginated with Apply. keys are keyset
+// positions of the items. The extra item fetched to detect a next page is trimmed.
+func NewPage[T any](p Pagination, items []T, keys []Cursor) *models.Page[T] {
+	page := &models.Page[T]{Items: items}
+
+	size := int(p.Size())
+	if len(items) > size {
+		page.Items = items[:size]
+		page.NextCursor = p.Next(len(items), keys[size-1])
+	}
+
+	page.NextOffset = p.NextOffset(len(page.Items))
+
+	return page
+}
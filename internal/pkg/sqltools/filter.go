@@ -0,0 +1,141 @@
+package sqltools
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var ErrorInvalidFilter = errors.New("invalid filter")
+
+type Operator string
+
+const (
+	OperatorEq       Operator = "eq"
+	OperatorGt       Operator = "gt"
+	OperatorGte      Operator = "gte"
+	OperatorLt       Operator = "lt"
+	OperatorLte      Operator = "lte"
+	OperatorPrefix   Operator = "prefix"   // Case insensitive prefix match
+	OperatorContains Operator = "contains" // Case insensitive substring match
+)
+
+// Condition is a single filter predicate over a public field name
+type Condition struct {
+	Field    string
+	Operator Operator
+	Value    any
+}
+
+// Sort is an ordering over a public field name
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses sort parameter. Supported forms: "field", "-field", "field:asc", "field:desc".
+// Empty parameter means default ordering.
+func ParseSort(raw string) (*Sort, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	sort := new(Sort)
+
+	if strings.HasPrefix(raw, "-") {
+		sort.Desc = true
+		raw = raw[1:]
+	}
+
+	if field, direction, ok := strings.Cut(raw, ":"); ok {
+		switch strings.ToLower(direction) {
+		case "asc":
+		case "desc":
+			sort.Desc = true
+		default:
+			return nil, fmt.Errorf("error unknown sort direction %s. %w", direction, ErrorInvalidFilter)
+		}
+
+		raw = field
+	}
+
+	if raw == "" {
+		return nil, fmt.Errorf("error empty sort field. %w", ErrorInvalidFilter)
+	}
+
+	sort.Field = raw
+
+	return sort, nil
+}
+
+// FilterSpec whitelists fields of a listing available for filtering and sorting
+type FilterSpec struct {
+	Fields    map[string]string // Public field name to SQL expression
+	CreatedAt string            // Creation time column. Default ordering key
+	Id        string            // Id column. Ordering tiebreaker
+}
+
+// Column returns an SQL expression of a whitelisted field
+func (s FilterSpec) Column(field string) (string, error) {
+	column, ok := s.Fields[field]
+	if !ok {
+		return "", fmt.Errorf("error field %s is not allowed. %w", field, ErrorInvalidFilter)
+	}
+
+	return column, nil
+}
+
+// Where translates conditions into a squirrel predicate
+func (s FilterSpec) Where(conditions []Condition) (sq.And, error) {
+	predicate := make(sq.And, 0, len(conditions))
+
+	for _, condition := range conditions {
+		column, err := s.Column(condition.Field)
+		if err != nil {
+			return nil, err
+		}
+
+		switch condition.Operator {
+		case OperatorEq:
+			predicate = append(predicate, sq.Eq{column: condition.Value})
+		case OperatorGt:
+			predicate = append(predicate, sq.Gt{column: condition.Value})
+		case OperatorGte:
+			predicate = append(predicate, sq.GtOrEq{column: condition.Value})
+		case OperatorLt:
+			predicate = append(predicate, sq.Lt{column: condition.Value})
+		case OperatorLte:
+			predicate = append(predicate, sq.LtOrEq{column: condition.Value})
+		case OperatorPrefix:
+			predicate = append(predicate, sq.ILike{column: EscapeLike(fmt.Sprint(condition.Value)) + "%"})
+		case OperatorContains:
+			predicate = append(predicate, sq.ILike{column: "%" + EscapeLike(fmt.Sprint(condition.Value)) + "%"})
+		default:
+			return nil, fmt.Errorf("error unknown operator %s. %w", condition.Operator, ErrorInvalidFilter)
+		}
+	}
+
+	return predicate, nil
+}
+
+// Apply adds conditions to a query
+func (s FilterSpec) Apply(query sq.SelectBuilder, conditions []Condition) (sq.SelectBuilder, error) {
+	if len(conditions) == 0 {
+		return query, nil
+	}
+
+	predicate, err := s.Where(conditions)
+	if err != nil {
+		return query, err
+	}
+
+	return query.Where(predicate), nil
+}
+
+// EscapeLike escapes LIKE pattern special characters
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
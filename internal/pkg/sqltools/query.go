@@ -0,0 +1,119 @@
+package sqltools
+
+import (
+	"cernunnos/internal/pkg/tracing"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Rows wraps sql.Rows counting fetched rows. The query span ends when Rows are closed.
+type Rows struct {
+	*sql.Rows
+
+	span    trace.Span
+	fetched int64
+	closed  bool
+}
+
+func (r *Rows) Next() bool {
+	if !r.Rows.Next() {
+		return false
+	}
+
+	r.fetched++
+
+	return true
+}
+
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+
+	if !r.closed {
+		r.closed = true
+
+		if err == nil {
+			err = r.Rows.Err()
+		}
+
+		r.span.SetAttributes(attribute.Int64("db.rows_returned", r.fetched))
+		tracing.End(r.span, err)
+	}
+
+	return err
+}
+
+// Query runs a select query in a traced span
+func Query(ctx context.Context, conn DBTX, query sq.Sqlizer) (*Rows, error) {
+	statement, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("error build query. %w", err)
+	}
+
+	ctx, span := startQuerySpan(ctx, statement)
+
+	rows, err := conn.QueryContext(ctx, statement, args...)
+	if err != nil {
+		tracing.End(span, err)
+
+		return nil, err
+	}
+
+	return &Rows{Rows: rows, span: span}, nil
+}
+
+// QueryRow runs a query returning a single row in a traced span and scans it into dest
+func QueryRow(ctx context.Context, conn DBTX, query sq.Sqlizer, dest ...any) error {
+	statement, args, err := query.ToSql()
+	if err != nil {
+		return fmt.Errorf("error build query. %w", err)
+	}
+
+	ctx, span := startQuerySpan(ctx, statement)
+
+	err = conn.QueryRowContext(ctx, statement, args...).Scan(dest...)
+	if err == nil {
+		span.SetAttributes(attribute.Int64("db.rows_returned", 1))
+	}
+
+	tracing.End(span, err)
+
+	return err
+}
+
+// Exec runs a modifying query in a traced span
+func Exec(ctx context.Context, conn DBTX, query sq.Sqlizer) (sql.Result, error) {
+	statement, args, err := query.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("error build query. %w", err)
+	}
+
+	ctx, span := startQuerySpan(ctx, statement)
+
+	result, err := conn.ExecContext(ctx, statement, args...)
+	if err == nil {
+		if affected, affectedErr := result.RowsAffected(); affectedErr == nil {
+			span.SetAttributes(attribute.Int64("db.rows_affected", affected))
+		}
+	}
+
+	tracing.End(span, err)
+
+	return result, err
+}
+
+func startQuerySpan(ctx context.Context, statement string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(statement), " ")
+	operation = strings.ToUpper(operation)
+
+	return tracing.Start(ctx, "sql "+operation,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operation),
+		attribute.String("db.statement", statement),
+	)
+}
@@ -1,10 +1,15 @@
 package sqltools
 
 import (
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/tracing"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type DBTX interface {
@@ -21,59 +26,132 @@ type TransactionalStorage interface {
 	Conn(ctx context.Context) DBTX
 }
 
-type txCtxKey struct{}
+type (
+	txCtxKey          struct{}
+	afterCommitCtxKey struct{}
+	primaryCtxKey     struct{}
+	replicaCtxKey     struct{}
+)
 
+// afterCommit holds functions to run once a transaction commits
+type afterCommit struct {
+	hooks []func()
+}
+
+// Transaction runs fn in a repeatable read transaction. If ctx already carries a transaction,
+// fn joins it and the outer Transaction call commits or rolls it back.
 func Transaction(ctx context.Context, db *sql.DB, fn func(context.Context) error) error {
-	var err error
+	return transaction(ctx, db, false, fn)
+}
 
-	var tx *sql.Tx = new(sql.Tx)
+// ReadTransaction runs fn in a read-only repeatable read transaction on replica. Falls back to
+// primary if replica is nil or ctx requires primary. Joins a transaction carried by ctx, like
+// Transaction does.
+func ReadTransaction(ctx context.Context, primary, replica *sql.DB, fn func(context.Context) error) error {
+	if replica == nil || PrimaryRequired(ctx) || hasExternalTransaction(ctx) {
+		return transaction(ctx, primary, false, fn)
+	}
 
-	hasExternalTx := hasExternalTransaction(ctx)
+	return transaction(context.WithValue(ctx, replicaCtxKey{}, true), replica, true, fn)
+}
 
-	defer func() {
-		if hasExternalTx {
-			if err != nil {
-				err = fmt.Errorf("error perform operation. %w", err)
-				return
-			}
+// WithPrimary makes read transactions started with ctx run on primary. Use it when a request
+// must see its own writes, which replica may not have received yet.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryCtxKey{}, true)
+}
 
-			return
-		}
+// PrimaryRequired reports whether read transactions must run on primary
+func PrimaryRequired(ctx context.Context) bool {
+	required, _ := ctx.Value(primaryCtxKey{}).(bool)
 
-		if err != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				err = errors.Join(fmt.Errorf("error rollback transaction. %w", rbErr), err)
-				return
-			}
+	return required
+}
+
+// OnReplica reports whether ctx carries a transaction started on replica. Replica rejects
+// locking reads, like select for update.
+func OnReplica(ctx context.Context) bool {
+	onReplica, _ := ctx.Value(replicaCtxKey{}).(bool)
 
-			err = fmt.Errorf("error execute transactional operation. %w", err)
+	return onReplica
+}
 
-			return
+func transaction(ctx context.Context, db *sql.DB, onReplica bool, fn func(context.Context) error) (err error) {
+	if hasExternalTransaction(ctx) {
+		if err := fn(ctx); err != nil {
+			return fmt.Errorf("error perform operation. %w", err)
 		}
 
-		if commitErr := tx.Commit(); commitErr != nil {
-			if rbErr := tx.Rollback(); rbErr != nil {
-				err = errors.Join(fmt.Errorf("error rollback transaction. %w", rbErr), commitErr, err)
+		return nil
+	}
 
-				return
-			}
+	startedAt := time.Now()
+	outcome := metrics.TransactionCommitted
 
-			err = fmt.Errorf("error commit transaction. %w", err)
-		}
+	ctx, span := tracing.Start(ctx, "sql transaction", attribute.Bool("db.replica", onReplica))
+
+	defer func() {
+		metrics.ObserveTransaction(outcome, time.Since(startedAt))
+
+		span.SetAttributes(attribute.String("db.transaction.outcome", outcome))
+		tracing.End(span, err)
 	}()
 
-	if !hasExternalTx {
-		tx, err = db.BeginTx(ctx, &sql.TxOptions{
-			Isolation: sql.LevelRepeatableRead,
-		})
-		if err != nil {
-			return fmt.Errorf("error begin transaction. %w", err)
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  onReplica,
+	})
+	if err != nil {
+		outcome = metrics.TransactionFailed
+
+		return fmt.Errorf("error begin transaction. %w", err)
+	}
+
+	committed := new(afterCommit)
+
+	if err = fn(context.WithValue(context.WithValue(ctx, txCtxKey{}, tx), afterCommitCtxKey{}, committed)); err != nil {
+		outcome = metrics.TransactionRolledBack
+
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(fmt.Errorf("error rollback transaction. %w", rbErr), err)
 		}
 
-		ctx = context.WithValue(ctx, txCtxKey{}, tx)
+		return fmt.Errorf("error execute transactional operation. %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		outcome = metrics.TransactionFailed
+
+		return fmt.Errorf("error commit transaction. %w", err)
+	}
+
+	for _, hook := range committed.hooks {
+		hook()
+	}
+
+	return nil
+}
+
+// AfterCommit runs fn once the transaction carried by ctx commits. Nested Transaction calls join
+// the outer transaction, so fn runs after the outermost one commits and never runs if it rolls
+// back. If ctx carries no transaction, fn runs right away.
+func AfterCommit(ctx context.Context, fn func()) {
+	if committed, ok := ctx.Value(afterCommitCtxKey{}).(*afterCommit); ok {
+		committed.hooks = append(committed.hooks, fn)
+
+		return
+	}
+
+	fn()
+}
+
+// Conn returns a transaction started by Transaction, if ctx carries one, or db otherwise
+func Conn(ctx context.Context, db *sql.DB) DBTX {
+	if tx, ok := ctx.Value(txCtxKey{}).(*sql.Tx); ok {
+		return tx
 	}
 
-	return fn(ctx)
+	return db
 }
 
 func hasExternalTransaction(ctx context.Context) bool {
@@ -0,0 +1,153 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrorUnknownExporter = errors.New("unknown trace exporter")
+
+// Supported span exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout" // Writes spans to stdout. For local runs without a collector
+	ExporterFile   = "file"   // Writes spans to a file. For local runs without a collector
+	ExporterOTLP   = "otlp"   // Sends spans over OTLP/HTTP. Configured with standard OTEL_EXPORTER_OTLP_* env
+)
+
+const (
+	serviceName = "cernunnos"
+	tracerName  = "cernunnos"
+)
+
+// Setup installs a global tracer provider with the exporter specified and W3C trace context
+// propagation. The returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, exporterName string, filePath string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, closeOutput, err := newExporter(ctx, exporterName, filePath)
+	if err != nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeErr := closeOutput(); closeErr != nil {
+			err = errors.Join(fmt.Errorf("error close trace output. %w", closeErr), err)
+		}
+
+		return err
+	}, nil
+}
+
+func newExporter(
+	ctx context.Context,
+	exporterName string,
+	filePath string,
+) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+
+	switch exporterName {
+	case "", ExporterNone:
+		return nil, noClose, nil
+	case ExporterStdout:
+		exporter, err := newWriterExporter(os.Stdout)
+
+		return exporter, noClose, err
+	case ExporterFile:
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, noClose, fmt.Errorf("error open trace file %s. %w", filePath, err)
+		}
+
+		exporter, err := newWriterExporter(file)
+		if err != nil {
+			file.Close()
+
+			return nil, noClose, err
+		}
+
+		return exporter, file.Close, nil
+	case ExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, noClose, fmt.Errorf("error create otlp exporter. %w", err)
+		}
+
+		return exporter, noClose, nil
+	default:
+		return nil, noClose, fmt.Errorf("error exporter %s. %w", exporterName, ErrorUnknownExporter)
+	}
+}
+
+func newWriterExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		return nil, fmt.Errorf("error create stdout exporter. %w", err)
+	}
+
+	return exporter, nil
+}
+
+// Start starts a span. If no tracer provider was set up, the span is a no-op.
+func Start(
+	ctx context.Context,
+	name string,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer starts a server span continuing a trace passed in incoming request headers
+func StartServer(
+	ctx context.Context,
+	header http.Header,
+	name string,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+
+	return otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// End records err, if any, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"cernunnos/internal/pkg/dto"
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/tracing"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// exportStock writes a stock file as rows are read. Unlike handle, the export is not limited with
+// request and write timeouts, as large exports take long. Errors are responded as usual until the
+// file is started. Later the response is aborted, so clients do not take a truncated file for a
+// complete one.
+func (s *Server) exportStock(w http.ResponseWriter, r *http.Request) {
+	const methodName = "export_stock"
+
+	ctx, span := tracing.StartServer(r.Context(), r.Header, methodName,
+		attribute.String("http.request.method", r.Method),
+		attribute.String("http.route", chi.RouteContext(r.Context()).RoutePattern()),
+	)
+
+	if spanContext := span.SpanContext(); spanContext.IsValid() {
+		logger.AddAttrs(ctx, slog.String("trace_id", spanContext.TraceID().String()))
+	}
+
+	observe := metrics.ObserveRequest(methodName)
+
+	controller := http.NewResponseController(w)
+
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil {
+		s.log.WarnContext(ctx, "error disable export write timeout", logger.Err(err))
+	}
+
+	file := &fileResponse{w: w, controller: controller}
+
+	err := s.writeStockExport(ctx, r, file)
+
+	switch {
+	case err == nil:
+		observe(http.StatusOK)
+		span.SetAttributes(attribute.Int("http.response.status_code", http.StatusOK))
+		span.End()
+	case !file.started:
+		code := s.responseError(ctx, w, err, methodName)
+
+		observe(code)
+		span.SetAttributes(attribute.Int("http.response.status_code", code))
+		tracing.End(span, err)
+	default:
+		s.log.ErrorContext(ctx, "error export stock", logger.Err(err))
+
+		observe(http.StatusInternalServerError)
+		span.SetAttributes(attribute.Int("http.response.status_code", http.StatusOK))
+		tracing.End(span, err)
+
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (s *Server) writeStockExport(ctx context.Context, r *http.Request, file *fileResponse) error {
+	request := new(dto.ExportStockRequest)
+
+	if err := dto.DecodeQuery(r.URL.Query(), request); err != nil {
+		return errors.Join(fmt.Errorf("error decode export request query. %w", err), errs.ErrorBadRequest)
+	}
+
+	s.log.DebugContext(ctx, "request", slog.Any("dto", request))
+
+	file.name = fmt.Sprintf("stock-%s.%s", time.Now().UTC().Format(time.DateOnly), cmp.Or(request.Format, "csv"))
+
+	if err := s.controllers.ExportController.Stock(ctx, request, file); err != nil {
+		return fmt.Errorf("error export stock. %w", err)
+	}
+
+	return nil
+}
+
+// fileResponse starts a file response on the first write. Written chunks are flushed to the client
+type fileResponse struct {
+	w           http.ResponseWriter
+	controller  *http.ResponseController
+	name        string
+	contentType string
+	started     bool
+}
+
+func (f *fileResponse) SetContentType(contentType string) {
+	f.contentType = contentType
+}
+
+func (f *fileResponse) Write(p []byte) (int, error) {
+	if !f.started {
+		f.started = true
+
+		f.w.Header().Set("Content-Type", f.contentType)
+		f.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.name))
+		f.w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	return n, f.controller.Flush()
+}
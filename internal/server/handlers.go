@@ -2,7 +2,9 @@ package server
 
 import (
 	"cernunnos/internal/pkg/dto"
+	errs "cernunnos/internal/pkg/errors"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -37,7 +39,7 @@ func (s *Server) storageProducts(ctx context.Context, r *http.Request) ([]byte,
 
 	request.StorageId = storageId
 
-	log.Debug("request", slog.Any("dto", request))
+	log.DebugContext(ctx, "request", slog.Any("dto", request))
 
 	response, err := s.controllers.ProductController.StorageProducts(ctx, request)
 	if err != nil {
@@ -57,7 +59,7 @@ func (s *Server) products(ctx context.Context, r *http.Request) ([]byte, error)
 		return nil, fmt.Errorf("error build products request. %w", err)
 	}
 
-	log.Debug("request", slog.Any("dto", request))
+	log.DebugContext(ctx, "request", slog.Any("dto", request))
 
 	response, err := s.controllers.ProductController.Products(ctx, request)
 	if err != nil {
@@ -67,6 +69,26 @@ func (s *Server) products(ctx context.Context, r *http.Request) ([]byte, error)
 	return response, nil
 }
 
+func (s *Server) searchProducts(ctx context.Context, r *http.Request) ([]byte, error) {
+	const methodName = "search_products"
+
+	log := s.log.WithGroup(methodName)
+
+	request, err := buildRequest[dto.SearchProductsRequest](r)
+	if err != nil {
+		return nil, fmt.Errorf("error build search products request. %w", err)
+	}
+
+	log.DebugContext(ctx, "request", slog.Any("dto", request))
+
+	response, err := s.controllers.ProductController.SearchProducts(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error search products. %w", err)
+	}
+
+	return response, nil
+}
+
 func (s *Server) reservations(ctx context.Context, r *http.Request) ([]byte, error) {
 	const methodName = "reservations"
 
@@ -77,7 +99,7 @@ func (s *Server) reservations(ctx context.Context, r *http.Request) ([]byte, err
 		return nil, fmt.Errorf("error build products request. %w", err)
 	}
 
-	log.Debug("request", slog.Any("dto", request))
+	log.DebugContext(ctx, "request", slog.Any("dto", request))
 
 	response, err := s.controllers.ReservationController.Reservations(ctx, request)
 	if err != nil {
@@ -128,3 +150,103 @@ func (s *Server) releaseProductReservation(ctx context.Context, r *http.Request)
 
 	return response, nil
 }
+
+func (s *Server) createWebhook(ctx context.Context, r *http.Request) ([]byte, error) {
+	request, err := buildRequest[dto.CreateWebhookRequest](r)
+	if err != nil {
+		return nil, fmt.Errorf("error build create webhook request. %w", err)
+	}
+
+	response, err := s.controllers.WebhookController.Create(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error create webhook. %w", err)
+	}
+
+	return response, nil
+}
+
+func (s *Server) webhooks(ctx context.Context, _ *http.Request) ([]byte, error) {
+	response, err := s.controllers.WebhookController.Webhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch webhooks. %w", err)
+	}
+
+	return response, nil
+}
+
+func (s *Server) deleteWebhook(ctx context.Context, r *http.Request) ([]byte, error) {
+	response, err := s.controllers.WebhookController.Delete(ctx, chi.URLParam(r, "webhook_id"))
+	if err != nil {
+		return nil, fmt.Errorf("error delete webhook. %w", err)
+	}
+
+	return response, nil
+}
+
+func (s *Server) webhookDeliveries(ctx context.Context, r *http.Request) ([]byte, error) {
+	const methodName = "webhook_deliveries"
+
+	log := s.log.WithGroup(methodName)
+
+	request, err := buildRequest[dto.WebhookDeliveriesRequest](r)
+	if err != nil {
+		return nil, fmt.Errorf("error build webhook deliveries request. %w", err)
+	}
+
+	request.WebhookId = chi.URLParam(r, "webhook_id")
+
+	log.DebugContext(ctx, "request", slog.Any("dto", request))
+
+	response, err := s.controllers.WebhookController.Deliveries(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch webhook deliveries. %w", err)
+	}
+
+	return response, nil
+}
+
+func (s *Server) replayWebhookDelivery(ctx context.Context, r *http.Request) ([]byte, error) {
+	response, err := s.controllers.WebhookController.Replay(
+		ctx,
+		chi.URLParam(r, "webhook_id"),
+		chi.URLParam(r, "delivery_id"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error replay webhook delivery. %w", err)
+	}
+
+	return response, nil
+}
+
+// importRows loads an uploaded file. Unlike other handlers, the body is not JSON, so the request
+// is decoded from query parameters only.
+func (s *Server) importRows(ctx context.Context, r *http.Request) ([]byte, error) {
+	const methodName = "import"
+
+	log := s.log.WithGroup(methodName)
+
+	request := &dto.ImportRequest{Kind: chi.URLParam(r, "kind")}
+
+	if err := dto.DecodeQuery(r.URL.Query(), request); err != nil {
+		return nil, errors.Join(fmt.Errorf("error decode import request query. %w", err), errs.ErrorBadRequest)
+	}
+
+	log.DebugContext(ctx, "request", slog.Any("dto", request))
+
+	response, err := s.controllers.ImportController.Import(ctx, request, r.Body)
+
+	var tooLarge *http.MaxBytesError
+
+	if errors.As(err, &tooLarge) {
+		return nil, errors.Join(
+			fmt.Errorf("error import file exceeds %d bytes. %w", tooLarge.Limit, err),
+			errs.ErrorBadRequest,
+		)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error import rows. %w", err)
+	}
+
+	return response, nil
+}
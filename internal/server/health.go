@@ -0,0 +1,48 @@
+package server
+
+import (
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/pkg/logger"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// healthz reports the process is alive. Dependencies are not checked.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	s.response(w, http.StatusOK, []byte(`{"status":"ok"}`))
+}
+
+// readyz reports whether the service is ready to handle requests
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	report := s.health.Run(r.Context())
+
+	if report.Status != health.StatusOk {
+		s.log.WarnContext(r.Context(), "service is not ready", slog.Any("checks", report.Checks))
+		s.response(w, http.StatusServiceUnavailable, []byte(`{"status":"failing"}`))
+
+		return
+	}
+
+	s.response(w, http.StatusOK, []byte(`{"status":"ok"}`))
+}
+
+// status reports build version, uptime and readiness checks results
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	report := s.health.Run(r.Context())
+
+	out, err := json.Marshal(report)
+	if err != nil {
+		s.log.Error("error marshal status report", logger.Err(err))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	code := http.StatusOK
+	if report.Status != health.StatusOk {
+		code = http.StatusServiceUnavailable
+	}
+
+	s.response(w, code, out)
+}
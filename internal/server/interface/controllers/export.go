@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// FileWriter receives an exported file. Content type is set before the file is written
+type FileWriter interface {
+	io.Writer
+	SetContentType(contentType string)
+}
+
+type ExportController interface {
+	// Writes stock of products in storages to out as it is read
+	Stock(ctx context.Context, req *dto.ExportStockRequest, out FileWriter) error
+}
+
+func NewExportController(
+	log *slog.Logger,
+	interactor interactors.ExportInteractor,
+	presenter presenters.ExportPresenter,
+) ExportController {
+	return &exportController{
+		log:        log.WithGroup("export_controller"),
+		interactor: interactor,
+		presenter:  presenter,
+	}
+}
+
+type exportController struct {
+	log        *slog.Logger
+	interactor interactors.ExportInteractor
+	presenter  presenters.ExportPresenter
+}
+
+func (c *exportController) Stock(ctx context.Context, req *dto.ExportStockRequest, out FileWriter) error {
+	format := req.Format
+	if format == "" {
+		format = presenters.ExportCSV
+	}
+
+	writer, err := c.presenter.StockWriter(out, format)
+	if err != nil {
+		return fmt.Errorf("error build stock writer. %w", errors.Join(err, interactors.ErrorInvalidField))
+	}
+
+	out.SetContentType(writer.ContentType())
+
+	err = c.interactor.Stock(ctx, interactors.ExportStockParams{
+		StorageIds: req.StorageIds,
+		ProductIds: req.ProductIds,
+	}, func(product *models.StorageProduct) error {
+		return writer.Write(product)
+	})
+	if err != nil {
+		return fmt.Errorf("error export stock. %w", err)
+	}
+
+	if err = writer.Flush(); err != nil {
+		return fmt.Errorf("error flush stock rows. %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+type ImportController interface {
+	// Loads rows of an uploaded file. The response reports rejected rows
+	Import(ctx context.Context, req *dto.ImportRequest, file io.Reader) ([]byte, error)
+}
+
+func NewImportController(
+	log *slog.Logger,
+	interactor interactors.ImportInteractor,
+	presenter presenters.ImportPresenter,
+) ImportController {
+	return &importController{
+		log:        log.WithGroup("import_controller"),
+		interactor: interactor,
+		presenter:  presenter,
+	}
+}
+
+type importController struct {
+	log        *slog.Logger
+	interactor interactors.ImportInteractor
+	presenter  presenters.ImportPresenter
+}
+
+func (c *importController) Import(ctx context.Context, req *dto.ImportRequest, file io.Reader) ([]byte, error) {
+	format := req.Format
+	if format == "" {
+		format = interactors.ImportCSV
+	}
+
+	result, err := c.interactor.Import(ctx, interactors.ImportParams{
+		Kind:   req.Kind,
+		Format: format,
+		DryRun: req.DryRun,
+		Source: file,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error import rows. %w", err)
+	}
+
+	response, err := c.presenter.ResponseImport(result)
+	if err != nil {
+		return nil, fmt.Errorf("error build import response. %w", err)
+	}
+
+	return response, nil
+}
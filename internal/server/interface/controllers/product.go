@@ -12,6 +12,7 @@ import (
 type ProductController interface {
 	Products(ctx context.Context, req *dto.ProductsRequest) ([]byte, error)
 	StorageProducts(ctx context.Context, req *dto.StorageProductsRequest) ([]byte, error)
+	SearchProducts(ctx context.Context, req *dto.SearchProductsRequest) ([]byte, error)
 }
 
 type productController struct {
@@ -37,11 +38,15 @@ func (c *productController) Products(
 	req *dto.ProductsRequest,
 ) ([]byte, error) {
 	products, err := c.interactor.Products(ctx, interactors.ProductsParams{
+		ProductsFilter:  mapProductsFilter(req.ProductsFilter),
 		Ids:             req.Ids,
 		StorageId:       req.StorageId,
+		AsOf:            dto.MapUnixMilliToTime(req.AsOf),
 		WithUnavailable: req.WithUnavailable,
 		Limit:           req.Limit,
 		Offset:          req.Offset,
+		Cursor:          req.Cursor,
+		WithTotal:       req.WithTotal,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error fetch products. %w", err)
@@ -60,11 +65,15 @@ func (c *productController) StorageProducts(
 	req *dto.StorageProductsRequest,
 ) ([]byte, error) {
 	products, err := c.interactor.StorageProducts(ctx, interactors.StorageProductsParams{
+		ProductsFilter:  mapProductsFilter(req.ProductsFilter),
 		Ids:             req.ProductsIds,
 		StorageId:       req.StorageId,
+		AsOf:            dto.MapUnixMilliToTime(req.AsOf),
 		WithUnavailable: req.WithUnavailable,
 		Limit:           uint64(req.Limit),
 		Offset:          uint64(req.Offset),
+		Cursor:          req.Cursor,
+		WithTotal:       req.WithTotal,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error fetch products. %w", err)
@@ -77,3 +86,39 @@ func (c *productController) StorageProducts(
 
 	return response, nil
 }
+
+func (c *productController) SearchProducts(
+	ctx context.Context,
+	req *dto.SearchProductsRequest,
+) ([]byte, error) {
+	products, err := c.interactor.SearchProducts(ctx, interactors.SearchProductsParams{
+		Query:     req.Query,
+		StorageId: req.StorageId,
+		Limit:     uint64(req.Limit),
+		Offset:    uint64(req.Offset),
+		Cursor:    req.Cursor,
+		WithTotal: req.WithTotal,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error search products. %w", err)
+	}
+
+	response, err := c.presenter.ResponseProducts(products)
+	if err != nil {
+		return nil, fmt.Errorf("error build products response. %w", err)
+	}
+
+	return response, nil
+}
+
+func mapProductsFilter(filter dto.ProductsFilter) interactors.ProductsFilter {
+	return interactors.ProductsFilter{
+		Sort:         filter.Sort,
+		NamePrefix:   filter.NamePrefix,
+		NameContains: filter.NameContains,
+		SizeMin:      filter.SizeMin,
+		SizeMax:      filter.SizeMax,
+		AvailableGt:  filter.AvailableGt,
+		UpdatedSince: dto.MapUnixMilliToTime(filter.UpdatedSince),
+	}
+}
@@ -52,6 +52,8 @@ func (c *reservationController) Reservations(
 		ShippingId: req.ShippingId,
 		Limit:      uint64(req.Limit),
 		Offset:     uint64(req.Offset),
+		Cursor:     req.Cursor,
+		WithTotal:  req.WithTotal,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error fetch reservations. %w", err)
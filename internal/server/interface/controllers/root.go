@@ -4,16 +4,28 @@ type RootController struct {
 	ProductController     ProductController
 	ReservationController ReservationController
 	StorageController     StorageController
+	StockController       StockController
+	WebhookController     WebhookController
+	ImportController      ImportController
+	ExportController      ExportController
 }
 
 func NewRootController(
 	productController ProductController,
 	reservationController ReservationController,
 	storageController StorageController,
+	stockController StockController,
+	webhookController WebhookController,
+	importController ImportController,
+	exportController ExportController,
 ) *RootController {
 	return &RootController{
 		ProductController:     productController,
 		ReservationController: reservationController,
 		StorageController:     storageController,
+		StockController:       stockController,
+		WebhookController:     webhookController,
+		ImportController:      importController,
+		ExportController:      exportController,
 	}
 }
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+type StockController interface {
+	// Streams stock changes as Server-Sent Events until ctx is done. The channel is closed when the
+	// stream ends and the client should reconnect
+	Changes(ctx context.Context, req *dto.StockStreamRequest) (<-chan []byte, error)
+}
+
+func NewStockController(
+	log *slog.Logger,
+	interactor interactors.StockInteractor,
+	presenter presenters.StockPresenter,
+) StockController {
+	return &stockController{
+		log:        log.WithGroup("stock_controller"),
+		interactor: interactor,
+		presenter:  presenter,
+	}
+}
+
+type stockController struct {
+	log        *slog.Logger
+	interactor interactors.StockInteractor
+	presenter  presenters.StockPresenter
+}
+
+func (c *stockController) Changes(ctx context.Context, req *dto.StockStreamRequest) (<-chan []byte, error) {
+	changes, err := c.interactor.Changes(ctx, interactors.StockChangesParams{
+		ProductId:   req.ProductId,
+		StorageId:   req.StorageId,
+		LastEventId: req.LastEventId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error subscribe to stock changes. %w", err)
+	}
+
+	events := make(chan []byte)
+
+	go func() {
+		defer close(events)
+
+		for change := range changes {
+			event, err := c.presenter.ResponseStockChange(change)
+			if err != nil {
+				c.log.ErrorContext(ctx, "error build stock change event", logger.Err(err))
+
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+			case events <- event:
+			}
+		}
+	}()
+
+	return events, nil
+}
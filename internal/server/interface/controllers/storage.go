@@ -33,9 +33,18 @@ func NewStorageController(
 
 func (c *storageController) Storages(ctx context.Context, req *dto.StoragesRequest) ([]byte, error) {
 	storages, err := c.interactor.Storages(ctx, interactors.StoragesParams{
-		Ids:    req.Ids,
-		Limit:  req.Limit,
-		Offset: req.Offset,
+		StoragesFilter: interactors.StoragesFilter{
+			Sort:         req.Sort,
+			NamePrefix:   req.NamePrefix,
+			NameContains: req.NameContains,
+			AvailableGt:  req.AvailableGt,
+			UpdatedSince: dto.MapUnixMilliToTime(req.UpdatedSince),
+		},
+		Ids:       req.Ids,
+		Limit:     req.Limit,
+		Offset:    req.Offset,
+		Cursor:    req.Cursor,
+		WithTotal: req.WithTotal,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error fetch storages. %w", err)
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+type WebhookController interface {
+	// Subscribes a URL to events. The response carries the signing secret
+	Create(ctx context.Context, req *dto.CreateWebhookRequest) ([]byte, error)
+	Webhooks(ctx context.Context) ([]byte, error)
+	Delete(ctx context.Context, webhookId string) ([]byte, error)
+	// List deliveries of a webhook with their latest attempt outcome
+	Deliveries(ctx context.Context, req *dto.WebhookDeliveriesRequest) ([]byte, error)
+	// Schedules a delivery to be sent again
+	Replay(ctx context.Context, webhookId string, deliveryId string) ([]byte, error)
+}
+
+func NewWebhookController(
+	log *slog.Logger,
+	interactor interactors.WebhookInteractor,
+	presenter presenters.WebhookPresenter,
+) WebhookController {
+	return &webhookController{
+		log:        log.WithGroup("webhook_controller"),
+		interactor: interactor,
+		presenter:  presenter,
+	}
+}
+
+type webhookController struct {
+	log        *slog.Logger
+	interactor interactors.WebhookInteractor
+	presenter  presenters.WebhookPresenter
+}
+
+func (c *webhookController) Create(ctx context.Context, req *dto.CreateWebhookRequest) ([]byte, error) {
+	subscription, err := c.interactor.Create(ctx, interactors.CreateWebhookParams{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		ProductId:  req.ProductId,
+		StorageId:  req.StorageId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error create webhook. %w", err)
+	}
+
+	response, err := c.presenter.ResponseCreate(subscription)
+	if err != nil {
+		return nil, fmt.Errorf("error build create webhook response. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *webhookController) Webhooks(ctx context.Context) ([]byte, error) {
+	subscriptions, err := c.interactor.Webhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch webhooks. %w", err)
+	}
+
+	response, err := c.presenter.ResponseWebhooks(subscriptions)
+	if err != nil {
+		return nil, fmt.Errorf("error build webhooks response. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *webhookController) Delete(ctx context.Context, webhookId string) ([]byte, error) {
+	if err := c.interactor.Delete(ctx, webhookId); err != nil {
+		return nil, fmt.Errorf("error delete webhook. %w", err)
+	}
+
+	response, err := c.presenter.ResponseDelete()
+	if err != nil {
+		return nil, fmt.Errorf("error build delete webhook response. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *webhookController) Deliveries(
+	ctx context.Context,
+	req *dto.WebhookDeliveriesRequest,
+) ([]byte, error) {
+	deliveries, err := c.interactor.Deliveries(ctx, interactors.WebhookDeliveriesParams{
+		WebhookId: req.WebhookId,
+		Status:    req.Status,
+		Limit:     uint64(req.Limit),
+		Offset:    uint64(req.Offset),
+		Cursor:    req.Cursor,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetch webhook deliveries. %w", err)
+	}
+
+	response, err := c.presenter.ResponseDeliveries(deliveries)
+	if err != nil {
+		return nil, fmt.Errorf("error build webhook deliveries response. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *webhookController) Replay(ctx context.Context, webhookId string, deliveryId string) ([]byte, error) {
+	if err := c.interactor.Replay(ctx, webhookId, deliveryId); err != nil {
+		return nil, fmt.Errorf("error replay webhook delivery. %w", err)
+	}
+
+	response, err := c.presenter.ResponseReplay()
+	if err != nil {
+		return nil, fmt.Errorf("error build replay response. %w", err)
+	}
+
+	return response, nil
+}
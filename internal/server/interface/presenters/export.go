@@ -0,0 +1,144 @@
+package presenters
+
+import (
+	"bufio"
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Export file formats
+const (
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+)
+
+// Rows are written out by chunks of the buffer size, so exports are not kept in memory
+const exportBufferSize = 32 << 10
+
+// StockWriter encodes stock rows into a file. Rows are buffered until Flush
+type StockWriter interface {
+	Write(product *models.StorageProduct) error
+	Flush() error
+	ContentType() string
+}
+
+type ExportPresenter interface {
+	// Returns a writer of stock files in the format. CSV header is written along with the first rows
+	StockWriter(w io.Writer, format string) (StockWriter, error)
+}
+
+func NewExportPresenter() ExportPresenter {
+	return new(exportPresenter)
+}
+
+type exportPresenter struct{}
+
+func (p *exportPresenter) StockWriter(w io.Writer, format string) (StockWriter, error) {
+	buffer := bufio.NewWriterSize(w, exportBufferSize)
+
+	switch format {
+	case ExportCSV:
+		writer := &csvStockWriter{buffer: buffer, csv: csv.NewWriter(buffer)}
+
+		err := writer.csv.Write([]string{
+			"storage_id",
+			"storage_name",
+			"product_id",
+			"product_name",
+			"product_size",
+			"amount",
+			"reserved",
+			"available",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error write csv header. %w", err)
+		}
+
+		return writer, nil
+	case ExportJSONL:
+		return &jsonlStockWriter{buffer: buffer, encoder: json.NewEncoder(buffer)}, nil
+	default:
+		return nil, fmt.Errorf("error unknown export format %s", format)
+	}
+}
+
+type csvStockWriter struct {
+	buffer *bufio.Writer
+	csv    *csv.Writer
+}
+
+func (w *csvStockWriter) Write(product *models.StorageProduct) error {
+	row, err := dto.MapStockRowFromModel(product)
+	if err != nil {
+		return fmt.Errorf("error map stock row to dto. %w", err)
+	}
+
+	err = w.csv.Write([]string{
+		row.StorageId,
+		row.StorageName,
+		row.ProductId,
+		row.ProductName,
+		strconv.FormatInt(row.ProductSize, 10),
+		strconv.FormatInt(row.Amount, 10),
+		strconv.FormatInt(row.Reserved, 10),
+		strconv.FormatInt(row.Available, 10),
+	})
+	if err != nil {
+		return fmt.Errorf("error write csv row. %w", err)
+	}
+
+	return nil
+}
+
+func (w *csvStockWriter) Flush() error {
+	w.csv.Flush()
+
+	if err := w.csv.Error(); err != nil {
+		return fmt.Errorf("error flush csv rows. %w", err)
+	}
+
+	if err := w.buffer.Flush(); err != nil {
+		return fmt.Errorf("error flush rows. %w", err)
+	}
+
+	return nil
+}
+
+func (w *csvStockWriter) ContentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+type jsonlStockWriter struct {
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (w *jsonlStockWriter) Write(product *models.StorageProduct) error {
+	row, err := dto.MapStockRowFromModel(product)
+	if err != nil {
+		return fmt.Errorf("error map stock row to dto. %w", err)
+	}
+
+	if err = w.encoder.Encode(row); err != nil {
+		return fmt.Errorf("error write json row. %w", err)
+	}
+
+	return nil
+}
+
+func (w *jsonlStockWriter) Flush() error {
+	if err := w.buffer.Flush(); err != nil {
+		return fmt.Errorf("error flush rows. %w", err)
+	}
+
+	return nil
+}
+
+func (w *jsonlStockWriter) ContentType() string {
+	return "application/x-ndjson"
+}
@@ -0,0 +1,32 @@
+package presenters
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"encoding/json"
+	"fmt"
+)
+
+type ImportPresenter interface {
+	ResponseImport(result *models.ImportResult) ([]byte, error)
+}
+
+func NewImportPresenter() ImportPresenter {
+	return new(importPresenter)
+}
+
+type importPresenter struct{}
+
+func (p *importPresenter) ResponseImport(result *models.ImportResult) ([]byte, error) {
+	response, err := dto.MapImportResultFromModel(result)
+	if err != nil {
+		return nil, fmt.Errorf("error map import result to dto. %w", err)
+	}
+
+	rawResponse, err := json.Marshal(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal response. %w", err)
+	}
+
+	return rawResponse, nil
+}
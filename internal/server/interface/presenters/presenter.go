@@ -8,8 +8,8 @@ import (
 )
 
 type ProductPresenter interface {
-	ResponseStorageProducts(products []*models.StorageProduct) ([]byte, error)
-	ResponseProducts(products []*models.ProductInfo) ([]byte, error)
+	ResponseStorageProducts(products *models.Page[*models.StorageProduct]) ([]byte, error)
+	ResponseProducts(products *models.Page[*models.ProductInfo]) ([]byte, error)
 }
 
 func NewProductPresenter() ProductPresenter {
@@ -18,15 +18,17 @@ func NewProductPresenter() ProductPresenter {
 
 type productPresenter struct{}
 
-func (p *productPresenter) ResponseStorageProducts(products []*models.StorageProduct) ([]byte, error) {
-	dtoProducts, err := dto.MapStorageProductsFromModels(products)
+func (p *productPresenter) ResponseStorageProducts(products *models.Page[*models.StorageProduct]) ([]byte, error) {
+	dtoProducts, err := dto.MapStorageProductsFromModels(products.Items)
 	if err != nil {
 		return nil, fmt.Errorf("error map storage products to dto. %w", err)
 	}
 
 	response := &dto.StorageProductsResponse{
-		Products: dtoProducts,
-		Offset:   uint32(len(dtoProducts)),
+		Products:   dtoProducts,
+		Offset:     uint32(products.NextOffset),
+		NextCursor: products.NextCursor,
+		Total:      products.Total,
 	}
 
 	rawResponse, err := json.Marshal(&response)
@@ -37,15 +39,17 @@ func (p *productPresenter) ResponseStorageProducts(products []*models.StoragePro
 	return rawResponse, nil
 }
 
-func (p *productPresenter) ResponseProducts(products []*models.ProductInfo) ([]byte, error) {
-	pInfos, err := dto.MapProductsInfoFromModels(products)
+func (p *productPresenter) ResponseProducts(products *models.Page[*models.ProductInfo]) ([]byte, error) {
+	pInfos, err := dto.MapProductsInfoFromModels(products.Items)
 	if err != nil {
 		return nil, fmt.Errorf("error map products info to dto. %w", err)
 	}
 
 	response := &dto.ProductsResponse{
-		Products: pInfos,
-		Offset:   uint32(len(pInfos)),
+		Products:   pInfos,
+		Offset:     uint32(products.NextOffset),
+		NextCursor: products.NextCursor,
+		Total:      products.Total,
 	}
 
 	rawResponse, err := json.Marshal(&response)
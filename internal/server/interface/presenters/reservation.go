@@ -8,7 +8,7 @@ import (
 )
 
 type ReservationPresenter interface {
-	ResponseReservations(reservations []*models.Reservation) ([]byte, error)
+	ResponseReservations(reservations *models.Page[*models.Reservation]) ([]byte, error)
 	ResponseReserve() ([]byte, error)
 	ResponseCancel() ([]byte, error)
 	ResponseRelease() ([]byte, error)
@@ -20,15 +20,17 @@ func NewReservationPresenter() ReservationPresenter {
 
 type reservationPresenter struct{}
 
-func (p *reservationPresenter) ResponseReservations(reservations []*models.Reservation) ([]byte, error) {
-	mappedReservations, err := dto.MapReservationsFromModels(reservations)
+func (p *reservationPresenter) ResponseReservations(reservations *models.Page[*models.Reservation]) ([]byte, error) {
+	mappedReservations, err := dto.MapReservationsFromModels(reservations.Items)
 	if err != nil {
 		return nil, fmt.Errorf("error map reservations from models. %w", err)
 	}
 
 	response := &dto.ReservationsResponse{
 		Reservations: mappedReservations,
-		Offset:       uint32(len(mappedReservations)),
+		Offset:       uint32(reservations.NextOffset),
+		NextCursor:   reservations.NextCursor,
+		Total:        reservations.Total,
 	}
 
 	rawResponse, err := json.Marshal(&response)
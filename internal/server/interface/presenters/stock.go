@@ -0,0 +1,36 @@
+package presenters
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"encoding/json"
+	"fmt"
+)
+
+// Event name of stock stream events
+const stockChangeEvent = "stock"
+
+type StockPresenter interface {
+	// Builds a Server-Sent Event. Event id is the change position to resume the stream after it
+	ResponseStockChange(change *models.StockChange) ([]byte, error)
+}
+
+func NewStockPresenter() StockPresenter {
+	return new(stockPresenter)
+}
+
+type stockPresenter struct{}
+
+func (p *stockPresenter) ResponseStockChange(change *models.StockChange) ([]byte, error) {
+	mappedChange, err := dto.MapStockChangeFromModel(change)
+	if err != nil {
+		return nil, fmt.Errorf("error map stock change to dto. %w", err)
+	}
+
+	data, err := json.Marshal(&mappedChange)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal response. %w", err)
+	}
+
+	return fmt.Appendf(nil, "id: %d\nevent: %s\ndata: %s\n\n", change.Position, stockChangeEvent, data), nil
+}
@@ -8,7 +8,7 @@ import (
 )
 
 type StoragePresenter interface {
-	ResponseStorages(storages []*models.Storage) ([]byte, error)
+	ResponseStorages(storages *models.Page[*models.Storage]) ([]byte, error)
 }
 
 type storagePresenter struct{}
@@ -17,15 +17,17 @@ func NewStoragePresenter() StoragePresenter {
 	return new(storagePresenter)
 }
 
-func (p *storagePresenter) ResponseStorages(storages []*models.Storage) ([]byte, error) {
-	dtoStorages, err := dto.MapStoragesFromModels(storages)
+func (p *storagePresenter) ResponseStorages(storages *models.Page[*models.Storage]) ([]byte, error) {
+	dtoStorages, err := dto.MapStoragesFromModels(storages.Items)
 	if err != nil {
 		return nil, fmt.Errorf("error map storages to dto. %w", err)
 	}
 
 	response := dto.StoragesResponse{
-		Storages: dtoStorages,
-		Offset:   uint32(len(dtoStorages)),
+		Storages:   dtoStorages,
+		Offset:     uint32(storages.NextOffset),
+		NextCursor: storages.NextCursor,
+		Total:      storages.Total,
 	}
 
 	rawResponse, err := json.Marshal(&response)
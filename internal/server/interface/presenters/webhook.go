@@ -0,0 +1,105 @@
+package presenters
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"encoding/json"
+	"fmt"
+)
+
+type WebhookPresenter interface {
+	ResponseCreate(subscription *models.WebhookSubscription) ([]byte, error)
+	ResponseWebhooks(subscriptions []*models.WebhookSubscription) ([]byte, error)
+	ResponseDelete() ([]byte, error)
+	ResponseDeliveries(deliveries *models.Page[*models.WebhookDelivery]) ([]byte, error)
+	ResponseReplay() ([]byte, error)
+}
+
+func NewWebhookPresenter() WebhookPresenter {
+	return new(webhookPresenter)
+}
+
+type webhookPresenter struct{}
+
+func (p *webhookPresenter) ResponseCreate(subscription *models.WebhookSubscription) ([]byte, error) {
+	webhook, err := dto.MapWebhookFromModel(subscription)
+	if err != nil {
+		return nil, fmt.Errorf("error map webhook to dto. %w", err)
+	}
+
+	response := &dto.CreateWebhookResponse{
+		Webhook: webhook,
+		Secret:  subscription.Secret,
+	}
+
+	rawResponse, err := json.Marshal(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal response. %w", err)
+	}
+
+	return rawResponse, nil
+}
+
+func (p *webhookPresenter) ResponseWebhooks(subscriptions []*models.WebhookSubscription) ([]byte, error) {
+	webhooks, err := dto.MapWebhooksFromModels(subscriptions)
+	if err != nil {
+		return nil, fmt.Errorf("error map webhooks to dto. %w", err)
+	}
+
+	response := &dto.WebhooksResponse{
+		Webhooks: webhooks,
+	}
+
+	rawResponse, err := json.Marshal(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal response. %w", err)
+	}
+
+	return rawResponse, nil
+}
+
+func (p *webhookPresenter) ResponseDelete() ([]byte, error) {
+	response := &dto.DeleteWebhookResponse{
+		Ok: true,
+	}
+
+	rawResponse, err := json.Marshal(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal response. %w", err)
+	}
+
+	return rawResponse, nil
+}
+
+func (p *webhookPresenter) ResponseDeliveries(deliveries *models.Page[*models.WebhookDelivery]) ([]byte, error) {
+	mappedDeliveries, err := dto.MapWebhookDeliveriesFromModels(deliveries.Items)
+	if err != nil {
+		return nil, fmt.Errorf("error map webhook deliveries to dto. %w", err)
+	}
+
+	response := &dto.WebhookDeliveriesResponse{
+		Deliveries: mappedDeliveries,
+		Offset:     uint32(deliveries.NextOffset),
+		NextCursor: deliveries.NextCursor,
+	}
+
+	rawResponse, err := json.Marshal(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal response. %w", err)
+	}
+
+	return rawResponse, nil
+}
+
+func (p *webhookPresenter) ResponseReplay() ([]byte, error) {
+	response := &dto.ReplayWebhookDeliveryResponse{
+		Ok: true,
+	}
+
+	rawResponse, err := json.Marshal(&response)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal response. %w", err)
+	}
+
+	return rawResponse, nil
+}
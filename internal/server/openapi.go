@@ -0,0 +1,480 @@
+package server
+
+import (
+	"cernunnos/internal/middleware"
+	"cernunnos/internal/pkg/auth"
+	"cernunnos/internal/pkg/dto"
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/openapi"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// apiRoute documents a route served in initializeRouter
+type apiRoute struct {
+	method   string
+	path     string
+	id       string // Operation id. Matches the method name of traces and metrics
+	tag      string
+	summary  string
+	scope    string // Required scope. Empty for public routes
+	query    any    // Request decoded from query parameters
+	body     any    // Request decoded from JSON body
+	response any
+	errors   []int // Error codes specific to the route, besides auth, rate limit and internal ones
+	stream   bool  // Response is a stream of Server-Sent Events with response data
+	upload   bool  // Request body is a CSV or JSONL file
+	download bool  // Response is a CSV or JSONL file of response rows
+}
+
+type healthResponse struct {
+	Status string `json:"status"` // ok or failing
+}
+
+// apiRoutes must list every route of initializeRouter, API test fails otherwise
+var apiRoutes = []apiRoute{
+	{
+		method:  http.MethodGet,
+		path:    "/metrics",
+		id:      "metrics",
+		tag:     "service",
+		summary: "Prometheus metrics",
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/healthz",
+		id:       "healthz",
+		tag:      "service",
+		summary:  "Liveness probe",
+		response: healthResponse{},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/readyz",
+		id:       "readyz",
+		tag:      "service",
+		summary:  "Readiness probe. Responds 503 if any dependency is failing",
+		response: healthResponse{},
+		errors:   []int{http.StatusServiceUnavailable},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/status",
+		id:       "status",
+		tag:      "service",
+		summary:  "Build version, uptime and readiness checks results",
+		response: health.Report{},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/openapi.json",
+		id:      "openapi",
+		tag:     "service",
+		summary: "This document",
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/docs",
+		id:      "docs",
+		tag:     "service",
+		summary: "API documentation page",
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/storages",
+		id:       "storages",
+		tag:      "storages",
+		summary:  "List storages",
+		scope:    auth.ScopeRead,
+		query:    dto.StoragesRequest{},
+		response: dto.StoragesResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/storages/{storage_id}/products",
+		id:       "storage_products",
+		tag:      "storages",
+		summary:  "List products in a storage",
+		scope:    auth.ScopeRead,
+		query:    dto.StorageProductsRequest{},
+		response: dto.StorageProductsResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/products",
+		id:       "products",
+		tag:      "products",
+		summary:  "List products with their distribution between storages",
+		scope:    auth.ScopeRead,
+		query:    dto.ProductsRequest{},
+		response: dto.ProductsResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/products/search",
+		id:       "search_products",
+		tag:      "products",
+		summary:  "Search products by name. Tolerates partial and misspelled names",
+		scope:    auth.ScopeRead,
+		query:    dto.SearchProductsRequest{},
+		response: dto.ProductsResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/reservations",
+		id:       "reservations",
+		tag:      "reservations",
+		summary:  "List reservations",
+		scope:    auth.ScopeRead,
+		query:    dto.ReservationsRequest{},
+		response: dto.ReservationsResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/reservations/new",
+		id:       "reserve_product",
+		tag:      "reservations",
+		summary:  "Reserve products for a shipping. Distributed between storages, unless storage_id is passed",
+		scope:    auth.ScopeReserve,
+		body:     dto.ReserveRequest{},
+		response: dto.ReserveResponse{},
+		errors:   []int{http.StatusBadRequest, http.StatusConflict, http.StatusInsufficientStorage},
+	},
+	{
+		method:   http.MethodDelete,
+		path:     "/reservations/cancel",
+		id:       "cancel_reservation",
+		tag:      "reservations",
+		summary:  "Cancel reservation. Reserved products are available for reservation again",
+		scope:    auth.ScopeReserve,
+		body:     dto.CancelRequest{},
+		response: dto.CancelResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		method:   http.MethodDelete,
+		path:     "/reservations/release",
+		id:       "release_reservation",
+		tag:      "reservations",
+		summary:  "Release reservation. Reserved products are written off from stock",
+		scope:    auth.ScopeRelease,
+		body:     dto.ReleaseRequest{},
+		response: dto.ReleaseResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/stream/stock",
+		id:       "stream_stock",
+		tag:      "stock",
+		summary:  "Stream stock changes as Server-Sent Events. Event data is StockChange",
+		scope:    auth.ScopeRead,
+		query:    dto.StockStreamRequest{},
+		response: dto.StockChange{},
+		errors:   []int{http.StatusBadRequest},
+		stream:   true,
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/exports/stock",
+		id:       "export_stock",
+		tag:      "stock",
+		summary:  "Export stock of products in storages as a consistent snapshot. Rows are StockRow",
+		scope:    auth.ScopeRead,
+		query:    dto.ExportStockRequest{},
+		response: dto.StockRow{},
+		errors:   []int{http.StatusBadRequest},
+		download: true,
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/webhooks",
+		id:       "create_webhook",
+		tag:      "webhooks",
+		summary:  "Subscribe to events. The secret is returned only once",
+		scope:    auth.ScopeAdmin,
+		body:     dto.CreateWebhookRequest{},
+		response: dto.CreateWebhookResponse{},
+		errors:   []int{http.StatusBadRequest},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/webhooks",
+		id:       "webhooks",
+		tag:      "webhooks",
+		summary:  "List webhook subscriptions",
+		scope:    auth.ScopeAdmin,
+		response: dto.WebhooksResponse{},
+	},
+	{
+		method:   http.MethodDelete,
+		path:     "/webhooks/{webhook_id}",
+		id:       "delete_webhook",
+		tag:      "webhooks",
+		summary:  "Delete a subscription along with its deliveries",
+		scope:    auth.ScopeAdmin,
+		response: dto.DeleteWebhookResponse{},
+		errors:   []int{http.StatusNotFound},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/webhooks/{webhook_id}/deliveries",
+		id:       "webhook_deliveries",
+		tag:      "webhooks",
+		summary:  "List deliveries of a subscription in order of creation",
+		scope:    auth.ScopeAdmin,
+		query:    dto.WebhookDeliveriesRequest{},
+		response: dto.WebhookDeliveriesResponse{},
+		errors:   []int{http.StatusBadRequest, http.StatusNotFound},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/webhooks/{webhook_id}/deliveries/{delivery_id}/replay",
+		id:       "replay_webhook_delivery",
+		tag:      "webhooks",
+		summary:  "Deliver the event again, even if delivery is dead",
+		scope:    auth.ScopeAdmin,
+		response: dto.ReplayWebhookDeliveryResponse{},
+		errors:   []int{http.StatusNotFound},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/imports/{kind}",
+		id:       "import",
+		tag:      "imports",
+		summary:  "Load products, storages or stock from a file. Nothing is loaded, if any row is rejected",
+		scope:    auth.ScopeAdmin,
+		query:    dto.ImportRequest{},
+		response: dto.ImportResponse{},
+		errors:   []int{http.StatusBadRequest},
+		upload:   true,
+	},
+}
+
+// OpenAPI builds OpenAPI document of HTTP API. Schemas are derived from dto types.
+func OpenAPI(version string) *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Cernunnos",
+		Description: "Storage management service",
+		Version:     version,
+	})
+
+	doc.Components.SecuritySchemes["apiKey"] = &openapi.SecurityScheme{
+		Type: "apiKey",
+		Name: middleware.APIKeyHeader,
+		In:   "header",
+	}
+	doc.Components.SecuritySchemes["bearer"] = &openapi.SecurityScheme{
+		Type:         "http",
+		Scheme:       "bearer",
+		BearerFormat: "JWT",
+	}
+
+	for _, route := range apiRoutes {
+		doc.Add(route.method, route.path, buildOperation(doc, route))
+	}
+
+	return doc
+}
+
+func buildOperation(doc *openapi.Document, route apiRoute) *openapi.Operation {
+	operation := &openapi.Operation{
+		OperationId: route.id,
+		Summary:     route.summary,
+		Tags:        []string{route.tag},
+		Responses:   make(map[string]*openapi.Response),
+	}
+
+	for _, name := range pathParameters(route.path) {
+		schema := &openapi.Schema{Type: "string"}
+		if strings.HasSuffix(name, "_id") {
+			schema.Format = "uuid"
+		}
+
+		operation.Parameters = append(operation.Parameters, &openapi.Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	if route.query != nil {
+		operation.Parameters = append(operation.Parameters, doc.QueryParameters(route.query)...)
+	}
+
+	if route.upload {
+		file := &openapi.Schema{Type: "string", Description: "CSV with a header or JSON object per line"}
+
+		operation.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content: map[string]*openapi.MediaType{
+				"text/csv":             {Schema: file},
+				"application/x-ndjson": {Schema: file},
+			},
+		}
+	}
+
+	if route.body != nil {
+		operation.RequestBody = &openapi.RequestBody{
+			Required: true,
+			Content:  map[string]*openapi.MediaType{"application/json": {Schema: doc.Schema(route.body)}},
+		}
+	}
+
+	switch {
+	case route.stream:
+		operation.Parameters = append(operation.Parameters, &openapi.Parameter{
+			Name:        "Last-Event-ID",
+			In:          "header",
+			Description: "Id of the last received event. Overrides last_event_id",
+			Schema:      &openapi.Schema{Type: "string"},
+		})
+		operation.Responses["200"] = &openapi.Response{
+			Description: "Stream of stock events",
+			Content: map[string]*openapi.MediaType{
+				"text/event-stream": {Schema: doc.Schema(route.response)},
+			},
+		}
+	case route.download:
+		// Every JSONL line is a row, CSV columns are named as row fields
+		operation.Responses["200"] = &openapi.Response{
+			Description: "File of rows",
+			Content: map[string]*openapi.MediaType{
+				"text/csv":             {Schema: &openapi.Schema{Type: "string"}},
+				"application/x-ndjson": {Schema: doc.Schema(route.response)},
+			},
+		}
+	case route.response != nil:
+		operation.Responses["200"] = &openapi.Response{
+			Description: "OK",
+			Content:     map[string]*openapi.MediaType{"application/json": {Schema: doc.Schema(route.response)}},
+		}
+	default:
+		operation.Responses["200"] = &openapi.Response{Description: "OK"}
+	}
+
+	// Reads may be served by a replica
+	if route.method == http.MethodGet && route.scope != "" {
+		operation.Parameters = append(operation.Parameters, &openapi.Parameter{
+			Name:        "X-Consistency",
+			In:          "header",
+			Description: "strong reads from the primary database, so own writes are seen at once",
+			Schema:      &openapi.Schema{Type: "string"},
+		})
+	}
+
+	codes := slices.Clone(route.errors)
+
+	// Writes are replayed for retries with the same key
+	if route.method != http.MethodGet && route.scope != "" {
+		operation.Parameters = append(operation.Parameters, &openapi.Parameter{
+			Name: middleware.IdempotencyKeyHeader,
+			In:   "header",
+			Description: "unique key of the write. Retries with the key get the first response with " +
+				middleware.IdempotentReplayedHeader + " header",
+			Schema: &openapi.Schema{Type: "string"},
+		})
+
+		codes = append(codes, http.StatusConflict, http.StatusUnprocessableEntity)
+	}
+
+	if route.scope != "" {
+		operation.Description = fmt.Sprintf("Requires %s scope", route.scope)
+		operation.Security = []map[string][]string{
+			{"apiKey": {route.scope}},
+			{"bearer": {route.scope}},
+		}
+
+		codes = append(codes, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests)
+	}
+
+	codes = append(codes, http.StatusInternalServerError)
+
+	for _, code := range codes {
+		operation.Responses[fmt.Sprint(code)] = &openapi.Response{
+			Description: http.StatusText(code),
+			Content:     map[string]*openapi.MediaType{"application/json": {Schema: doc.Schema(errs.APIError{})}},
+		}
+	}
+
+	return operation
+}
+
+func pathParameters(path string) []string {
+	var names []string
+
+	for _, segment := range strings.Split(path, "/") {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			names = append(names, strings.TrimSuffix(name, "}"))
+		}
+	}
+
+	return names
+}
+
+// Routes lists method and pattern of every route served by HTTP API, e.g. "GET /storages"
+func Routes() ([]string, error) {
+	s := &Server{log: slog.Default(), errorsHandler: errs.NewErrorHandler()}
+	s.initializeRouter()
+
+	var routes []string
+
+	err := chi.Walk(s.Mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+normalizeRoute(route))
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walk routes. %w", err)
+	}
+
+	slices.Sort(routes)
+
+	return routes, nil
+}
+
+// normalizeRoute drops trailing slash of subrouter index routes, as "/storages/" is served as "/storages"
+func normalizeRoute(route string) string {
+	route = strings.ReplaceAll(route, "/*/", "/")
+
+	if len(route) > 1 {
+		route = strings.TrimSuffix(route, "/")
+	}
+
+	return route
+}
+
+func (s *Server) openapi(w http.ResponseWriter, r *http.Request) {
+	out, err := json.Marshal(OpenAPI(s.version))
+	if err != nil {
+		s.log.Error("error marshal openapi document", logger.Err(err))
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	s.response(w, http.StatusOK, out)
+}
+
+func (s *Server) docs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(openapi.DocsPage); err != nil {
+		s.log.Error("error write docs page", logger.Err(err))
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -10,10 +11,17 @@ import (
 	"net/http"
 	"time"
 
+	"cernunnos/internal/grpcserver"
 	"cernunnos/internal/middleware"
+	"cernunnos/internal/pkg/auth"
 	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/pkg/dto"
 	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/health"
 	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/ratelimit"
+	"cernunnos/internal/pkg/tracing"
 	"cernunnos/internal/server/interface/controllers"
 
 	"github.com/go-chi/render"
@@ -21,68 +29,237 @@ import (
 	chimw "github.com/go-chi/chi/v5/middleware"
 
 	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
 )
 
+// Worker is a background process running along with the HTTP server. Run must return when ctx is
+// cancelled.
+type Worker interface {
+	Run(ctx context.Context) error
+}
+
 type Server struct {
 	*chi.Mux
 
-	address       string
-	log           *slog.Logger
-	errorsHandler errs.ErrorHandler
-	controllers   *controllers.RootController
+	address        string
+	version        string
+	timeouts       config.Timeouts
+	workers        []Worker
+	log            *slog.Logger
+	errorsHandler  errs.ErrorHandler
+	controllers    *controllers.RootController
+	health         *health.Checker
+	authenticator  middleware.Authenticator // nil if authentication is disabled
+	rateLimits     config.RateLimits
+	idempotency    middleware.IdempotencyStore
+	idempotencyTTL time.Duration
+	shutdown       chan struct{} // Closed on shutdown to end streams, as they are never idle
 }
 
-func newServer(
+// New builds HTTP server. Passed nil authenticator disables authentication, nil idempotency store
+// disables idempotency keys.
+func New(
 	cfg *config.Config,
 	log *slog.Logger,
 	rootController *controllers.RootController,
+	healthChecker *health.Checker,
+	authenticator middleware.Authenticator,
+	idempotency middleware.IdempotencyStore,
+	workers []Worker,
 ) *Server {
 	s := &Server{
-		address:       cfg.Address,
-		log:           log,
-		errorsHandler: errs.NewErrorHandler(),
-		controllers:   rootController,
+		address:        cfg.Address,
+		version:        cfg.Version,
+		timeouts:       cfg.Timeouts,
+		workers:        workers,
+		log:            log,
+		errorsHandler:  errs.NewErrorHandler(),
+		controllers:    rootController,
+		health:         healthChecker,
+		authenticator:  authenticator,
+		rateLimits:     cfg.RateLimits,
+		idempotency:    idempotency,
+		idempotencyTTL: cfg.IdempotencyTTL,
+		shutdown:       make(chan struct{}),
 	}
 
+	metrics.RateLimitConfigured("read", cfg.RateLimits.ReadRPS, cfg.RateLimits.ReadBurst)
+	metrics.RateLimitConfigured("write", cfg.RateLimits.WriteRPS, cfg.RateLimits.WriteBurst)
+
 	s.initializeRouter()
 
 	return s
 }
 
-func (s *Server) Start() error {
+// Start serves HTTP requests and runs background workers until ctx is cancelled. On shutdown
+// in-flight requests are drained within shutdown timeout, then workers are stopped.
+func (s *Server) Start(ctx context.Context) error {
+	httpServer := &http.Server{
+		Addr:              s.address,
+		Handler:           s,
+		ReadTimeout:       s.timeouts.Read,
+		ReadHeaderTimeout: s.timeouts.Read,
+		WriteTimeout:      s.timeouts.Write,
+		IdleTimeout:       s.timeouts.Idle,
+	}
+
+	httpServer.RegisterOnShutdown(func() { close(s.shutdown) })
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	workers, workersCtx := errgroup.WithContext(workersCtx)
+
+	for _, worker := range s.workers {
+		workers.Go(func() error {
+			return worker.Run(workersCtx)
+		})
+	}
+
 	s.log.Info("starting cernunnos server", slog.String("address", s.address))
 
-	if err := http.ListenAndServe(s.address, s); err != nil {
-		return fmt.Errorf("error listen to %s. %w", s.address, err)
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		stopWorkers()
+
+		return errors.Join(fmt.Errorf("error listen to %s. %w", s.address, err), workers.Wait())
+	case <-ctx.Done():
 	}
 
-	return nil
+	s.log.Info("shutting down cernunnos server", slog.Duration("timeout", s.timeouts.Shutdown))
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.timeouts.Shutdown)
+	defer cancel()
+
+	var err error
+
+	if shutdownErr := httpServer.Shutdown(shutdownCtx); shutdownErr != nil {
+		err = fmt.Errorf("error drain in-flight requests. %w", shutdownErr)
+	}
+
+	stopWorkers()
+
+	if workersErr := workers.Wait(); workersErr != nil && !errors.Is(workersErr, context.Canceled) {
+		err = errors.Join(err, fmt.Errorf("error stop workers. %w", workersErr))
+	}
+
+	return err
+}
+
+// Servers are HTTP and gRPC servers sharing controllers and interactors
+type Servers struct {
+	HTTP *Server
+	GRPC *grpcserver.Server // nil if gRPC API is disabled
+}
+
+func newServers(httpServer *Server, grpcServer *grpcserver.Server) *Servers {
+	return &Servers{HTTP: httpServer, GRPC: grpcServer}
+}
+
+// Start runs all servers until ctx is cancelled or any of them fails
+func (s *Servers) Start(ctx context.Context) error {
+	servers, ctx := errgroup.WithContext(ctx)
+
+	servers.Go(func() error {
+		return s.HTTP.Start(ctx)
+	})
+
+	if s.GRPC != nil {
+		servers.Go(func() error {
+			return s.GRPC.Start(ctx)
+		})
+	}
+
+	return servers.Wait()
 }
 
 func (s *Server) initializeRouter() {
 	router := chi.NewRouter()
-	middlewareBuilder := middleware.NewMiddlewareBuilder(s.log.WithGroup("middleware"))
+	middlewareBuilder := middleware.NewMiddlewareBuilder(
+		s.log.WithGroup("middleware"),
+		s.errorsHandler,
+		s.authenticator,
+		s.idempotency,
+		s.idempotencyTTL,
+	)
 
-	router.Use(middlewareBuilder.Recovery)
 	router.Use(chimw.RequestID)
+	router.Use(middlewareBuilder.AccessLog)
+	router.Use(middlewareBuilder.Recovery)
 	router.Use(render.SetContentType(render.ContentTypeJSON))
+	router.Use(middlewareBuilder.Consistency)
+
+	router.Method(http.MethodGet, "/metrics", metrics.Handler())
+	router.Get("/healthz", s.healthz)
+	router.Get("/readyz", s.readyz)
+	router.Get("/status", s.status)
+	router.Get("/openapi.json", s.openapi)
+	router.Get("/docs", s.docs)
+
+	router.Group(func(router chi.Router) {
+		router.Use(middlewareBuilder.Authentication)
 
-	router.Route("/storages", func(r chi.Router) {
-		r.Get("/", s.handle(s.storages, "storages"))
-		r.Route("/{storage_id}/products", func(r chi.Router) {
-			r.Get("/", s.handle(s.storageProducts, "storage_products"))
+		readLimit := middlewareBuilder.RateLimit(
+			ratelimit.New(s.rateLimits.ReadRPS, s.rateLimits.ReadBurst), "read",
+		)
+		writeLimit := middlewareBuilder.RateLimit(
+			ratelimit.New(s.rateLimits.WriteRPS, s.rateLimits.WriteBurst), "write",
+		)
+
+		read := chi.Chain(middlewareBuilder.RequireScope(auth.ScopeRead), readLimit).Handler
+		reserve := chi.Chain(
+			middlewareBuilder.RequireScope(auth.ScopeReserve), writeLimit, middlewareBuilder.Idempotency,
+		).Handler
+		release := chi.Chain(
+			middlewareBuilder.RequireScope(auth.ScopeRelease), writeLimit, middlewareBuilder.Idempotency,
+		).Handler
+		admin := chi.Chain(
+			middlewareBuilder.RequireScope(auth.ScopeAdmin), writeLimit, middlewareBuilder.Idempotency,
+		).Handler
+		adminRead := chi.Chain(middlewareBuilder.RequireScope(auth.ScopeAdmin), readLimit).Handler
+
+		router.Route("/storages", func(r chi.Router) {
+			r.With(read).Get("/", s.handle(s.storages, "storages"))
+			r.Route("/{storage_id}/products", func(r chi.Router) {
+				r.With(read).Get("/", s.handle(s.storageProducts, "storage_products"))
+			})
 		})
-	})
 
-	router.Route("/products", func(r chi.Router) {
-		r.Get("/", s.handle(s.products, "products"))
-	})
+		router.Route("/products", func(r chi.Router) {
+			r.With(read).Get("/", s.handle(s.products, "products"))
+			r.With(read).Get("/search", s.handle(s.searchProducts, "search_products"))
+		})
+
+		router.Route("/reservations", func(r chi.Router) {
+			r.With(read).Get("/", s.handle(s.reservations, "reservations"))
+			r.With(reserve).Post("/new", s.handle(s.reserveProduct, "reserve_product"))
+			r.With(reserve).Delete("/cancel", s.handle(s.cancelProductReservation, "cancel_reservation"))
+			r.With(release).Delete("/release", s.handle(s.releaseProductReservation, "release_reservation"))
+		})
+
+		router.With(read).Get("/stream/stock", s.streamStock)
+		router.With(read).Get("/exports/stock", s.exportStock)
+
+		router.Route("/webhooks", func(r chi.Router) {
+			r.With(admin).Post("/", s.handle(s.createWebhook, "create_webhook"))
+			r.With(adminRead).Get("/", s.handle(s.webhooks, "webhooks"))
+			r.With(admin).Delete("/{webhook_id}", s.handle(s.deleteWebhook, "delete_webhook"))
+			r.With(adminRead).Get("/{webhook_id}/deliveries", s.handle(s.webhookDeliveries, "webhook_deliveries"))
+			r.With(admin).Post(
+				"/{webhook_id}/deliveries/{delivery_id}/replay",
+				s.handle(s.replayWebhookDelivery, "replay_webhook_delivery"),
+			)
+		})
 
-	router.Route("/reservations", func(r chi.Router) {
-		r.Get("/", s.handle(s.reservations, "reservations"))
-		r.Post("/new", s.handle(s.reserveProduct, "reserve_product"))
-		r.Delete("/cancel", s.handle(s.cancelProductReservation, "cancel_reservation"))
-		r.Delete("/release", s.handle(s.releaseProductReservation, "release_reservation"))
+		// The body is limited before idempotency middleware reads it
+		router.With(chimw.RequestSize(importMaxSize), admin).Post("/imports/{kind}", s.handle(s.importRows, "import"))
 	})
 
 	s.Mux = router
@@ -90,48 +267,72 @@ func (s *Server) initializeRouter() {
 
 const requestTimeout time.Duration = 15 * time.Second
 
+// Max size of an uploaded import file. Larger files are meant to be imported with the CLI
+const importMaxSize = 32 << 20
+
 type handlerFunc func(ctx context.Context, r *http.Request) ([]byte, error)
 
 func (s *Server) handle(h handlerFunc, mathodName string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+		ctx, span := tracing.StartServer(r.Context(), r.Header, mathodName,
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", chi.RouteContext(r.Context()).RoutePattern()),
+		)
+
+		if spanContext := span.SpanContext(); spanContext.IsValid() {
+			logger.AddAttrs(ctx, slog.String("trace_id", spanContext.TraceID().String()))
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, requestTimeout)
 		defer cancel()
 
+		observe := metrics.ObserveRequest(mathodName)
+
 		resp, err := h(ctx, r)
 		if err != nil {
-			s.responseError(w, err, mathodName)
+			code := s.responseError(ctx, w, err, mathodName)
+
+			observe(code)
+			span.SetAttributes(attribute.Int("http.response.status_code", code))
+			tracing.End(span, err)
 
 			return
 		}
 
 		s.response(w, http.StatusOK, resp)
+		observe(http.StatusOK)
+		span.SetAttributes(attribute.Int("http.response.status_code", http.StatusOK))
+		span.End()
 	}
 }
 
 func (s *Server) responseError(
+	ctx context.Context,
 	w http.ResponseWriter,
 	e error,
 	methodName string,
-) {
+) int {
 	log := s.log.WithGroup("api_error").With(slog.String("method_name", methodName))
 	apiErr := s.errorsHandler.Handle(e)
 
-	log.Error("error", logger.Err(e))
+	log.ErrorContext(ctx, "error", logger.Err(e))
 
 	out, err := json.Marshal(&apiErr)
 	if err != nil {
-		log.Error("error marshal api error", logger.Err(err))
+		log.ErrorContext(ctx, "error marshal api error", logger.Err(err))
 		w.WriteHeader(http.StatusInternalServerError)
 
-		return
+		return http.StatusInternalServerError
 	}
 
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(int(apiErr.Code))
 
 	if _, err = w.Write(out); err != nil {
-		log.Error("error write api error", logger.Err(err))
+		log.ErrorContext(ctx, "error write api error", logger.Err(err))
 	}
+
+	return int(apiErr.Code)
 }
 
 func (s *Server) response(
@@ -147,6 +348,8 @@ func (s *Server) response(
 	}
 }
 
+// buildRequest decodes a request from the JSON body and URL query parameters. Query
+// parameters override the body fields, so GET endpoints can be called without a body.
 func buildRequest[R any](r *http.Request) (*R, error) {
 	rawBody, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -161,9 +364,18 @@ func buildRequest[R any](r *http.Request) (*R, error) {
 
 	var request R
 
-	if err = json.Unmarshal(rawBody, &request); err != nil {
+	if len(bytes.TrimSpace(rawBody)) > 0 {
+		if err = json.Unmarshal(rawBody, &request); err != nil {
+			return nil, errors.Join(
+				fmt.Errorf("error unmarshal request body. %w", err),
+				errs.ErrorBadRequest,
+			)
+		}
+	}
+
+	if err = dto.DecodeQuery(r.URL.Query(), &request); err != nil {
 		return nil, errors.Join(
-			fmt.Errorf("error unmarshal request body. %w", err),
+			fmt.Errorf("error decode request query. %w", err),
 			errs.ErrorBadRequest,
 		)
 	}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/tracing"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// Comments are sent to idle streams, so proxies keep connections open
+	streamHeartbeat = 15 * time.Second
+	// Client reconnection delay, in milliseconds
+	streamRetry = 3000
+)
+
+// streamStock pushes stock changes as Server-Sent Events until the client disconnects or the server
+// shuts down. Unlike handle, the stream is not limited with request and write timeouts.
+func (s *Server) streamStock(w http.ResponseWriter, r *http.Request) {
+	const methodName = "stream_stock"
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	spanCtx, span := tracing.StartServer(ctx, r.Header, methodName,
+		attribute.String("http.request.method", r.Method),
+		attribute.String("http.route", chi.RouteContext(ctx).RoutePattern()),
+	)
+
+	if spanContext := span.SpanContext(); spanContext.IsValid() {
+		logger.AddAttrs(spanCtx, slog.String("trace_id", spanContext.TraceID().String()))
+	}
+
+	observe := metrics.ObserveRequest(methodName)
+
+	events, err := s.subscribeStock(spanCtx, r)
+	if err != nil {
+		code := s.responseError(spanCtx, w, err, methodName)
+
+		observe(code)
+		span.SetAttributes(attribute.Int("http.response.status_code", code))
+		tracing.End(span, err)
+
+		return
+	}
+
+	// The span and the latency metric cover subscription only, as streams last for long
+	observe(http.StatusOK)
+	span.SetAttributes(attribute.Int("http.response.status_code", http.StatusOK))
+	span.End()
+
+	controller := http.NewResponseController(w)
+
+	if err = controller.SetWriteDeadline(time.Time{}); err != nil {
+		s.log.WarnContext(ctx, "error disable stream write timeout", logger.Err(err))
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	write := func(event []byte) bool {
+		if _, err := w.Write(event); err != nil {
+			return false
+		}
+
+		return controller.Flush() == nil
+	}
+
+	if !write(fmt.Appendf(nil, "retry: %d\n\n", streamRetry)) {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-heartbeat.C:
+			if !write([]byte(": heartbeat\n\n")) {
+				return
+			}
+		case event, ok := <-events:
+			if !ok || !write(event) {
+				return
+			}
+
+			heartbeat.Reset(streamHeartbeat)
+		}
+	}
+}
+
+func (s *Server) subscribeStock(ctx context.Context, r *http.Request) (<-chan []byte, error) {
+	request, err := buildRequest[dto.StockStreamRequest](r)
+	if err != nil {
+		return nil, fmt.Errorf("error build stock stream request. %w", err)
+	}
+
+	// EventSource passes the id of the last received event on reconnection
+	if lastEventId := r.Header.Get("Last-Event-ID"); lastEventId != "" {
+		request.LastEventId = lastEventId
+	}
+
+	s.log.DebugContext(ctx, "request", slog.Any("dto", request))
+
+	events, err := s.controllers.StockController.Changes(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("error subscribe to stock changes. %w", err)
+	}
+
+	return events, nil
+}
@@ -4,58 +4,252 @@
 package server
 
 import (
+	"cernunnos/internal/grpcserver"
+	"cernunnos/internal/middleware"
+	"cernunnos/internal/pkg/auth"
 	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/pkg/health"
 	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/publisher"
 	"cernunnos/internal/server/interface/controllers"
 	"cernunnos/internal/server/interface/presenters"
 	"cernunnos/internal/usecase/interactors"
 	"cernunnos/internal/usecase/repository"
+	apikeysRepo "cernunnos/internal/usecase/repository/apikeys"
+	exportsRepo "cernunnos/internal/usecase/repository/exports"
+	idempotencyRepo "cernunnos/internal/usecase/repository/idempotency"
+	importsRepo "cernunnos/internal/usecase/repository/imports"
+	outboxRepo "cernunnos/internal/usecase/repository/outbox"
 	productsRepo "cernunnos/internal/usecase/repository/products"
 	reservationsRepo "cernunnos/internal/usecase/repository/reservations"
+	stockRepo "cernunnos/internal/usecase/repository/stock"
 	storagesRepo "cernunnos/internal/usecase/repository/storages"
+	webhooksRepo "cernunnos/internal/usecase/repository/webhooks"
+	"cernunnos/internal/usecase/workers"
 	"database/sql"
+	"fmt"
 	"log/slog"
+	"net/http"
 
 	"github.com/google/wire"
 )
 
-func ProvideServer(c *config.Config) (*Server, func(), error) {
+func ProvideServers(c *config.Config) (*Servers, func(), error) {
 	wire.Build(
 		repository.ProvideDatabaseConnection,
+		repository.ProvideReplicaConnection,
 		provideStoragesRepository,
 		provideProductsRepository,
 		provideReservationsRepository,
+		provideAPIKeysRepository,
+		provideOutboxRepository,
+		provideWebhooksRepository,
+		provideStockRepository,
+		provideIdempotencyRepository,
+		provideImportsRepository,
+		provideExportsRepository,
+		wire.Bind(new(middleware.IdempotencyStore), new(idempotencyRepo.Repository)),
+		providePublisher,
 		provideLogger,
+		provideHealthChecker,
+		provideAuthenticator,
+		provideWorkers,
 
 		presenters.NewProductPresenter,
 		presenters.NewReservationPresenter,
 		presenters.NewStoragePresenter,
+		presenters.NewStockPresenter,
+		presenters.NewWebhookPresenter,
+		presenters.NewImportPresenter,
+		presenters.NewExportPresenter,
 
 		interactors.NewProductInteractor,
 		interactors.NewReservationInteractor,
 		interactors.NewStorageInteractor,
+		interactors.NewAPIKeyInteractor,
+		interactors.NewWebhookInteractor,
+		interactors.NewStockInteractor,
+		interactors.NewImportInteractor,
+		interactors.NewExportInteractor,
 
 		controllers.NewProductController,
 		controllers.NewStorageController,
 		controllers.NewReservationController,
+		controllers.NewStockController,
+		controllers.NewWebhookController,
+		controllers.NewImportController,
+		controllers.NewExportController,
 		controllers.NewRootController,
-		newServer,
+		New,
+		provideGRPCServer,
+		newServers,
 	)
-	return &Server{}, func() {}, nil
+	return &Servers{}, func() {}, nil
 }
 
-func provideStoragesRepository(db *sql.DB) storagesRepo.Repository {
-	return storagesRepo.NewRepository(db)
+func provideAPIKeysRepository(db *sql.DB) apikeysRepo.Repository {
+	return apikeysRepo.NewRepository(db)
 }
 
-func provideProductsRepository(db *sql.DB) productsRepo.Repository {
-	return productsRepo.NewRepository(db)
+func provideStoragesRepository(db *sql.DB, replica repository.Replica) storagesRepo.Repository {
+	return storagesRepo.NewRepository(db, replica.DB)
 }
 
-func provideReservationsRepository(db *sql.DB) reservationsRepo.Repository {
-	return reservationsRepo.NewRepository(db)
+func provideProductsRepository(db *sql.DB, replica repository.Replica) productsRepo.Repository {
+	return productsRepo.NewRepository(db, replica.DB)
+}
+
+func provideReservationsRepository(
+	db *sql.DB,
+	replica repository.Replica,
+	outbox outboxRepo.Repository,
+	stock stockRepo.Repository,
+) reservationsRepo.Repository {
+	return reservationsRepo.NewRepository(db, replica.DB, outbox, stock)
+}
+
+func provideOutboxRepository(db *sql.DB) outboxRepo.Repository {
+	return outboxRepo.NewRepository(db)
+}
+
+// provideStockRepository listens to stock changes on primary, as notifications are not replicated
+func provideStockRepository(c *config.Config, db *sql.DB) stockRepo.Repository {
+	return stockRepo.NewRepository(db, repository.DSN(c, c.DatabaseHost))
+}
+
+func provideIdempotencyRepository(db *sql.DB) idempotencyRepo.Repository {
+	return idempotencyRepo.NewRepository(db)
+}
+
+func provideImportsRepository(
+	db *sql.DB,
+	outbox outboxRepo.Repository,
+	stock stockRepo.Repository,
+) importsRepo.Repository {
+	return importsRepo.NewRepository(db, outbox, stock)
+}
+
+// provideExportsRepository reads exports from replica, if configured
+func provideExportsRepository(db *sql.DB, replica repository.Replica) exportsRepo.Repository {
+	return exportsRepo.NewRepository(db, replica.DB)
+}
+
+func provideWebhooksRepository(db *sql.DB) webhooksRepo.Repository {
+	return webhooksRepo.NewRepository(db)
+}
+
+// providePublisher returns nil if events publishing is disabled
+func providePublisher(c *config.Config) (publisher.Publisher, func(), error) {
+	return publisher.New(c.Outbox.Publisher, c.Outbox.File, c.Outbox.WebhookURL)
 }
 
 func provideLogger(c *config.Config) *slog.Logger {
 	return logger.NewLogger(logger.MapLevel(c.LogLevel))
 }
+
+func provideHealthChecker(c *config.Config, db *sql.DB, replica repository.Replica) *health.Checker {
+	checker := health.NewChecker(c.Version)
+
+	checker.AddSerial("pool", repository.PoolCheck(db))
+	checker.Add("database", repository.PingCheck(db))
+	checker.Add("schema", repository.SchemaCheck(db))
+
+	if replica.DB != nil {
+		checker.Add("replica", repository.PingCheck(replica.DB))
+	}
+
+	return checker
+}
+
+func provideWorkers(
+	c *config.Config,
+	log *slog.Logger,
+	outbox outboxRepo.Repository,
+	eventsPublisher publisher.Publisher,
+	webhooks webhooksRepo.Repository,
+	webhookInteractor interactors.WebhookInteractor,
+	stock stockRepo.Repository,
+	stockInteractor interactors.StockInteractor,
+	idempotency idempotencyRepo.Repository,
+) []Worker {
+	background := []Worker{
+		workers.NewStockListener(log, stock, outbox, stockInteractor),
+		workers.NewIdempotencyPurger(log, idempotency, c.IdempotencyTTL),
+	}
+
+	// Webhook deliveries are enqueued by the relay along with the configured publisher
+	if c.Webhooks.Enabled {
+		eventsPublisher = publisher.Fanout(eventsPublisher, webhookInteractor)
+
+		background = append(background, workers.NewWebhookSender(
+			log,
+			webhooks,
+			&http.Client{Timeout: c.Webhooks.Timeout},
+			workers.WebhookSenderOptions{
+				PollInterval: c.Webhooks.PollInterval,
+				BatchSize:    c.Webhooks.BatchSize,
+				MaxAttempts:  c.Webhooks.MaxAttempts,
+				BackoffBase:  c.Webhooks.BackoffBase,
+				BackoffMax:   c.Webhooks.BackoffMax,
+			},
+		))
+	}
+
+	if eventsPublisher != nil {
+		background = append(background, workers.NewOutboxRelay(
+			log, outbox, eventsPublisher, c.Outbox.PollInterval, c.Outbox.BatchSize,
+		))
+	}
+
+	return background
+}
+
+// provideGRPCServer returns nil if gRPC API is disabled
+func provideGRPCServer(
+	c *config.Config,
+	log *slog.Logger,
+	healthChecker *health.Checker,
+	authenticator middleware.Authenticator,
+	storageInteractor interactors.StorageInteractor,
+	productInteractor interactors.ProductInteractor,
+	reservationInteractor interactors.ReservationInteractor,
+) *grpcserver.Server {
+	if c.GRPC.Address == "" {
+		return nil
+	}
+
+	return grpcserver.New(
+		log,
+		grpcserver.Options{Config: c, Authenticator: authenticator, Checker: healthChecker},
+		storageInteractor,
+		productInteractor,
+		reservationInteractor,
+	)
+}
+
+// provideAuthenticator returns nil if authentication is disabled
+func provideAuthenticator(
+	c *config.Config,
+	apiKeyInteractor interactors.APIKeyInteractor,
+) (middleware.Authenticator, error) {
+	switch c.AuthMode {
+	case config.AuthNone:
+		return nil, nil
+	case config.AuthAPIKey, "":
+		return apiKeyInteractor, nil
+	case config.AuthJWT:
+		authenticator, err := auth.NewJWTAuthenticator(auth.JWTOptions{
+			KeysFile: c.JWT.KeysFile,
+			Secret:   c.JWT.Secret,
+			Issuer:   c.JWT.Issuer,
+			Audience: c.JWT.Audience,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error create jwt authenticator. %w", err)
+		}
+
+		return authenticator, nil
+	default:
+		return nil, fmt.Errorf("error unknown auth mode %s", c.AuthMode)
+	}
+}
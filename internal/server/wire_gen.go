@@ -7,60 +7,258 @@
 package server
 
 import (
+	"cernunnos/internal/grpcserver"
+	"cernunnos/internal/middleware"
+	"cernunnos/internal/pkg/auth"
 	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/pkg/health"
 	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/publisher"
 	"cernunnos/internal/server/interface/controllers"
 	"cernunnos/internal/server/interface/presenters"
 	"cernunnos/internal/usecase/interactors"
 	"cernunnos/internal/usecase/repository"
+	"cernunnos/internal/usecase/repository/apikeys"
+	"cernunnos/internal/usecase/repository/exports"
+	"cernunnos/internal/usecase/repository/idempotency"
+	"cernunnos/internal/usecase/repository/imports"
+	"cernunnos/internal/usecase/repository/outbox"
 	"cernunnos/internal/usecase/repository/products"
 	"cernunnos/internal/usecase/repository/reservations"
+	"cernunnos/internal/usecase/repository/stock"
 	repository2 "cernunnos/internal/usecase/repository/storages"
+	"cernunnos/internal/usecase/repository/webhooks"
+	"cernunnos/internal/usecase/workers"
 	"database/sql"
+	"fmt"
 	"log/slog"
+	"net/http"
 )
 
 // Injectors from wire.go:
 
-func ProvideServer(c *config.Config) (*Server, func(), error) {
+func ProvideServers(c *config.Config) (*Servers, func(), error) {
 	logger := provideLogger(c)
 	productPresenter := presenters.NewProductPresenter()
 	db, cleanup, err := repository.ProvideDatabaseConnection(c)
 	if err != nil {
 		return nil, nil, err
 	}
-	productsRepository := provideProductsRepository(db)
+	replica, cleanup2, err := repository.ProvideReplicaConnection(c)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	productsRepository := provideProductsRepository(db, replica)
 	productInteractor := interactors.NewProductInteractor(logger, productsRepository)
 	productController := controllers.NewProductController(logger, productPresenter, productInteractor)
-	reservationsRepository := provideReservationsRepository(db)
+	outboxRepository := provideOutboxRepository(db)
+	stockRepository := provideStockRepository(c, db)
+	reservationsRepository := provideReservationsRepository(db, replica, outboxRepository, stockRepository)
 	reservationInteractor := interactors.NewReservationInteractor(logger, reservationsRepository)
 	reservationPresenter := presenters.NewReservationPresenter()
 	reservationController := controllers.NewReservationController(logger, reservationInteractor, reservationPresenter)
-	repositoryRepository := provideStoragesRepository(db)
+	repositoryRepository := provideStoragesRepository(db, replica)
 	storageInteractor := interactors.NewStorageInteractor(logger, repositoryRepository)
 	storagePresenter := presenters.NewStoragePresenter()
 	storageController := controllers.NewStorageController(logger, storageInteractor, storagePresenter)
-	rootController := controllers.NewRootController(productController, reservationController, storageController)
-	server := newServer(c, logger, rootController)
-	return server, func() {
+	stockInteractor := interactors.NewStockInteractor(logger, outboxRepository)
+	stockPresenter := presenters.NewStockPresenter()
+	stockController := controllers.NewStockController(logger, stockInteractor, stockPresenter)
+	webhooksRepository := provideWebhooksRepository(db)
+	webhookInteractor := interactors.NewWebhookInteractor(logger, webhooksRepository)
+	webhookPresenter := presenters.NewWebhookPresenter()
+	webhookController := controllers.NewWebhookController(logger, webhookInteractor, webhookPresenter)
+	importsRepository := provideImportsRepository(db, outboxRepository, stockRepository)
+	importInteractor := interactors.NewImportInteractor(logger, importsRepository)
+	importPresenter := presenters.NewImportPresenter()
+	importController := controllers.NewImportController(logger, importInteractor, importPresenter)
+	exportsRepository := provideExportsRepository(db, replica)
+	exportInteractor := interactors.NewExportInteractor(logger, exportsRepository)
+	exportPresenter := presenters.NewExportPresenter()
+	exportController := controllers.NewExportController(logger, exportInteractor, exportPresenter)
+	rootController := controllers.NewRootController(productController, reservationController, storageController, stockController, webhookController, importController, exportController)
+	checker := provideHealthChecker(c, db, replica)
+	apikeysRepository := provideAPIKeysRepository(db)
+	apiKeyInteractor := interactors.NewAPIKeyInteractor(logger, apikeysRepository)
+	authenticator, err := provideAuthenticator(c, apiKeyInteractor)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	idempotencyRepository := provideIdempotencyRepository(db)
+	publisher, cleanup3, err := providePublisher(c)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	v := provideWorkers(c, logger, outboxRepository, publisher, webhooksRepository, webhookInteractor, stockRepository, stockInteractor, idempotencyRepository)
+	server := New(c, logger, rootController, checker, authenticator, idempotencyRepository, v)
+	grpcserverServer := provideGRPCServer(c, logger, checker, authenticator, storageInteractor, productInteractor, reservationInteractor)
+	servers := newServers(server, grpcserverServer)
+	return servers, func() {
+		cleanup3()
+		cleanup2()
 		cleanup()
 	}, nil
 }
 
 // wire.go:
 
-func provideStoragesRepository(db *sql.DB) repository2.Repository {
-	return repository2.NewRepository(db)
+func provideAPIKeysRepository(db *sql.DB) apikeys.Repository {
+	return apikeys.NewRepository(db)
+}
+
+func provideStoragesRepository(db *sql.DB, replica repository.Replica) repository2.Repository {
+	return repository2.NewRepository(db, replica.DB)
+}
+
+func provideProductsRepository(db *sql.DB, replica repository.Replica) products.Repository {
+	return products.NewRepository(db, replica.DB)
+}
+
+func provideReservationsRepository(
+	db *sql.DB,
+	replica repository.Replica, outbox2 outbox.Repository, stock2 stock.Repository,
+
+) reservations.Repository {
+	return reservations.NewRepository(db, replica.DB, outbox2, stock2)
+}
+
+func provideOutboxRepository(db *sql.DB) outbox.Repository {
+	return outbox.NewRepository(db)
+}
+
+// provideStockRepository listens to stock changes on primary, as notifications are not replicated
+func provideStockRepository(c *config.Config, db *sql.DB) stock.Repository {
+	return stock.NewRepository(db, repository.DSN(c, c.DatabaseHost))
+}
+
+func provideIdempotencyRepository(db *sql.DB) idempotency.Repository {
+	return idempotency.NewRepository(db)
+}
+
+func provideImportsRepository(
+	db *sql.DB, outbox2 outbox.Repository, stock2 stock.Repository,
+
+) imports.Repository {
+	return imports.NewRepository(db, outbox2, stock2)
 }
 
-func provideProductsRepository(db *sql.DB) products.Repository {
-	return products.NewRepository(db)
+// provideExportsRepository reads exports from replica, if configured
+func provideExportsRepository(db *sql.DB, replica repository.Replica) exports.Repository {
+	return exports.NewRepository(db, replica.DB)
 }
 
-func provideReservationsRepository(db *sql.DB) reservations.Repository {
-	return reservations.NewRepository(db)
+func provideWebhooksRepository(db *sql.DB) webhooks.Repository {
+	return webhooks.NewRepository(db)
+}
+
+// providePublisher returns nil if events publishing is disabled
+func providePublisher(c *config.Config) (publisher.Publisher, func(), error) {
+	return publisher.New(c.Outbox.Publisher, c.Outbox.File, c.Outbox.WebhookURL)
 }
 
 func provideLogger(c *config.Config) *slog.Logger {
 	return logger.NewLogger(logger.MapLevel(c.LogLevel))
 }
+
+func provideHealthChecker(c *config.Config, db *sql.DB, replica repository.Replica) *health.Checker {
+	checker := health.NewChecker(c.Version)
+
+	checker.AddSerial("pool", repository.PoolCheck(db))
+	checker.Add("database", repository.PingCheck(db))
+	checker.Add("schema", repository.SchemaCheck(db))
+
+	if replica.DB != nil {
+		checker.Add("replica", repository.PingCheck(replica.DB))
+	}
+
+	return checker
+}
+
+func provideWorkers(
+	c *config.Config,
+	log *slog.Logger, outbox2 outbox.Repository,
+
+	eventsPublisher publisher.Publisher, webhooks2 webhooks.Repository,
+
+	webhookInteractor interactors.WebhookInteractor, stock2 stock.Repository,
+
+	stockInteractor interactors.StockInteractor, idempotency2 idempotency.Repository,
+
+) []Worker {
+	background := []Worker{workers.NewStockListener(log, stock2, outbox2, stockInteractor), workers.NewIdempotencyPurger(log, idempotency2, c.IdempotencyTTL)}
+
+	if c.Webhooks.Enabled {
+		eventsPublisher = publisher.Fanout(eventsPublisher, webhookInteractor)
+
+		background = append(background, workers.NewWebhookSender(
+			log, webhooks2, &http.Client{Timeout: c.Webhooks.Timeout}, workers.WebhookSenderOptions{
+				PollInterval: c.Webhooks.PollInterval,
+				BatchSize:    c.Webhooks.BatchSize,
+				MaxAttempts:  c.Webhooks.MaxAttempts,
+				BackoffBase:  c.Webhooks.BackoffBase,
+				BackoffMax:   c.Webhooks.BackoffMax,
+			},
+		))
+	}
+
+	if eventsPublisher != nil {
+		background = append(background, workers.NewOutboxRelay(
+			log, outbox2, eventsPublisher, c.Outbox.PollInterval, c.Outbox.BatchSize,
+		))
+	}
+
+	return background
+}
+
+// provideGRPCServer returns nil if gRPC API is disabled
+func provideGRPCServer(
+	c *config.Config,
+	log *slog.Logger,
+	healthChecker *health.Checker,
+	authenticator middleware.Authenticator,
+	storageInteractor interactors.StorageInteractor,
+	productInteractor interactors.ProductInteractor,
+	reservationInteractor interactors.ReservationInteractor,
+) *grpcserver.Server {
+	if c.GRPC.Address == "" {
+		return nil
+	}
+
+	return grpcserver.New(
+		log, grpcserver.Options{Config: c, Authenticator: authenticator, Checker: healthChecker}, storageInteractor,
+		productInteractor,
+		reservationInteractor,
+	)
+}
+
+// provideAuthenticator returns nil if authentication is disabled
+func provideAuthenticator(
+	c *config.Config,
+	apiKeyInteractor interactors.APIKeyInteractor,
+) (middleware.Authenticator, error) {
+	switch c.AuthMode {
+	case config.AuthNone:
+		return nil, nil
+	case config.AuthAPIKey, "":
+		return apiKeyInteractor, nil
+	case config.AuthJWT:
+		authenticator, err := auth.NewJWTAuthenticator(auth.JWTOptions{
+			KeysFile: c.JWT.KeysFile,
+			Secret:   c.JWT.Secret,
+			Issuer:   c.JWT.Issuer,
+			Audience: c.JWT.Audience,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error create jwt authenticator. %w", err)
+		}
+
+		return authenticator, nil
+	default:
+		return nil, fmt.Errorf("error unknown auth mode %s", c.AuthMode)
+	}
+}
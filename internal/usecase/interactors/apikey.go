@@ -0,0 +1,119 @@
+package interactors
+
+import (
+	"cernunnos/internal/pkg/auth"
+	"cernunnos/internal/pkg/models"
+	apikeysRepo "cernunnos/internal/usecase/repository/apikeys"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type APIKeyInteractor interface {
+	// Creates a key with scopes passed. The key itself is returned only once and is never stored
+	Create(ctx context.Context, params CreateAPIKeyParams) (string, *models.APIKey, error)
+	// List all keys, including revoked ones
+	Keys(ctx context.Context) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+	// Resolves a client by its key. Unknown and revoked keys are rejected with auth.ErrorUnauthorized
+	Authenticate(ctx context.Context, key string) (*auth.Principal, error)
+}
+
+type apiKeyInteractor struct {
+	log               *slog.Logger
+	apiKeysRepository apikeysRepo.Repository
+}
+
+func NewAPIKeyInteractor(
+	log *slog.Logger,
+	apiKeysRepository apikeysRepo.Repository,
+) APIKeyInteractor {
+	return &apiKeyInteractor{
+		log:               log.WithGroup("api_key_interactor"),
+		apiKeysRepository: apiKeysRepository,
+	}
+}
+
+type CreateAPIKeyParams struct {
+	Name   string
+	Scopes []string // read, reserve, release or admin
+}
+
+func (c *apiKeyInteractor) Create(
+	ctx context.Context,
+	params CreateAPIKeyParams,
+) (string, *models.APIKey, error) {
+	params.Name = strings.TrimSpace(params.Name)
+
+	if params.Name == "" || len(params.Scopes) == 0 {
+		return "", nil, fmt.Errorf("error some of required fields are not provided. %w", ErrorFieldRequired)
+	}
+
+	if err := auth.ValidateScopes(params.Scopes); err != nil {
+		return "", nil, fmt.Errorf("error validate scopes. %w", err)
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("error generate api key. %w", err)
+	}
+
+	apiKey, err := c.apiKeysRepository.Create(ctx, apikeysRepo.CreateParams{
+		Name:    params.Name,
+		KeyHash: auth.HashKey(key),
+		Scopes:  params.Scopes,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("error store api key. %w", err)
+	}
+
+	return key, apiKey, nil
+}
+
+func (c *apiKeyInteractor) Keys(ctx context.Context) ([]*models.APIKey, error) {
+	keys, err := c.apiKeysRepository.Keys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch api keys from repository. %w", err)
+	}
+
+	return keys, nil
+}
+
+func (c *apiKeyInteractor) Revoke(ctx context.Context, id string) error {
+	keyId, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("error parse api key id. %w", err)
+	}
+
+	if err = c.apiKeysRepository.Revoke(ctx, keyId); err != nil {
+		return fmt.Errorf("error revoke api key. %w", err)
+	}
+
+	return nil
+}
+
+func (c *apiKeyInteractor) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	if key == "" {
+		return nil, fmt.Errorf("error api key is not passed. %w", auth.ErrorUnauthorized)
+	}
+
+	apiKey, err := c.apiKeysRepository.KeyByHash(ctx, auth.HashKey(key))
+	if errors.Is(err, apikeysRepo.ErrorKeyNotFound) {
+		return nil, fmt.Errorf("error unknown api key. %w", auth.ErrorUnauthorized)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error fetch api key. %w", err)
+	}
+
+	return &auth.Principal{
+		Id:          apiKey.Id.String(),
+		Name:        apiKey.Name,
+		Scopes:      apiKey.Scopes,
+		AllStorages: true,
+	}, nil
+}
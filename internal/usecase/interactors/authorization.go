@@ -0,0 +1,59 @@
+package interactors
+
+import (
+	"cernunnos/internal/pkg/auth"
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// authorizeStorage denies operations in storages not permitted to the client. Operations over all
+// storages, with no storage id passed, are denied for clients restricted to some storages.
+// Nothing is checked if authentication is disabled.
+func authorizeStorage(ctx context.Context, storageId uuid.UUID) error {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.AllStorages {
+		return nil
+	}
+
+	if storageId == uuid.Nil {
+		return fmt.Errorf("error storage id is required for client %s. %w", principal.Name, auth.ErrorForbidden)
+	}
+
+	if !principal.StorageAllowed(storageId) {
+		return fmt.Errorf(
+			"error storage %s is not permitted to client %s. %w",
+			storageId.String(),
+			principal.Name,
+			auth.ErrorForbidden,
+		)
+	}
+
+	return nil
+}
+
+// permittedStorages narrows storages filter to storages permitted to the client. If no storages
+// are passed, all permitted storages are returned.
+func permittedStorages(ctx context.Context, storageIds uuid.UUIDs) (uuid.UUIDs, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.AllStorages {
+		return storageIds, nil
+	}
+
+	if len(storageIds) == 0 {
+		if len(principal.Storages) == 0 {
+			return nil, fmt.Errorf("error no storages permitted to client %s. %w", principal.Name, auth.ErrorForbidden)
+		}
+
+		return principal.Storages, nil
+	}
+
+	for _, storageId := range storageIds {
+		if err := authorizeStorage(ctx, storageId); err != nil {
+			return nil, err
+		}
+	}
+
+	return storageIds, nil
+}
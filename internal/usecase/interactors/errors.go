@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	ErrorFieldRequired = errors.New("Field Required")
+	ErrorInvalidField  = errors.New("Invalid Field")
 )
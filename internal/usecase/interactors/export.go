@@ -0,0 +1,77 @@
+package interactors
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	exportsRepo "cernunnos/internal/usecase/repository/exports"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+type ExportInteractor interface {
+	// Passes stock of products in storages to fn as it is read. Rows are a consistent snapshot
+	Stock(ctx context.Context, params ExportStockParams, fn func(product *models.StorageProduct) error) error
+}
+
+type exportInteractor struct {
+	log               *slog.Logger
+	exportsRepository exportsRepo.Repository
+}
+
+func NewExportInteractor(
+	log *slog.Logger,
+	exportsRepository exportsRepo.Repository,
+) ExportInteractor {
+	return &tracedExportInteractor{
+		next: &exportInteractor{
+			log:               log.WithGroup("export_interactor"),
+			exportsRepository: exportsRepository,
+		},
+	}
+}
+
+type ExportStockParams struct {
+	StorageIds []string // Any storage if empty
+	ProductIds []string // Any product if empty
+}
+
+func (i *exportInteractor) Stock(
+	ctx context.Context,
+	params ExportStockParams,
+	fn func(product *models.StorageProduct) error,
+) error {
+	storageIds, err := dto.MapIdsToUUIDs(params.StorageIds)
+	if err != nil {
+		return fmt.Errorf("error map storage ids to uuids. %w", errors.Join(err, ErrorInvalidField))
+	}
+
+	productIds, err := dto.MapIdsToUUIDs(params.ProductIds)
+	if err != nil {
+		return fmt.Errorf("error map product ids to uuids. %w", errors.Join(err, ErrorInvalidField))
+	}
+
+	// Clients restricted to some storages export stock of the permitted ones
+	if storageIds, err = permittedStorages(ctx, storageIds); err != nil {
+		return fmt.Errorf("error authorize storages. %w", err)
+	}
+
+	var rows int64
+
+	err = i.exportsRepository.Stock(ctx, exportsRepo.StockFilter{
+		StorageIds: storageIds,
+		ProductIds: productIds,
+	}, func(product *models.StorageProduct) error {
+		rows++
+
+		return fn(product)
+	})
+	if err != nil {
+		return fmt.Errorf("error export stock. %w", err)
+	}
+
+	i.log.InfoContext(ctx, "stock exported", slog.Int64("rows", rows))
+
+	return nil
+}
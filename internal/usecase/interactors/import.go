@@ -0,0 +1,543 @@
+package interactors
+
+import (
+	"bufio"
+	"bytes"
+	"cernunnos/internal/pkg/models"
+	importsRepo "cernunnos/internal/usecase/repository/imports"
+	"cmp"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Import kinds
+const (
+	ImportProducts = "products"
+	ImportStorages = "storages"
+	ImportStock    = "stock"
+)
+
+// Import file formats
+const (
+	ImportCSV   = "csv"
+	ImportJSONL = "jsonl"
+)
+
+const (
+	// Rows upserted with a single statement
+	importBatchSize = 500
+	// Rejected rows reported with their errors. The rest are only counted
+	maxImportErrors = 100
+	// Max length of a JSONL line
+	maxImportLineSize = 1 << 20
+	// Max length of product and storage names
+	maxNameLength = 300
+)
+
+// importColumn is a field of imported rows. CSV header names columns, JSONL rows are objects with
+// the same keys.
+type importColumn struct {
+	name     string
+	required bool
+}
+
+var importColumns = map[string][]importColumn{
+	// Products are created with generated ids, if id is empty
+	ImportProducts: {{name: "id"}, {name: "name", required: true}, {name: "size"}},
+	// Storages are created with generated ids, if id is empty
+	ImportStorages: {{name: "id"}, {name: "name", required: true}, {name: "available", required: true}},
+	// Amount is a total amount of a product in a storage, reserved products included
+	ImportStock: {
+		{name: "storage_id", required: true},
+		{name: "product_id", required: true},
+		{name: "amount", required: true},
+	},
+}
+
+// errorMalformedRow is returned by import readers for rows which can not be parsed. The following
+// rows may still be read.
+var errorMalformedRow = errors.New("malformed row")
+
+type ImportInteractor interface {
+	// Loads rows of a file in a transaction. Every row is validated. If any row is rejected or dry
+	// run is requested, loaded rows are rolled back, so the result reports what would be done.
+	Import(ctx context.Context, params ImportParams) (*models.ImportResult, error)
+}
+
+type importInteractor struct {
+	log               *slog.Logger
+	importsRepository importsRepo.Repository
+}
+
+func NewImportInteractor(
+	log *slog.Logger,
+	importsRepository importsRepo.Repository,
+) ImportInteractor {
+	return &tracedImportInteractor{
+		next: &importInteractor{
+			log:               log.WithGroup("import_interactor"),
+			importsRepository: importsRepository,
+		},
+	}
+}
+
+type ImportParams struct {
+	Kind   string // products, storages or stock
+	Format string // csv or jsonl
+	DryRun bool
+	Source io.Reader
+}
+
+func (i *importInteractor) Import(ctx context.Context, params ImportParams) (*models.ImportResult, error) {
+	columns, ok := importColumns[params.Kind]
+	if !ok {
+		return nil, fmt.Errorf("error unknown import kind %s. %w", params.Kind, ErrorInvalidField)
+	}
+
+	var (
+		reader importReader
+		err    error
+	)
+
+	switch params.Format {
+	case ImportCSV:
+		reader, err = newCSVImportReader(params.Source, columns)
+	case ImportJSONL:
+		reader = newJSONLImportReader(params.Source, columns)
+	default:
+		return nil, fmt.Errorf("error unknown import format %s. %w", params.Format, ErrorInvalidField)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error read import header. %w", err)
+	}
+
+	result := &models.ImportResult{Kind: params.Kind, DryRun: params.DryRun}
+
+	err = i.importsRepository.Load(ctx, func(ctx context.Context) error {
+		batch := newImportBatch(params.Kind, i.importsRepository, result)
+
+		for {
+			line, record, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			if errors.Is(err, errorMalformedRow) {
+				result.Rows++
+				batch.reject(line, err)
+
+				continue
+			}
+
+			if err != nil {
+				return fmt.Errorf("error read import row. %w", err)
+			}
+
+			result.Rows++
+
+			if err = batch.add(ctx, line, record, columns); err != nil {
+				return fmt.Errorf("error load import rows. %w", err)
+			}
+		}
+
+		if err := batch.flush(ctx); err != nil {
+			return fmt.Errorf("error load import rows. %w", err)
+		}
+
+		if result.Rejected > 0 || params.DryRun {
+			return importsRepo.ErrorRollback
+		}
+
+		result.Committed = true
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error import %s. %w", params.Kind, err)
+	}
+
+	// Stock rows rejected by database are reported after the rows of their batch rejected on parsing
+	slices.SortFunc(result.Errors, func(a, b *models.ImportRowError) int {
+		return cmp.Compare(a.Line, b.Line)
+	})
+
+	i.log.InfoContext(
+		ctx,
+		"import finished",
+		slog.String("kind", result.Kind),
+		slog.Int64("rows", result.Rows),
+		slog.Int64("rejected", result.Rejected),
+		slog.Bool("dry_run", result.DryRun),
+		slog.Bool("committed", result.Committed),
+	)
+
+	return result, nil
+}
+
+// importBatch validates rows and upserts them by batches of importBatchSize
+type importBatch struct {
+	kind       string
+	repository importsRepo.Repository
+	result     *models.ImportResult
+	seen       map[string]int64 // Line of every loaded row by its key, to reject duplicates
+
+	lines    []int64 // Lines of batched rows
+	products []*models.ProductInfo
+	storages []*models.Storage
+	stock    []*importsRepo.StockLine
+}
+
+func newImportBatch(kind string, repository importsRepo.Repository, result *models.ImportResult) *importBatch {
+	return &importBatch{
+		kind:       kind,
+		repository: repository,
+		result:     result,
+		seen:       make(map[string]int64),
+	}
+}
+
+func (b *importBatch) add(ctx context.Context, line int64, record map[string]string, columns []importColumn) error {
+	for _, column := range columns {
+		if column.required && record[column.name] == "" {
+			b.reject(line, fmt.Errorf("%s is required", column.name))
+
+			return nil
+		}
+	}
+
+	row, key, err := parseImportRow(b.kind, record)
+	if err != nil {
+		b.reject(line, err)
+
+		return nil
+	}
+
+	if seenAt, ok := b.seen[key]; ok {
+		b.reject(line, fmt.Errorf("duplicates line %d", seenAt))
+
+		return nil
+	}
+
+	b.seen[key] = line
+	b.lines = append(b.lines, line)
+
+	switch row := row.(type) {
+	case *models.ProductInfo:
+		b.products = append(b.products, row)
+	case *models.Storage:
+		b.storages = append(b.storages, row)
+	case *importsRepo.StockLine:
+		b.stock = append(b.stock, row)
+	}
+
+	if len(b.lines) < importBatchSize {
+		return nil
+	}
+
+	return b.flush(ctx)
+}
+
+func (b *importBatch) flush(ctx context.Context) error {
+	if len(b.lines) == 0 {
+		return nil
+	}
+
+	switch b.kind {
+	case ImportProducts:
+		if err := b.repository.UpsertProducts(ctx, b.products); err != nil {
+			return fmt.Errorf("error upsert products. %w", err)
+		}
+	case ImportStorages:
+		if err := b.repository.UpsertStorages(ctx, b.storages); err != nil {
+			return fmt.Errorf("error upsert storages. %w", err)
+		}
+	default:
+		rejected, err := b.repository.UpsertStock(ctx, b.stock)
+		if err != nil {
+			return fmt.Errorf("error upsert stock. %w", err)
+		}
+
+		indexes := make([]int, 0, len(rejected))
+		for index := range rejected {
+			indexes = append(indexes, index)
+		}
+
+		slices.Sort(indexes)
+
+		for _, index := range indexes {
+			b.reject(b.lines[index], rejected[index])
+		}
+	}
+
+	b.lines, b.products, b.storages, b.stock = b.lines[:0], b.products[:0], b.storages[:0], b.stock[:0]
+
+	return nil
+}
+
+func (b *importBatch) reject(line int64, err error) {
+	b.result.Rejected++
+
+	if len(b.result.Errors) < maxImportErrors {
+		b.result.Errors = append(b.result.Errors, &models.ImportRowError{Line: line, Error: err.Error()})
+	}
+}
+
+// parseImportRow parses a row of a kind and returns it with a key identifying the loaded entity
+func parseImportRow(kind string, record map[string]string) (any, string, error) {
+	switch kind {
+	case ImportProducts:
+		id, err := parseImportId(record, "id")
+		if err != nil {
+			return nil, "", err
+		}
+
+		name, err := parseImportName(record)
+		if err != nil {
+			return nil, "", err
+		}
+
+		size, err := parseImportAmount(record, "size", math.MaxInt32)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return &models.ProductInfo{Id: id, Name: name, Size: size}, id.String(), nil
+	case ImportStorages:
+		id, err := parseImportId(record, "id")
+		if err != nil {
+			return nil, "", err
+		}
+
+		name, err := parseImportName(record)
+		if err != nil {
+			return nil, "", err
+		}
+
+		available, err := parseImportAmount(record, "available", math.MaxInt64)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return &models.Storage{Id: id, Name: name, Available: available}, id.String(), nil
+	default:
+		storageId, err := parseImportId(record, "storage_id")
+		if err != nil {
+			return nil, "", err
+		}
+
+		productId, err := parseImportId(record, "product_id")
+		if err != nil {
+			return nil, "", err
+		}
+
+		amount, err := parseImportAmount(record, "amount", math.MaxInt64)
+		if err != nil {
+			return nil, "", err
+		}
+
+		line := &importsRepo.StockLine{StorageId: storageId, ProductId: productId, Amount: amount}
+
+		return line, storageId.String() + "/" + productId.String(), nil
+	}
+}
+
+// parseImportId parses an id field. Empty id field gets a new id
+func parseImportId(record map[string]string, field string) (uuid.UUID, error) {
+	if record[field] == "" {
+		return uuid.New(), nil
+	}
+
+	id, err := uuid.Parse(record[field])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%s must be uuid", field)
+	}
+
+	return id, nil
+}
+
+func parseImportName(record map[string]string) (string, error) {
+	name := strings.TrimSpace(record["name"])
+	if name == "" {
+		return "", errors.New("name is required")
+	}
+
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return "", fmt.Errorf("name exceeds %d characters", maxNameLength)
+	}
+
+	return name, nil
+}
+
+// parseImportAmount parses a non negative integer field up to max. Empty field is zero
+func parseImportAmount(record map[string]string, field string, max int64) (int64, error) {
+	if record[field] == "" {
+		return 0, nil
+	}
+
+	amount, err := strconv.ParseInt(record[field], 10, 64)
+	if err != nil || amount < 0 || amount > max {
+		return 0, fmt.Errorf("%s must be an integer from 0 to %d", field, max)
+	}
+
+	return amount, nil
+}
+
+// importReader reads rows of an import file as fields by column names
+type importReader interface {
+	// Returns a row with the line it starts at. Returns io.EOF after the last row
+	Read() (int64, map[string]string, error)
+}
+
+type csvImportReader struct {
+	reader *csv.Reader
+	header []string
+}
+
+// newCSVImportReader reads the header. Header must name required columns and may not name unknown
+// ones.
+func newCSVImportReader(source io.Reader, columns []importColumn) (*csvImportReader, error) {
+	reader := csv.NewReader(source)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error empty file. %w", ErrorFieldRequired)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error parse header. %w", errors.Join(err, ErrorInvalidField))
+	}
+
+	header = slices.Clone(header)
+
+	// Spreadsheet editors may prepend byte order mark
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	for i, name := range header {
+		header[i] = strings.TrimSpace(name)
+
+		if !slices.ContainsFunc(columns, func(column importColumn) bool { return column.name == header[i] }) {
+			return nil, fmt.Errorf("error unknown column %s. %w", header[i], ErrorInvalidField)
+		}
+	}
+
+	for _, column := range columns {
+		if column.required && !slices.Contains(header, column.name) {
+			return nil, fmt.Errorf("error column %s is missing. %w", column.name, ErrorFieldRequired)
+		}
+	}
+
+	return &csvImportReader{reader: reader, header: header}, nil
+}
+
+func (r *csvImportReader) Read() (int64, map[string]string, error) {
+	fields, err := r.reader.Read()
+
+	var parseErr *csv.ParseError
+
+	if errors.As(err, &parseErr) {
+		return int64(parseErr.StartLine), nil, fmt.Errorf("%w. %s", errorMalformedRow, parseErr.Err)
+	}
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	record := make(map[string]string, len(fields))
+
+	for i, field := range fields {
+		record[r.header[i]] = strings.TrimSpace(field)
+	}
+
+	return int64(line), record, nil
+}
+
+type jsonlImportReader struct {
+	scanner *bufio.Scanner
+	columns []importColumn
+	line    int64
+}
+
+func newJSONLImportReader(source io.Reader, columns []importColumn) *jsonlImportReader {
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(nil, maxImportLineSize)
+
+	return &jsonlImportReader{scanner: scanner, columns: columns}
+}
+
+// Read parses a line as an object of strings and numbers. Empty lines are skipped
+func (r *jsonlImportReader) Read() (int64, map[string]string, error) {
+	for r.scanner.Scan() {
+		r.line++
+
+		raw := bytes.TrimSpace(r.scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		record, err := r.parse(raw)
+		if err != nil {
+			return r.line, nil, fmt.Errorf("%w. %w", errorMalformedRow, err)
+		}
+
+		return r.line, record, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return 0, nil, fmt.Errorf(
+				"error line %d exceeds %d bytes. %w", r.line+1, maxImportLineSize, ErrorInvalidField,
+			)
+		}
+
+		return 0, nil, err
+	}
+
+	return 0, nil, io.EOF
+}
+
+func (r *jsonlImportReader) parse(raw []byte) (map[string]string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var object map[string]any
+
+	if err := decoder.Decode(&object); err != nil || decoder.More() || object == nil {
+		return nil, errors.New("line must be a json object")
+	}
+
+	record := make(map[string]string, len(object))
+
+	for key, value := range object {
+		if !slices.ContainsFunc(r.columns, func(column importColumn) bool { return column.name == key }) {
+			return nil, fmt.Errorf("unknown field %s", key)
+		}
+
+		switch value := value.(type) {
+		case string:
+			record[key] = strings.TrimSpace(value)
+		case json.Number:
+			record[key] = value.String()
+		case nil:
+		default:
+			return nil, fmt.Errorf("%s must be a string or a number", key)
+		}
+	}
+
+	return record, nil
+}
@@ -3,17 +3,25 @@ package interactors
 import (
 	"cernunnos/internal/pkg/dto"
 	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
 	productsRepo "cernunnos/internal/usecase/repository/products"
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type ProductInteractor interface {
-	Products(ctx context.Context, params ProductsParams) ([]*models.ProductInfo, error)
-	StorageProducts(ctx context.Context, params StorageProductsParams) ([]*models.StorageProduct, error)
+	Products(ctx context.Context, params ProductsParams) (*models.Page[*models.ProductInfo], error)
+	StorageProducts(
+		ctx context.Context,
+		params StorageProductsParams,
+	) (*models.Page[*models.StorageProduct], error)
+	// Search products by name. Tolerates partial and misspelled names
+	SearchProducts(ctx context.Context, params SearchProductsParams) (*models.Page[*models.ProductInfo], error)
 }
 
 type productInteractor struct {
@@ -25,26 +33,104 @@ func NewProductInteractor(
 	log *slog.Logger,
 	productsRepository productsRepo.Repository,
 ) ProductInteractor {
-	return &productInteractor{
-		log:                log.WithGroup("product_interactor"),
-		productsRepository: productsRepository,
+	return &tracedProductInteractor{
+		next: &productInteractor{
+			log:                log.WithGroup("product_interactor"),
+			productsRepository: productsRepository,
+		},
 	}
 }
 
+// ProductsFilter narrows and orders products listings
+type ProductsFilter struct {
+	Sort         string // name, size, created_at, updated_at or available. "-" prefix for descending order
+	NamePrefix   string
+	NameContains string
+	SizeMin      *int64
+	SizeMax      *int64
+	AvailableGt  *int64 // Total available amount or available amount in a storage, if passed
+	UpdatedSince *time.Time
+}
+
+func (f ProductsFilter) conditions() []sqltools.Condition {
+	conditions := make([]sqltools.Condition, 0)
+
+	if f.NamePrefix != "" {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "name", Operator: sqltools.OperatorPrefix, Value: f.NamePrefix,
+		})
+	}
+
+	if f.NameContains != "" {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "name", Operator: sqltools.OperatorContains, Value: f.NameContains,
+		})
+	}
+
+	if f.SizeMin != nil {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "size", Operator: sqltools.OperatorGte, Value: *f.SizeMin,
+		})
+	}
+
+	if f.SizeMax != nil {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "size", Operator: sqltools.OperatorLte, Value: *f.SizeMax,
+		})
+	}
+
+	if f.AvailableGt != nil {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "available", Operator: sqltools.OperatorGt, Value: *f.AvailableGt,
+		})
+	}
+
+	if f.UpdatedSince != nil {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "updated_at", Operator: sqltools.OperatorGte, Value: *f.UpdatedSince,
+		})
+	}
+
+	return conditions
+}
+
 type ProductsParams struct {
+	ProductsFilter
+
 	Ids             []string
 	StorageId       string
+	AsOf            *time.Time // Amounts at the point in time instead of current ones, if passed
 	WithUnavailable bool
 	Limit           uint32
 	Offset          uint32
+	Cursor          string // Opaque cursor returned with a previous page. Overrides Offset
+	WithTotal       bool
 }
 
 func (c *productInteractor) Products(
 	ctx context.Context,
 	params ProductsParams,
-) ([]*models.ProductInfo, error) {
+) (*models.Page[*models.ProductInfo], error) {
 	var err error
 
+	cursor, err := sqltools.ParseCursor(params.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("error parse cursor. %w", err)
+	}
+
+	sort, err := sqltools.ParseSort(params.Sort)
+	if err != nil {
+		return nil, fmt.Errorf("error parse sort. %w", err)
+	}
+
+	pagination := sqltools.Pagination{
+		Cursor:    cursor,
+		Sort:      sort,
+		Limit:     uint64(params.Limit),
+		Offset:    uint64(params.Offset),
+		WithTotal: params.WithTotal,
+	}
+
 	ids := make(uuid.UUIDs, 0, len(params.Ids))
 	if len(params.Ids) > 0 {
 		ids, err = dto.MapIdsToUUIDs(params.Ids)
@@ -59,32 +145,47 @@ func (c *productInteractor) Products(
 			return nil, fmt.Errorf("error parse storage id. %w", err)
 		}
 
+		if err = authorizeStorage(ctx, storageUUID); err != nil {
+			return nil, fmt.Errorf("error authorize storage. %w", err)
+		}
+
 		storageProducts, err := c.productsRepository.StorageProducts(
 			ctx,
 			productsRepo.StorageProductsParams{
 				Ids:             ids,
 				StorageId:       storageUUID,
+				AsOf:            params.AsOf,
 				WithUnavailable: params.WithUnavailable,
-				Limit:           uint64(params.Limit),
-				Offset:          uint64(params.Offset),
+				Conditions:      params.conditions(),
+				Pagination:      pagination,
 			},
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error fetch storage products. %w", err)
 		}
 
-		infos, err := models.MapStorageProductsToProductInfos(storageProducts)
+		infos, err := models.MapStorageProductsToProductInfos(storageProducts.Items)
 		if err != nil {
 			return nil, fmt.Errorf("error map storage products to product infos. %w", err)
 		}
 
-		return infos, nil
+		return &models.Page[*models.ProductInfo]{
+			Items:      infos,
+			NextCursor: storageProducts.NextCursor,
+			NextOffset: storageProducts.NextOffset,
+			Total:      storageProducts.Total,
+		}, nil
+	}
+
+	if err = authorizeStorage(ctx, uuid.Nil); err != nil {
+		return nil, fmt.Errorf("error authorize storage. %w", err)
 	}
 
 	products, err := c.productsRepository.Products(ctx, productsRepo.ProductsParams{
-		Ids:    ids,
-		Limit:  params.Limit,
-		Offset: params.Offset,
+		Ids:        ids,
+		AsOf:       params.AsOf,
+		Conditions: params.conditions(),
+		Pagination: pagination,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error fetch products. %w", err)
@@ -94,19 +195,34 @@ func (c *productInteractor) Products(
 }
 
 type StorageProductsParams struct {
+	ProductsFilter
+
 	Ids             []string
 	StorageId       string
+	AsOf            *time.Time // Amounts at the point in time instead of current ones, if passed
 	WithUnavailable bool
 	Limit           uint64
 	Offset          uint64
+	Cursor          string // Opaque cursor returned with a previous page. Overrides Offset
+	WithTotal       bool
 }
 
 func (c *productInteractor) StorageProducts(
 	ctx context.Context,
 	params StorageProductsParams,
-) ([]*models.StorageProduct, error) {
+) (*models.Page[*models.StorageProduct], error) {
 	var err error
 
+	cursor, err := sqltools.ParseCursor(params.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("error parse cursor. %w", err)
+	}
+
+	sort, err := sqltools.ParseSort(params.Sort)
+	if err != nil {
+		return nil, fmt.Errorf("error parse sort. %w", err)
+	}
+
 	ids := make(uuid.UUIDs, 0, len(params.Ids))
 	if len(params.Ids) > 0 {
 		ids, err = dto.MapIdsToUUIDs(params.Ids)
@@ -120,12 +236,25 @@ func (c *productInteractor) StorageProducts(
 		return nil, fmt.Errorf("error parse storage id. %w", err)
 	}
 
+	if err = authorizeStorage(ctx, storageUUID); err != nil {
+		return nil, fmt.Errorf("error authorize storage. %w", err)
+	}
+
 	storageProducts, err := c.productsRepository.StorageProducts(
 		ctx,
 		productsRepo.StorageProductsParams{
 			Ids:             ids,
 			WithUnavailable: params.WithUnavailable,
 			StorageId:       storageUUID,
+			AsOf:            params.AsOf,
+			Conditions:      params.conditions(),
+			Pagination: sqltools.Pagination{
+				Cursor:    cursor,
+				Sort:      sort,
+				Limit:     params.Limit,
+				Offset:    params.Offset,
+				WithTotal: params.WithTotal,
+			},
 		},
 	)
 	if err != nil {
@@ -134,3 +263,56 @@ func (c *productInteractor) StorageProducts(
 
 	return storageProducts, nil
 }
+
+type SearchProductsParams struct {
+	Query     string
+	StorageId string // If passed, only products available in the storage will be found
+	Limit     uint64
+	Offset    uint64
+	Cursor    string // Opaque cursor returned with a previous page. Overrides Offset
+	WithTotal bool
+}
+
+func (c *productInteractor) SearchProducts(
+	ctx context.Context,
+	params SearchProductsParams,
+) (*models.Page[*models.ProductInfo], error) {
+	query := strings.TrimSpace(params.Query)
+	if query == "" {
+		return nil, fmt.Errorf("error search query is not provided. %w", ErrorFieldRequired)
+	}
+
+	cursor, err := sqltools.ParseCursor(params.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("error parse cursor. %w", err)
+	}
+
+	var storageUUID uuid.UUID
+
+	if params.StorageId != "" {
+		storageUUID, err = uuid.Parse(params.StorageId)
+		if err != nil {
+			return nil, fmt.Errorf("error parse storage id. %w", err)
+		}
+	}
+
+	if err = authorizeStorage(ctx, storageUUID); err != nil {
+		return nil, fmt.Errorf("error authorize storage. %w", err)
+	}
+
+	products, err := c.productsRepository.SearchProducts(ctx, productsRepo.SearchProductsParams{
+		Query:     query,
+		StorageId: storageUUID,
+		Pagination: sqltools.Pagination{
+			Cursor:    cursor,
+			Limit:     params.Limit,
+			Offset:    params.Offset,
+			WithTotal: params.WithTotal,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error search products. %w", err)
+	}
+
+	return products, nil
+}
@@ -2,6 +2,7 @@ package interactors
 
 import (
 	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
 	reservationsRepo "cernunnos/internal/usecase/repository/reservations"
 	"context"
 	"fmt"
@@ -12,7 +13,7 @@ import (
 
 type ReservationInteractor interface {
 	// List product reservations
-	Reservations(ctx context.Context, params ReservationsParams) ([]*models.Reservation, error)
+	Reservations(ctx context.Context, params ReservationsParams) (*models.Page[*models.Reservation], error)
 	// Reserves a product. If StorageId is passed, then reservation will be performed in a
 	// storage specified WITHOUT reservation distributing
 	Reserve(ctx context.Context, params ReserveParams) error
@@ -28,9 +29,11 @@ func NewReservationInteractor(
 	log *slog.Logger,
 	reservationsRepository reservationsRepo.Repository,
 ) ReservationInteractor {
-	return &reservationInteractor{
-		log:                    log.WithGroup("reservation_interactor"),
-		reservationsRepository: reservationsRepository,
+	return &tracedReservationInteractor{
+		next: &reservationInteractor{
+			log:                    log.WithGroup("reservation_interactor"),
+			reservationsRepository: reservationsRepository,
+		},
 	}
 }
 
@@ -45,12 +48,14 @@ type ReservationsParams struct {
 	ShippingId string
 	Limit      uint64
 	Offset     uint64
+	Cursor     string // Opaque cursor returned with a previous page. Overrides Offset
+	WithTotal  bool
 }
 
 func (c *reservationInteractor) Reservations(
 	ctx context.Context,
 	params ReservationsParams,
-) ([]*models.Reservation, error) {
+) (*models.Page[*models.Reservation], error) {
 	var ids *reservationsIds = new(reservationsIds)
 
 	var err error
@@ -67,11 +72,24 @@ func (c *reservationInteractor) Reservations(
 		}
 	}
 
+	if err = authorizeStorage(ctx, ids.storageId); err != nil {
+		return nil, fmt.Errorf("error authorize storage. %w", err)
+	}
+
+	cursor, err := sqltools.ParseCursor(params.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("error parse cursor. %w", err)
+	}
+
 	reservationsParams := reservationsRepo.ReservationsParams{
 		StorageId:  ids.storageId,
 		ShippingId: ids.shippingId,
-		Limit:      params.Limit,
-		Offset:     params.Offset,
+		Pagination: sqltools.Pagination{
+			Cursor:    cursor,
+			Limit:     params.Limit,
+			Offset:    params.Offset,
+			WithTotal: params.WithTotal,
+		},
 	}
 
 	if len(ids.productIds) > 0 {
@@ -104,6 +122,10 @@ func (c *reservationInteractor) Reserve(ctx context.Context, params ReserveParam
 		return fmt.Errorf("error parse ids. %w", err)
 	}
 
+	if err = authorizeStorage(ctx, ids.storageId); err != nil {
+		return fmt.Errorf("error authorize storage. %w", err)
+	}
+
 	err = c.reservationsRepository.Reserve(ctx, reservationsRepo.ReserveParams{
 		ProductIds: ids.productIds,
 		StorageId:  ids.storageId,
@@ -133,6 +155,10 @@ func (c *reservationInteractor) Cancel(ctx context.Context, params CancelParams)
 		return fmt.Errorf("error parse ids. %w", err)
 	}
 
+	if err = authorizeStorage(ctx, ids.storageId); err != nil {
+		return fmt.Errorf("error authorize storage. %w", err)
+	}
+
 	err = c.reservationsRepository.Cancel(ctx, reservationsRepo.CancelParams{
 		ProductIds: ids.productIds,
 		ShippingId: ids.shippingId,
@@ -161,6 +187,10 @@ func (c *reservationInteractor) Release(ctx context.Context, params ReleaseParam
 		return fmt.Errorf("error parse ids. %w", err)
 	}
 
+	if err = authorizeStorage(ctx, ids.storageId); err != nil {
+		return fmt.Errorf("error authorize storage. %w", err)
+	}
+
 	err = c.reservationsRepository.Release(ctx, reservationsRepo.ReleaseParams{
 		ProductIds: ids.productIds,
 		ShippingId: ids.shippingId,
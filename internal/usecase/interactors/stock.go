@@ -0,0 +1,258 @@
+package interactors
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/models"
+	outboxRepo "cernunnos/internal/usecase/repository/outbox"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Changes buffered for a subscriber. Subscribers falling behind further are dropped
+	stockSubscriberBuffer = 256
+	// Changes fetched from outbox at once when resuming a stream
+	stockResumeBatch = 500
+)
+
+type StockInteractor interface {
+	// Streams stock level changes matching params until ctx is done. The channel is closed when ctx
+	// is done or the subscriber falls behind, so the stream should be resumed with LastEventId.
+	Changes(ctx context.Context, params StockChangesParams) (<-chan *models.StockChange, error)
+	// Passes a stock level changed event to subscribers. Stock listener broadcasts notified events here
+	Broadcast(ctx context.Context, event *models.Event) error
+}
+
+type stockInteractor struct {
+	log              *slog.Logger
+	outboxRepository outboxRepo.Repository
+
+	mu          sync.Mutex
+	subscribers map[*stockSubscriber]struct{}
+}
+
+type stockSubscriber struct {
+	productId uuid.UUID
+	storageId uuid.UUID
+	changes   chan *models.StockChange
+}
+
+func (s *stockSubscriber) matches(change *models.StockChange) bool {
+	return (s.productId == uuid.Nil || s.productId == change.ProductId) &&
+		(s.storageId == uuid.Nil || s.storageId == change.StorageId)
+}
+
+func NewStockInteractor(
+	log *slog.Logger,
+	outboxRepository outboxRepo.Repository,
+) StockInteractor {
+	return &tracedStockInteractor{
+		next: &stockInteractor{
+			log:              log.WithGroup("stock_interactor"),
+			outboxRepository: outboxRepository,
+			subscribers:      make(map[*stockSubscriber]struct{}),
+		},
+	}
+}
+
+type StockChangesParams struct {
+	ProductId   string // All products if empty
+	StorageId   string // All storages if empty
+	LastEventId string // Id of the last received change. Changes after it are streamed first
+}
+
+func (c *stockInteractor) Changes(
+	ctx context.Context,
+	params StockChangesParams,
+) (<-chan *models.StockChange, error) {
+	var (
+		subscriber = &stockSubscriber{changes: make(chan *models.StockChange, stockSubscriberBuffer)}
+		after      int64
+		err        error
+	)
+
+	if params.ProductId != "" {
+		if subscriber.productId, err = uuid.Parse(params.ProductId); err != nil {
+			return nil, fmt.Errorf("error parse product id. %w", err)
+		}
+	}
+
+	if params.StorageId != "" {
+		if subscriber.storageId, err = uuid.Parse(params.StorageId); err != nil {
+			return nil, fmt.Errorf("error parse storage id. %w", err)
+		}
+	}
+
+	if err = authorizeStorage(ctx, subscriber.storageId); err != nil {
+		return nil, err
+	}
+
+	if params.LastEventId != "" {
+		if after, err = strconv.ParseInt(params.LastEventId, 10, 64); err != nil || after < 0 {
+			return nil, fmt.Errorf("error invalid last event id %s. %w", params.LastEventId, ErrorInvalidField)
+		}
+	}
+
+	// Subscribe before resuming, so changes committed while resuming are not missed
+	c.subscribe(subscriber)
+
+	out := make(chan *models.StockChange)
+
+	go func() {
+		defer close(out)
+		defer c.unsubscribe(subscriber)
+
+		c.stream(ctx, subscriber, after, out)
+	}()
+
+	return out, nil
+}
+
+// stream passes changes after a position from outbox, if passed, then live changes of subscriber.
+// Outbox positions follow commit order, so no change committed later may have a position below
+// the delivered ones. Changes up to the last delivered position are skipped as repeated.
+func (c *stockInteractor) stream(
+	ctx context.Context,
+	subscriber *stockSubscriber,
+	after int64,
+	out chan<- *models.StockChange,
+) {
+	for after > 0 {
+		events, err := c.outboxRepository.Events(ctx, outboxRepo.EventsParams{
+			Type:      models.EventStockLevelChanged,
+			After:     after,
+			ProductId: subscriber.productId,
+			StorageId: subscriber.storageId,
+			Limit:     stockResumeBatch,
+		})
+		if err != nil {
+			c.log.ErrorContext(ctx, "error resume stock changes", logger.Err(err))
+
+			return
+		}
+
+		for _, event := range events {
+			after = event.Position
+
+			change, err := mapStockChange(event)
+			if err != nil {
+				c.log.ErrorContext(ctx, "error map stock change", logger.Err(err))
+
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- change:
+			}
+		}
+
+		if len(events) < stockResumeBatch {
+			break
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change, ok := <-subscriber.changes:
+			if !ok {
+				return
+			}
+
+			if change.Position <= after {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- change:
+			}
+
+			after = change.Position
+		}
+	}
+}
+
+func (c *stockInteractor) Broadcast(ctx context.Context, event *models.Event) error {
+	change, err := mapStockChange(event)
+	if err != nil {
+		return fmt.Errorf("error map stock change. %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for subscriber := range c.subscribers {
+		if !subscriber.matches(change) {
+			continue
+		}
+
+		select {
+		case subscriber.changes <- change:
+		default:
+			// Closing lets the client resume from the last change it received
+			delete(c.subscribers, subscriber)
+			close(subscriber.changes)
+
+			c.log.WarnContext(ctx, "stock subscriber fell behind and was dropped")
+		}
+	}
+
+	return nil
+}
+
+func (c *stockInteractor) subscribe(subscriber *stockSubscriber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subscribers[subscriber] = struct{}{}
+}
+
+func (c *stockInteractor) unsubscribe(subscriber *stockSubscriber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.subscribers[subscriber]; ok {
+		delete(c.subscribers, subscriber)
+		close(subscriber.changes)
+	}
+}
+
+func mapStockChange(event *models.Event) (*models.StockChange, error) {
+	var level dto.StockLevelEvent
+
+	if err := json.Unmarshal(event.Payload, &level); err != nil {
+		return nil, fmt.Errorf("error unmarshal %s event data. %w", event.Type, err)
+	}
+
+	productId, err := uuid.Parse(level.ProductId)
+	if err != nil {
+		return nil, fmt.Errorf("error parse product id. %w", err)
+	}
+
+	storageId, err := uuid.Parse(level.StorageId)
+	if err != nil {
+		return nil, fmt.Errorf("error parse storage id. %w", err)
+	}
+
+	return &models.StockChange{
+		Position:  event.Position,
+		ProductId: productId,
+		StorageId: storageId,
+		Amount:    level.Amount,
+		Reserved:  level.Reserved,
+		Available: level.Available,
+		ChangedAt: event.CreatedAt,
+	}, nil
+}
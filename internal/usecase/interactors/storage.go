@@ -3,14 +3,16 @@ package interactors
 import (
 	"cernunnos/internal/pkg/dto"
 	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
 	storagesRepo "cernunnos/internal/usecase/repository/storages"
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 )
 
 type StorageInteractor interface {
-	Storages(ctx context.Context, params StoragesParams) ([]*models.Storage, error)
+	Storages(ctx context.Context, params StoragesParams) (*models.Page[*models.Storage], error)
 }
 
 type storageInteractor struct {
@@ -22,28 +24,97 @@ func NewStorageInteractor(
 	log *slog.Logger,
 	storagesRepository storagesRepo.Repository,
 ) StorageInteractor {
-	return &storageInteractor{
-		log:                log.WithGroup("storage_interactor"),
-		storagesRepository: storagesRepository,
+	return &tracedStorageInteractor{
+		next: &storageInteractor{
+			log:                log.WithGroup("storage_interactor"),
+			storagesRepository: storagesRepository,
+		},
 	}
 }
 
+// StoragesFilter narrows and orders storages listings
+type StoragesFilter struct {
+	Sort         string // name, available, reserved, created_at or updated_at. "-" prefix for descending order
+	NamePrefix   string
+	NameContains string
+	AvailableGt  *int64
+	UpdatedSince *time.Time
+}
+
+func (f StoragesFilter) conditions() []sqltools.Condition {
+	conditions := make([]sqltools.Condition, 0)
+
+	if f.NamePrefix != "" {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "name", Operator: sqltools.OperatorPrefix, Value: f.NamePrefix,
+		})
+	}
+
+	if f.NameContains != "" {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "name", Operator: sqltools.OperatorContains, Value: f.NameContains,
+		})
+	}
+
+	if f.AvailableGt != nil {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "available", Operator: sqltools.OperatorGt, Value: *f.AvailableGt,
+		})
+	}
+
+	if f.UpdatedSince != nil {
+		conditions = append(conditions, sqltools.Condition{
+			Field: "updated_at", Operator: sqltools.OperatorGte, Value: *f.UpdatedSince,
+		})
+	}
+
+	return conditions
+}
+
 type StoragesParams struct {
-	Ids    []string
-	Limit  uint32
-	Offset uint32
+	StoragesFilter
+
+	Ids       []string
+	Limit     uint32
+	Offset    uint32
+	Cursor    string // Opaque cursor returned with a previous page. Overrides Offset
+	WithTotal bool
 }
 
-func (c *storageInteractor) Storages(ctx context.Context, params StoragesParams) ([]*models.Storage, error) {
+func (c *storageInteractor) Storages(
+	ctx context.Context,
+	params StoragesParams,
+) (*models.Page[*models.Storage], error) {
 	uuids, err := dto.MapIdsToUUIDs(params.Ids)
 	if err != nil {
 		return nil, fmt.Errorf("error map storage ids to uuids. %w", err)
 	}
 
+	uuids, err = permittedStorages(ctx, uuids)
+	if err != nil {
+		return nil, fmt.Errorf("error authorize storages. %w", err)
+	}
+
+	cursor, err := sqltools.ParseCursor(params.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("error parse cursor. %w", err)
+	}
+
+	sort, err := sqltools.ParseSort(params.Sort)
+	if err != nil {
+		return nil, fmt.Errorf("error parse sort. %w", err)
+	}
+
 	storages, err := c.storagesRepository.Storages(ctx, storagesRepo.StoragesParams{
-		Ids:    uuids,
-		Limit:  uint64(params.Limit),
-		Offset: uint64(params.Offset),
+		Ids:        uuids,
+		Conditions: params.conditions(),
+		Pagination: sqltools.Pagination{
+			Cursor:    cursor,
+			Sort:      sort,
+			Limit:     uint64(params.Limit),
+			Offset:    uint64(params.Offset),
+			WithTotal: params.WithTotal,
+		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error fetch storages from database. %w", err)
@@ -0,0 +1,225 @@
+package interactors
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/tracing"
+	"context"
+)
+
+// Interactors are wrapped with tracing decorators, so every use case call gets its own span
+// between a handler span and repository query spans.
+
+type tracedProductInteractor struct {
+	next ProductInteractor
+}
+
+func (t *tracedProductInteractor) Products(
+	ctx context.Context,
+	params ProductsParams,
+) (*models.Page[*models.ProductInfo], error) {
+	ctx, span := tracing.Start(ctx, "ProductInteractor.Products")
+
+	page, err := t.next.Products(ctx, params)
+	tracing.End(span, err)
+
+	return page, err
+}
+
+func (t *tracedProductInteractor) StorageProducts(
+	ctx context.Context,
+	params StorageProductsParams,
+) (*models.Page[*models.StorageProduct], error) {
+	ctx, span := tracing.Start(ctx, "ProductInteractor.StorageProducts")
+
+	page, err := t.next.StorageProducts(ctx, params)
+	tracing.End(span, err)
+
+	return page, err
+}
+
+func (t *tracedProductInteractor) SearchProducts(
+	ctx context.Context,
+	params SearchProductsParams,
+) (*models.Page[*models.ProductInfo], error) {
+	ctx, span := tracing.Start(ctx, "ProductInteractor.SearchProducts")
+
+	page, err := t.next.SearchProducts(ctx, params)
+	tracing.End(span, err)
+
+	return page, err
+}
+
+type tracedStorageInteractor struct {
+	next StorageInteractor
+}
+
+func (t *tracedStorageInteractor) Storages(
+	ctx context.Context,
+	params StoragesParams,
+) (*models.Page[*models.Storage], error) {
+	ctx, span := tracing.Start(ctx, "StorageInteractor.Storages")
+
+	page, err := t.next.Storages(ctx, params)
+	tracing.End(span, err)
+
+	return page, err
+}
+
+type tracedReservationInteractor struct {
+	next ReservationInteractor
+}
+
+func (t *tracedReservationInteractor) Reservations(
+	ctx context.Context,
+	params ReservationsParams,
+) (*models.Page[*models.Reservation], error) {
+	ctx, span := tracing.Start(ctx, "ReservationInteractor.Reservations")
+
+	page, err := t.next.Reservations(ctx, params)
+	tracing.End(span, err)
+
+	return page, err
+}
+
+func (t *tracedReservationInteractor) Reserve(ctx context.Context, params ReserveParams) error {
+	ctx, span := tracing.Start(ctx, "ReservationInteractor.Reserve")
+
+	err := t.next.Reserve(ctx, params)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (t *tracedReservationInteractor) Cancel(ctx context.Context, params CancelParams) error {
+	ctx, span := tracing.Start(ctx, "ReservationInteractor.Cancel")
+
+	err := t.next.Cancel(ctx, params)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (t *tracedReservationInteractor) Release(ctx context.Context, params ReleaseParams) error {
+	ctx, span := tracing.Start(ctx, "ReservationInteractor.Release")
+
+	err := t.next.Release(ctx, params)
+	tracing.End(span, err)
+
+	return err
+}
+
+type tracedWebhookInteractor struct {
+	next WebhookInteractor
+}
+
+func (t *tracedWebhookInteractor) Create(
+	ctx context.Context,
+	params CreateWebhookParams,
+) (*models.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "WebhookInteractor.Create")
+
+	subscription, err := t.next.Create(ctx, params)
+	tracing.End(span, err)
+
+	return subscription, err
+}
+
+func (t *tracedWebhookInteractor) Webhooks(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	ctx, span := tracing.Start(ctx, "WebhookInteractor.Webhooks")
+
+	subscriptions, err := t.next.Webhooks(ctx)
+	tracing.End(span, err)
+
+	return subscriptions, err
+}
+
+func (t *tracedWebhookInteractor) Delete(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "WebhookInteractor.Delete")
+
+	err := t.next.Delete(ctx, id)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (t *tracedWebhookInteractor) Deliveries(
+	ctx context.Context,
+	params WebhookDeliveriesParams,
+) (*models.Page[*models.WebhookDelivery], error) {
+	ctx, span := tracing.Start(ctx, "WebhookInteractor.Deliveries")
+
+	page, err := t.next.Deliveries(ctx, params)
+	tracing.End(span, err)
+
+	return page, err
+}
+
+func (t *tracedWebhookInteractor) Replay(ctx context.Context, webhookId string, deliveryId string) error {
+	ctx, span := tracing.Start(ctx, "WebhookInteractor.Replay")
+
+	err := t.next.Replay(ctx, webhookId, deliveryId)
+	tracing.End(span, err)
+
+	return err
+}
+
+func (t *tracedWebhookInteractor) Publish(ctx context.Context, event *models.Event) error {
+	ctx, span := tracing.Start(ctx, "WebhookInteractor.Publish")
+
+	err := t.next.Publish(ctx, event)
+	tracing.End(span, err)
+
+	return err
+}
+
+type tracedStockInteractor struct {
+	next StockInteractor
+}
+
+func (t *tracedStockInteractor) Changes(
+	ctx context.Context,
+	params StockChangesParams,
+) (<-chan *models.StockChange, error) {
+	// The span covers subscription only, the stream outlives it
+	ctx, span := tracing.Start(ctx, "StockInteractor.Changes")
+
+	changes, err := t.next.Changes(ctx, params)
+	tracing.End(span, err)
+
+	return changes, err
+}
+
+// Broadcast is not traced, as it runs for every stock change
+func (t *tracedStockInteractor) Broadcast(ctx context.Context, event *models.Event) error {
+	return t.next.Broadcast(ctx, event)
+}
+
+type tracedImportInteractor struct {
+	next ImportInteractor
+}
+
+func (t *tracedImportInteractor) Import(ctx context.Context, params ImportParams) (*models.ImportResult, error) {
+	ctx, span := tracing.Start(ctx, "ImportInteractor.Import")
+
+	result, err := t.next.Import(ctx, params)
+	tracing.End(span, err)
+
+	return result, err
+}
+
+type tracedExportInteractor struct {
+	next ExportInteractor
+}
+
+func (t *tracedExportInteractor) Stock(
+	ctx context.Context,
+	params ExportStockParams,
+	fn func(product *models.StorageProduct) error,
+) error {
+	ctx, span := tracing.Start(ctx, "ExportInteractor.Stock")
+
+	err := t.next.Stock(ctx, params, fn)
+	tracing.End(span, err)
+
+	return err
+}
@@ -0,0 +1,256 @@
+package interactors
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
+	webhooksRepo "cernunnos/internal/usecase/repository/webhooks"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Event types webhooks can subscribe to
+var webhookEventTypes = []string{
+	models.EventReservationCreated,
+	models.EventReservationCancelled,
+	models.EventReservationReleased,
+	models.EventStockLevelChanged,
+	models.EventStockDepleted,
+}
+
+type WebhookInteractor interface {
+	// Subscribes a URL to events. Secret is generated, if not passed
+	Create(ctx context.Context, params CreateWebhookParams) (*models.WebhookSubscription, error)
+	Webhooks(ctx context.Context) ([]*models.WebhookSubscription, error)
+	Delete(ctx context.Context, id string) error
+	// List deliveries of a subscription with their latest attempt outcome
+	Deliveries(ctx context.Context, params WebhookDeliveriesParams) (*models.Page[*models.WebhookDelivery], error)
+	// Delivers an event again, including dead deliveries
+	Replay(ctx context.Context, webhookId string, deliveryId string) error
+	// Enqueues deliveries of an event to matching subscriptions. Outbox relay publishes events here
+	Publish(ctx context.Context, event *models.Event) error
+}
+
+type webhookInteractor struct {
+	log                *slog.Logger
+	webhooksRepository webhooksRepo.Repository
+}
+
+func NewWebhookInteractor(
+	log *slog.Logger,
+	webhooksRepository webhooksRepo.Repository,
+) WebhookInteractor {
+	return &tracedWebhookInteractor{
+		next: &webhookInteractor{
+			log:                log.WithGroup("webhook_interactor"),
+			webhooksRepository: webhooksRepository,
+		},
+	}
+}
+
+type CreateWebhookParams struct {
+	URL        string
+	Secret     string
+	EventTypes []string // All events if empty
+	ProductId  string   // If passed, only events of the product are delivered
+	StorageId  string   // If passed, only events in the storage are delivered
+}
+
+func (c *webhookInteractor) Create(
+	ctx context.Context,
+	params CreateWebhookParams,
+) (*models.WebhookSubscription, error) {
+	if params.URL == "" {
+		return nil, fmt.Errorf("error webhook url is not provided. %w", ErrorFieldRequired)
+	}
+
+	target, err := url.Parse(params.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("error webhook url %s must be absolute http(s) url. %w", params.URL, ErrorInvalidField)
+	}
+
+	for _, eventType := range params.EventTypes {
+		if !slices.Contains(webhookEventTypes, eventType) {
+			return nil, fmt.Errorf("error unknown event type %s. %w", eventType, ErrorInvalidField)
+		}
+	}
+
+	createParams := webhooksRepo.CreateParams{
+		URL:        params.URL,
+		Secret:     params.Secret,
+		EventTypes: params.EventTypes,
+	}
+
+	if params.ProductId != "" {
+		if createParams.ProductId, err = uuid.Parse(params.ProductId); err != nil {
+			return nil, fmt.Errorf("error parse product id. %w", err)
+		}
+	}
+
+	if params.StorageId != "" {
+		if createParams.StorageId, err = uuid.Parse(params.StorageId); err != nil {
+			return nil, fmt.Errorf("error parse storage id. %w", err)
+		}
+	}
+
+	if createParams.Secret == "" {
+		if createParams.Secret, err = generateSecret(); err != nil {
+			return nil, fmt.Errorf("error generate webhook secret. %w", err)
+		}
+	}
+
+	if createParams.EventTypes == nil {
+		createParams.EventTypes = []string{}
+	}
+
+	subscription, err := c.webhooksRepository.Create(ctx, createParams)
+	if err != nil {
+		return nil, fmt.Errorf("error store webhook subscription. %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (c *webhookInteractor) Webhooks(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	subscriptions, err := c.webhooksRepository.Subscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch webhook subscriptions. %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (c *webhookInteractor) Delete(ctx context.Context, id string) error {
+	subscriptionId, err := uuid.Parse(id)
+	if err != nil {
+		return fmt.Errorf("error parse webhook id. %w", err)
+	}
+
+	if err = c.webhooksRepository.Delete(ctx, subscriptionId); err != nil {
+		return fmt.Errorf("error delete webhook subscription. %w", err)
+	}
+
+	return nil
+}
+
+type WebhookDeliveriesParams struct {
+	WebhookId string
+	Status    string // pending, delivered or dead. All if empty
+	Limit     uint64
+	Offset    uint64
+	Cursor    string // Opaque cursor returned with a previous page. Overrides Offset
+}
+
+func (c *webhookInteractor) Deliveries(
+	ctx context.Context,
+	params WebhookDeliveriesParams,
+) (*models.Page[*models.WebhookDelivery], error) {
+	subscriptionId, err := uuid.Parse(params.WebhookId)
+	if err != nil {
+		return nil, fmt.Errorf("error parse webhook id. %w", err)
+	}
+
+	statuses := []string{models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead}
+	if params.Status != "" && !slices.Contains(statuses, params.Status) {
+		return nil, fmt.Errorf("error unknown delivery status %s. %w", params.Status, ErrorInvalidField)
+	}
+
+	cursor, err := sqltools.ParseCursor(params.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("error parse cursor. %w", err)
+	}
+
+	deliveries, err := c.webhooksRepository.Deliveries(ctx, webhooksRepo.DeliveriesParams{
+		SubscriptionId: subscriptionId,
+		Status:         params.Status,
+		Pagination: sqltools.Pagination{
+			Cursor: cursor,
+			Limit:  params.Limit,
+			Offset: params.Offset,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetch webhook deliveries. %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (c *webhookInteractor) Replay(ctx context.Context, webhookId string, deliveryId string) error {
+	subscriptionId, err := uuid.Parse(webhookId)
+	if err != nil {
+		return fmt.Errorf("error parse webhook id. %w", err)
+	}
+
+	deliveryUUID, err := uuid.Parse(deliveryId)
+	if err != nil {
+		return fmt.Errorf("error parse delivery id. %w", err)
+	}
+
+	if err = c.webhooksRepository.Replay(ctx, subscriptionId, deliveryUUID); err != nil {
+		return fmt.Errorf("error replay webhook delivery. %w", err)
+	}
+
+	return nil
+}
+
+func (c *webhookInteractor) Publish(ctx context.Context, event *models.Event) error {
+	// Event data of all inventory events carries product and storage ids
+	var data struct {
+		ProductId string `json:"product_id"`
+		StorageId string `json:"storage_id"`
+	}
+
+	if err := json.Unmarshal(event.Payload, &data); err != nil {
+		return fmt.Errorf("error unmarshal %s event data. %w", event.Type, err)
+	}
+
+	payload, err := json.Marshal(dto.MapEventFromModel(event))
+	if err != nil {
+		return fmt.Errorf("error marshal event. %w", err)
+	}
+
+	params := webhooksRepo.EnqueueParams{
+		EventId:   event.Id,
+		EventType: event.Type,
+		Payload:   payload,
+	}
+
+	// Ids are optional filters. Malformed ones just match no filtered subscriptions
+	params.ProductId, _ = uuid.Parse(data.ProductId)
+	params.StorageId, _ = uuid.Parse(data.StorageId)
+
+	enqueued, err := c.webhooksRepository.Enqueue(ctx, params)
+	if err != nil {
+		return fmt.Errorf("error enqueue webhook deliveries. %w", err)
+	}
+
+	if enqueued > 0 {
+		c.log.DebugContext(
+			ctx,
+			"webhook deliveries enqueued",
+			slog.String("event_id", event.Id.String()),
+			slog.Int64("amount", enqueued),
+		)
+	}
+
+	return nil
+}
+
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,5 @@
+package apikeys
+
+import "errors"
+
+var ErrorKeyNotFound = errors.New("api key not found")
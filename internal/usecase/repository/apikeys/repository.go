@@ -0,0 +1,160 @@
+package apikeys
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// API keys repository
+type Repository interface {
+	// Stores a new key by its hash
+	Create(ctx context.Context, params CreateParams) (*models.APIKey, error)
+	// Fetch an active key by its hash
+	KeyByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	// Fetch all keys, including revoked ones
+	Keys(ctx context.Context) ([]*models.APIKey, error)
+	// Revokes a key. Revoked keys are kept for audit
+	Revoke(ctx context.Context, id uuid.UUID) error
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositorySql{db}
+}
+
+type repositorySql struct {
+	db *sql.DB
+}
+
+func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
+	return sqltools.Conn(ctx, s.db)
+}
+
+type CreateParams struct {
+	Name    string
+	KeyHash string
+	Scopes  []string
+}
+
+func (r *repositorySql) Create(ctx context.Context, params CreateParams) (*models.APIKey, error) {
+	key := &models.APIKey{
+		Id:        uuid.New(),
+		Name:      params.Name,
+		Scopes:    params.Scopes,
+		CreatedAt: time.Now(),
+	}
+
+	query := sq.Insert("api_keys").
+		Columns("id", "name", "key_hash", "scopes", "created_at").
+		Values(key.Id, key.Name, params.KeyHash, pq.Array(key.Scopes), key.CreatedAt).
+		PlaceholderFormat(sq.Dollar)
+
+	if _, err := sqltools.Exec(ctx, r.Conn(ctx), query); err != nil {
+		return nil, fmt.Errorf("error insert api key. %w", err)
+	}
+
+	return key, nil
+}
+
+func selectKeysQuery() sq.SelectBuilder {
+	return sq.Select("id", "name", "scopes", "created_at", "revoked_at").
+		From("api_keys").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func (r *repositorySql) KeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	query := selectKeysQuery().Where(sq.Eq{
+		"key_hash":   hash,
+		"revoked_at": nil,
+	})
+
+	rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch api key. %w", err)
+	}
+
+	keys, err := scanKeys(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("error key is not found or revoked. %w", ErrorKeyNotFound)
+	}
+
+	return keys[0], nil
+}
+
+func (r *repositorySql) Keys(ctx context.Context) ([]*models.APIKey, error) {
+	rows, err := sqltools.Query(ctx, r.Conn(ctx), selectKeysQuery().OrderBy("created_at", "id"))
+	if err != nil {
+		return nil, fmt.Errorf("error fetch api keys. %w", err)
+	}
+
+	return scanKeys(rows)
+}
+
+func scanKeys(rows *sqltools.Rows) (keys []*models.APIKey, err error) {
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			key       models.APIKey
+			revokedAt sql.NullTime
+		)
+
+		if err := rows.Scan(&key.Id, &key.Name, pq.Array(&key.Scopes), &key.CreatedAt, &revokedAt); err != nil {
+			return nil, fmt.Errorf("error scan row. %w", err)
+		}
+
+		if revokedAt.Valid {
+			key.RevokedAt = &revokedAt.Time
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error process rows. %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *repositorySql) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := sq.Update("api_keys").
+		Set("revoked_at", time.Now()).
+		Where(sq.Eq{
+			"id":         id,
+			"revoked_at": nil,
+		}).
+		PlaceholderFormat(sq.Dollar)
+
+	result, err := sqltools.Exec(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return fmt.Errorf("error revoke api key. %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error fetch affected rows. %w", err)
+	}
+
+	if affected == 0 {
+		return fmt.Errorf("error key %s is not found or already revoked. %w", id.String(), ErrorKeyNotFound)
+	}
+
+	return nil
+}
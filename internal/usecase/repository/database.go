@@ -2,27 +2,130 @@ package repository
 
 import (
 	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/pkg/metrics"
 	sqlutils "cernunnos/internal/pkg/sqltools"
+	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
+	"strconv"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// Replica is a read-only database connection. DB is nil if no replica is configured
+type Replica struct {
+	DB *sql.DB
+}
+
 func ProvideDatabaseConnection(c *config.Config) (*sql.DB, func(), error) {
-	connStr := fmt.Sprintf("postgresql://%s:%s@%s/cernunnos?sslmode=disable",
-		c.DatabaseUser, c.DatabasePassword, c.DatabaseHost)
+	return connect(c, c.DatabaseHost, c.Database.Name)
+}
 
-	db, err := sql.Open("postgres", connStr)
+// ProvideReplicaConnection connects to replica with primary credentials and options
+func ProvideReplicaConnection(c *config.Config) (Replica, func(), error) {
+	if c.Database.ReplicaHost == "" {
+		return Replica{}, func() {}, nil
+	}
+
+	db, cleanup, err := connect(c, c.Database.ReplicaHost, c.Database.Name+"_replica")
+	if err != nil {
+		return Replica{}, func() {}, fmt.Errorf("error connecting to replica: %w", err)
+	}
+
+	return Replica{DB: db}, cleanup, nil
+}
+
+func connect(c *config.Config, host string, metricsName string) (*sql.DB, func(), error) {
+	db, err := sql.Open("postgres", DSN(c, host))
 	if err != nil {
 		return nil, func() {}, fmt.Errorf("error connecting to database: %w", err)
 	}
 
+	ConfigurePool(db, c.Database)
+
+	if err = ping(db, c.Database.ConnectAttempts, c.Database.ConnectRetryDelay); err != nil {
+		db.Close()
+
+		return nil, func() {}, fmt.Errorf("error connecting to database: %w", err)
+	}
+
+	unregisterMetrics, err := metrics.RegisterDatabase(db, metricsName)
+	if err != nil {
+		db.Close()
+
+		return nil, func() {}, fmt.Errorf("error register database metrics: %w", err)
+	}
+
 	return db, func() {
+		unregisterMetrics()
 		db.Close()
 	}, nil
 }
 
+// ConfigurePool limits connections pool by database options
+func ConfigurePool(db *sql.DB, options config.Database) {
+	db.SetMaxOpenConns(options.MaxOpenConns)
+	db.SetMaxIdleConns(options.MaxIdleConns)
+	db.SetConnMaxLifetime(options.ConnMaxLifetime)
+}
+
+// DSN builds a connection string to host. Statement timeout is passed as a session parameter
+func DSN(c *config.Config, host string) string {
+	params := url.Values{}
+	params.Set("sslmode", c.Database.SSLMode)
+
+	optional := map[string]string{
+		"sslrootcert":      c.Database.SSLRootCert,
+		"sslcert":          c.Database.SSLCert,
+		"sslkey":           c.Database.SSLKey,
+		"application_name": c.Database.ApplicationName,
+	}
+
+	for key, value := range optional {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+
+	if c.Database.StatementTimeout > 0 {
+		params.Set("statement_timeout", strconv.FormatInt(c.Database.StatementTimeout.Milliseconds(), 10))
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgresql",
+		User:     url.UserPassword(c.DatabaseUser, c.DatabasePassword),
+		Host:     host,
+		Path:     c.Database.Name,
+		RawQuery: params.Encode(),
+	}
+
+	return dsn.String()
+}
+
+// ping waits for database to accept connections. Fails if no attempt succeeded
+func ping(db *sql.DB, attempts int, delay time.Duration) error {
+	var err error
+
+	for attempt := 1; attempt <= max(attempts, 1); attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = db.PingContext(ctx)
+
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+
+		if attempt < attempts {
+			time.Sleep(delay)
+		}
+	}
+
+	return fmt.Errorf("error ping database after %d attempts. %w", max(attempts, 1), err)
+}
+
 type Repository interface {
 	DB() sqlutils.DBTX
 }
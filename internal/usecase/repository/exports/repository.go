@@ -0,0 +1,119 @@
+package exports
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+// Exports repository streams rows to callers instead of fetching them by pages
+type Repository interface {
+	// Passes products distribution with products and storages to fn row by row, ordered by storage
+	// and product ids. Rows are read in a single transaction, so they are a consistent snapshot.
+	Stock(ctx context.Context, filter StockFilter, fn func(product *models.StorageProduct) error) error
+}
+
+func NewRepository(db *sql.DB, replica *sql.DB) Repository {
+	return &repositorySql{db, replica}
+}
+
+type repositorySql struct {
+	db      *sql.DB
+	replica *sql.DB // nil if replica is not configured
+}
+
+func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
+	return sqltools.Conn(ctx, s.db)
+}
+
+type StockFilter struct {
+	StorageIds uuid.UUIDs // Any storage if empty
+	ProductIds uuid.UUIDs // Any product if empty
+}
+
+func (r *repositorySql) Stock(
+	ctx context.Context,
+	filter StockFilter,
+	fn func(product *models.StorageProduct) error,
+) error {
+	query := sq.Select(
+		"s.id",
+		"s.name",
+		"s.available",
+		"s.reserved",
+		"p.id",
+		"p.name",
+		"p.size",
+		"pd.amount",
+		"pd.reserved",
+		"pd.available",
+	).
+		From("products_distribution as pd").
+		InnerJoin("storages as s on s.id = pd.storage_id").
+		InnerJoin("products as p on p.id = pd.product_id").
+		OrderBy("pd.storage_id", "pd.product_id").
+		PlaceholderFormat(sq.Dollar)
+
+	if len(filter.StorageIds) > 0 {
+		query = query.Where(sq.Eq{"pd.storage_id": filter.StorageIds})
+	}
+
+	if len(filter.ProductIds) > 0 {
+		query = query.Where(sq.Eq{"pd.product_id": filter.ProductIds})
+	}
+
+	err := sqltools.ReadTransaction(ctx, r.db, r.replica, func(ctx context.Context) (err error) {
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+		if err != nil {
+			return fmt.Errorf("error fetch stock from database. %w", err)
+		}
+
+		defer func() {
+			if closeErr := rows.Close(); closeErr != nil {
+				err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+			}
+		}()
+
+		for rows.Next() {
+			storage := new(models.Storage)
+			product := &models.StorageProduct{ProductDestribution: models.ProductDestribution{Storage: storage}}
+
+			err = rows.Scan(
+				&storage.Id,
+				&storage.Name,
+				&storage.Available,
+				&storage.Reserved,
+				&product.Id,
+				&product.Name,
+				&product.Size,
+				&product.Amount,
+				&product.Reserved,
+				&product.Available,
+			)
+			if err != nil {
+				return fmt.Errorf("error scan row. %w", err)
+			}
+
+			if err = fn(product); err != nil {
+				return fmt.Errorf("error handle stock row. %w", err)
+			}
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error process rows. %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error execute transactional operation. %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is the latest migration version the service expects. Must be bumped with every
+// migration added to migrations directory.
+const SchemaVersion = 9
+
+var (
+	ErrorSchemaOutdated = errors.New("database schema is outdated")
+	ErrorPoolExhausted  = errors.New("database connection pool is exhausted")
+)
+
+// PingCheck checks database is reachable
+func PingCheck(db *sql.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := db.PingContext(ctx); err != nil {
+			return fmt.Errorf("error ping database. %w", err)
+		}
+
+		return nil
+	}
+}
+
+// SchemaCheck checks all migrations expected are applied
+func SchemaCheck(db *sql.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var version sql.NullInt64
+
+		err := db.QueryRowContext(ctx, "select max(version) from schema_migrations").Scan(&version)
+		if err != nil {
+			return fmt.Errorf("error fetch schema version. %w", err)
+		}
+
+		if version.Int64 < SchemaVersion {
+			return fmt.Errorf(
+				"error schema version %d, expected %d. %w", version.Int64, SchemaVersion, ErrorSchemaOutdated,
+			)
+		}
+
+		return nil
+	}
+}
+
+// PoolCheck checks there are free connections in a pool. Pools without connections limit are
+// never exhausted. Other checks of the pool hold its connections, so the check must run alone.
+func PoolCheck(db *sql.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		stats := db.Stats()
+
+		if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+			return fmt.Errorf(
+				"error %d of %d connections in use. %w", stats.InUse, stats.MaxOpenConnections, ErrorPoolExhausted,
+			)
+		}
+
+		return nil
+	}
+}
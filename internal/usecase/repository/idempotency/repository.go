@@ -0,0 +1,169 @@
+package idempotency
+
+import (
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// Idempotency keys repository. Keys are scoped by client, so clients may not replay responses of
+// each other.
+type Repository interface {
+	// Claims a key for a request. Returns nil if the key is claimed, or the stored request if the key
+	// was claimed before and is not expired. Keys claimed before abandonedBefore and never completed
+	// are claimed again, as their requests are gone without releasing them.
+	Claim(
+		ctx context.Context,
+		request *models.IdempotentRequest,
+		expiredBefore, abandonedBefore time.Time,
+	) (*models.IdempotentRequest, error)
+	// Stores the response of a claimed request. Fails with ErrorIdempotencyClaimLost if the key was
+	// claimed again since, so a request outliving its lease can not overwrite a later request.
+	Complete(ctx context.Context, claim *models.IdempotentRequest, statusCode int, response []byte) error
+	// Drops a claimed key, so the request may be retried. Fails with ErrorIdempotencyClaimLost if
+	// the key was claimed again since.
+	Release(ctx context.Context, claim *models.IdempotentRequest) error
+	// Drops keys created before a moment
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositorySql{db}
+}
+
+type repositorySql struct {
+	db *sql.DB
+}
+
+func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
+	return sqltools.Conn(ctx, s.db)
+}
+
+func (r *repositorySql) Claim(
+	ctx context.Context,
+	request *models.IdempotentRequest,
+	expiredBefore, abandonedBefore time.Time,
+) (*models.IdempotentRequest, error) {
+	// Expired keys are claimed again, as if they were never used. So are keys left in progress by
+	// crashed requests.
+	claimQuery := sq.Insert("idempotency_keys").
+		Columns("client", "key", "request_hash", "created_at").
+		Values(request.Client, request.Key, request.RequestHash, request.CreatedAt).
+		Suffix(`on conflict (client, key) do update
+			set request_hash = excluded.request_hash, status_code = 0, response = null, created_at = excluded.created_at
+			where idempotency_keys.created_at < ?
+				or (idempotency_keys.status_code = 0 and idempotency_keys.created_at < ?)
+			returning key`, expiredBefore, abandonedBefore).
+		PlaceholderFormat(sq.Dollar)
+
+	var key string
+
+	err := sqltools.QueryRow(ctx, r.Conn(ctx), claimQuery, &key)
+	if err == nil {
+		return nil, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("error claim idempotency key. %w", err)
+	}
+
+	query := sq.Select("request_hash", "status_code", "response", "created_at").
+		From("idempotency_keys").
+		Where(sq.Eq{"client": request.Client, "key": request.Key}).
+		PlaceholderFormat(sq.Dollar)
+
+	stored := &models.IdempotentRequest{Client: request.Client, Key: request.Key}
+
+	err = sqltools.QueryRow(
+		ctx,
+		r.Conn(ctx),
+		query,
+		&stored.RequestHash,
+		&stored.StatusCode,
+		&stored.Response,
+		&stored.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch idempotency key. %w", err)
+	}
+
+	return stored, nil
+}
+
+func (r *repositorySql) Complete(
+	ctx context.Context,
+	claim *models.IdempotentRequest,
+	statusCode int,
+	response []byte,
+) error {
+	query := sq.Update("idempotency_keys").
+		Set("status_code", statusCode).
+		Set("response", response).
+		Where(claimedBy(claim)).
+		PlaceholderFormat(sq.Dollar)
+
+	result, err := sqltools.Exec(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return fmt.Errorf("error store idempotent response. %w", err)
+	}
+
+	return claimHeld(result)
+}
+
+func (r *repositorySql) Release(ctx context.Context, claim *models.IdempotentRequest) error {
+	query := sq.Delete("idempotency_keys").
+		Where(claimedBy(claim)).
+		PlaceholderFormat(sq.Dollar)
+
+	result, err := sqltools.Exec(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return fmt.Errorf("error release idempotency key. %w", err)
+	}
+
+	return claimHeld(result)
+}
+
+// claimedBy matches a key only while it is held by the claim. Claiming a key again overwrites its
+// creation time.
+func claimedBy(claim *models.IdempotentRequest) sq.Eq {
+	return sq.Eq{"client": claim.Client, "key": claim.Key, "created_at": claim.CreatedAt}
+}
+
+// claimHeld fails if the claimed key was not changed, as it is claimed by another request
+func claimHeld(result sql.Result) error {
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error count changed idempotency keys. %w", err)
+	}
+
+	if changed == 0 {
+		return fmt.Errorf("error idempotency key is claimed by another request. %w", errs.ErrorIdempotencyClaimLost)
+	}
+
+	return nil
+}
+
+func (r *repositorySql) Purge(ctx context.Context, before time.Time) (int64, error) {
+	query := sq.Delete("idempotency_keys").
+		Where(sq.Lt{"created_at": before}).
+		PlaceholderFormat(sq.Dollar)
+
+	result, err := sqltools.Exec(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return 0, fmt.Errorf("error purge idempotency keys. %w", err)
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error count purged idempotency keys. %w", err)
+	}
+
+	return purged, nil
+}
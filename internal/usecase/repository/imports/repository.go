@@ -0,0 +1,322 @@
+package imports
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
+	outboxRepo "cernunnos/internal/usecase/repository/outbox"
+	stockRepo "cernunnos/internal/usecase/repository/stock"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrorRollback discards rows loaded by Load. Return it from the load function
+	ErrorRollback            = errors.New("import rolled back")
+	ErrorStorageNotFound     = errors.New("storage not found")
+	ErrorProductNotFound     = errors.New("product not found")
+	ErrorAmountBelowReserved = errors.New("amount is less than reserved one")
+)
+
+// Imports repository loads rows in batches. Rows of a batch are upserted with a single statement
+type Repository interface {
+	// Runs fn in a transaction. Loaded rows are committed, if fn succeeds. ErrorRollback returned by
+	// fn rolls the transaction back without an error.
+	Load(ctx context.Context, fn func(ctx context.Context) error) error
+	// Inserts storages or updates names and available space of existing ones
+	UpsertStorages(ctx context.Context, storages []*models.Storage) error
+	// Inserts products or updates names and sizes of existing ones
+	UpsertProducts(ctx context.Context, products []*models.ProductInfo) error
+	// Sets amounts of products in storages. Reserved amounts are kept, so available ones are
+	// recalculated. Stock level changes are written to outbox and notified once the load function
+	// succeeds. Lines which can not be loaded are skipped, their reasons are returned by line index.
+	UpsertStock(ctx context.Context, lines []*StockLine) (map[int]error, error)
+}
+
+func NewRepository(db *sql.DB, outbox outboxRepo.Repository, stock stockRepo.Repository) Repository {
+	return &repositorySql{db, outbox, stock}
+}
+
+type repositorySql struct {
+	db     *sql.DB
+	outbox outboxRepo.Repository
+	stock  stockRepo.Repository
+}
+
+func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
+	return sqltools.Conn(ctx, s.db)
+}
+
+// StockLine is an amount of a product in a storage
+type StockLine struct {
+	StorageId uuid.UUID
+	ProductId uuid.UUID
+	Amount    int64
+}
+
+// loadedEvents collects events of a load. They are written to outbox right before commit, as
+// adding events blocks other transactions adding them until the load ends.
+type loadedEvents struct {
+	events []*models.Event
+}
+
+type loadedEventsCtxKey struct{}
+
+func (r *repositorySql) Load(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
+		loaded := new(loadedEvents)
+
+		if err := fn(context.WithValue(ctx, loadedEventsCtxKey{}, loaded)); err != nil {
+			return err
+		}
+
+		return r.addEvents(ctx, loaded.events)
+	})
+	if err != nil && !errors.Is(err, ErrorRollback) {
+		return fmt.Errorf("error load rows. %w", err)
+	}
+
+	return nil
+}
+
+// addEvents writes events to outbox and notifies stock listeners of them
+func (r *repositorySql) addEvents(ctx context.Context, events []*models.Event) error {
+	if err := r.outbox.Add(ctx, events...); err != nil {
+		return fmt.Errorf("error add events to outbox. %w", err)
+	}
+
+	for _, event := range events {
+		if err := r.stock.Notify(ctx, event); err != nil {
+			return fmt.Errorf("error notify stock level change. %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *repositorySql) UpsertStorages(ctx context.Context, storages []*models.Storage) error {
+	if len(storages) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	query := sq.Insert("storages").
+		Columns("id", "name", "available", "reserved", "created_at", "updated_at").
+		Suffix(`on conflict (id) do update set
+			name = excluded.name,
+			available = excluded.available,
+			updated_at = excluded.updated_at`).
+		PlaceholderFormat(sq.Dollar)
+
+	for _, storage := range storages {
+		query = query.Values(storage.Id, storage.Name, storage.Available, 0, now, now)
+	}
+
+	if _, err := sqltools.Exec(ctx, r.Conn(ctx), query); err != nil {
+		return fmt.Errorf("error upsert storages. %w", err)
+	}
+
+	return nil
+}
+
+func (r *repositorySql) UpsertProducts(ctx context.Context, products []*models.ProductInfo) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	query := sq.Insert("products").
+		Columns("id", "name", "size", "created_at", "updated_at").
+		Suffix(`on conflict (id) do update set
+			name = excluded.name,
+			size = excluded.size,
+			updated_at = excluded.updated_at`).
+		PlaceholderFormat(sq.Dollar)
+
+	for _, product := range products {
+		query = query.Values(product.Id, product.Name, product.Size, now, now)
+	}
+
+	if _, err := sqltools.Exec(ctx, r.Conn(ctx), query); err != nil {
+		return fmt.Errorf("error upsert products. %w", err)
+	}
+
+	return nil
+}
+
+func (r *repositorySql) UpsertStock(ctx context.Context, lines []*StockLine) (map[int]error, error) {
+	rejected := make(map[int]error)
+
+	if len(lines) == 0 {
+		return rejected, nil
+	}
+
+	storageIds := make([]uuid.UUID, 0, len(lines))
+	productIds := make([]uuid.UUID, 0, len(lines))
+
+	for _, line := range lines {
+		storageIds = append(storageIds, line.StorageId)
+		productIds = append(productIds, line.ProductId)
+	}
+
+	storages, err := r.existing(ctx, "storages", storageIds)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch existing storages. %w", err)
+	}
+
+	products, err := r.existing(ctx, "products", productIds)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch existing products. %w", err)
+	}
+
+	now := time.Now()
+
+	query := sq.Insert("products_distribution").
+		Columns("storage_id", "product_id", "amount", "reserved", "available", "created_at", "updated_at").
+		Suffix(`on conflict (storage_id, product_id) do update set
+			amount = excluded.amount,
+			available = excluded.amount - products_distribution.reserved,
+			updated_at = excluded.updated_at
+			where products_distribution.reserved <= excluded.amount
+			returning storage_id, product_id, amount, reserved, available`).
+		PlaceholderFormat(sq.Dollar)
+
+	loading := make(map[[2]uuid.UUID]int, len(lines))
+
+	for i, line := range lines {
+		switch {
+		case !storages[line.StorageId]:
+			rejected[i] = ErrorStorageNotFound
+		case !products[line.ProductId]:
+			rejected[i] = ErrorProductNotFound
+		default:
+			loading[[2]uuid.UUID{line.StorageId, line.ProductId}] = i
+			query = query.Values(line.StorageId, line.ProductId, line.Amount, 0, line.Amount, now, now)
+		}
+	}
+
+	if len(loading) == 0 {
+		return rejected, nil
+	}
+
+	levels, err := r.upsertStock(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*models.Event, 0, len(levels))
+
+	for _, level := range levels {
+		delete(loading, [2]uuid.UUID{level.StorageId, level.ProductId})
+
+		event, err := models.NewEvent(models.EventStockLevelChanged, dto.StockLevelEvent{
+			ProductId: level.ProductId.String(),
+			StorageId: level.StorageId.String(),
+			Amount:    level.Amount,
+			Reserved:  level.Reserved,
+			Available: level.Available,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	// Lines not returned conflicted with distributions having more products reserved
+	for _, i := range loading {
+		rejected[i] = ErrorAmountBelowReserved
+	}
+
+	// Events of stock loaded outside of Load are added right away
+	if loaded, ok := ctx.Value(loadedEventsCtxKey{}).(*loadedEvents); ok {
+		loaded.events = append(loaded.events, events...)
+	} else if err = r.addEvents(ctx, events); err != nil {
+		return nil, err
+	}
+
+	return rejected, nil
+}
+
+// upsertStock runs a distributions upsert and returns levels of loaded lines
+func (r *repositorySql) upsertStock(
+	ctx context.Context,
+	query sq.InsertBuilder,
+) (levels []*models.StockChange, err error) {
+	rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return nil, fmt.Errorf("error upsert products distribution. %w", err)
+	}
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+		}
+	}()
+
+	for rows.Next() {
+		level := new(models.StockChange)
+
+		err = rows.Scan(&level.StorageId, &level.ProductId, &level.Amount, &level.Reserved, &level.Available)
+		if err != nil {
+			return nil, fmt.Errorf("error scan row. %w", err)
+		}
+
+		levels = append(levels, level)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error process rows. %w", err)
+	}
+
+	return levels, nil
+}
+
+// existing returns which of ids are present in a table
+func (r *repositorySql) existing(
+	ctx context.Context,
+	table string,
+	ids []uuid.UUID,
+) (_ map[uuid.UUID]bool, err error) {
+	query := sq.Select("id").
+		From(table).
+		Where(sq.Eq{"id": ids}).
+		PlaceholderFormat(sq.Dollar)
+
+	rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch ids. %w", err)
+	}
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+		}
+	}()
+
+	existing := make(map[uuid.UUID]bool, len(ids))
+
+	for rows.Next() {
+		var id uuid.UUID
+
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scan row. %w", err)
+		}
+
+		existing[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error process rows. %w", err)
+	}
+
+	return existing, nil
+}
@@ -0,0 +1,265 @@
+package outbox
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+// relayLockId is an advisory lock held by the relay publishing events. Only one relay
+// publishes at a time, so events are published in order.
+const relayLockId = 0x6f7574626f78
+
+// appendLockId is an advisory lock held by transactions adding events until they end. Positions
+// are allocated one transaction at a time, so they follow commit order and a reader never sees an
+// event committed after events with greater positions.
+const appendLockId = 0x6f7574626f79
+
+// Events inserted with a single statement, so large batches stay within bind parameters limit
+const insertBatch = 1000
+
+// Outbox repository
+type Repository interface {
+	// Writes events in a transaction carried by ctx and sets their positions. Events are published
+	// in the order they are added. Adding events blocks other transactions adding them until the
+	// transaction ends, so events should be added right before commit.
+	Add(ctx context.Context, events ...*models.Event) error
+	// Fetch events of a type after a position in position order, whether published or not
+	Events(ctx context.Context, params EventsParams) ([]*models.Event, error)
+	// Passes up to limit unpublished events to publish in order and marks passed ones as published.
+	// Stops at the first publish error. Does nothing if another relay is publishing.
+	Publish(
+		ctx context.Context,
+		limit uint64,
+		publish func(ctx context.Context, event *models.Event) error,
+	) (int, error)
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositorySql{db}
+}
+
+type repositorySql struct {
+	db *sql.DB
+}
+
+func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
+	return sqltools.Conn(ctx, s.db)
+}
+
+func (r *repositorySql) Add(ctx context.Context, events ...*models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	lockQuery := sq.Select().
+		Column(sq.Expr("pg_advisory_xact_lock(?)", appendLockId)).
+		PlaceholderFormat(sq.Dollar)
+
+	if _, err := sqltools.Exec(ctx, r.Conn(ctx), lockQuery); err != nil {
+		return fmt.Errorf("error acquire outbox append lock. %w", err)
+	}
+
+	for start := 0; start < len(events); start += insertBatch {
+		if err := r.insert(ctx, events[start:min(start+insertBatch, len(events))]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insert writes events and sets their positions
+func (r *repositorySql) insert(ctx context.Context, events []*models.Event) (err error) {
+	query := sq.Insert("outbox").
+		Columns("event_id", "event_type", "payload", "created_at").
+		PlaceholderFormat(sq.Dollar)
+
+	positions := make(map[uuid.UUID]*models.Event, len(events))
+
+	for _, event := range events {
+		query = query.Values(event.Id, event.Type, event.Payload, event.CreatedAt)
+		positions[event.Id] = event
+	}
+
+	rows, err := sqltools.Query(ctx, r.Conn(ctx), query.Suffix("returning event_id, id"))
+	if err != nil {
+		return fmt.Errorf("error insert outbox events. %w", err)
+	}
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			eventId  uuid.UUID
+			position int64
+		)
+
+		if err = rows.Scan(&eventId, &position); err != nil {
+			return fmt.Errorf("error scan row. %w", err)
+		}
+
+		if event, ok := positions[eventId]; ok {
+			event.Position = position
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error process rows. %w", err)
+	}
+
+	return nil
+}
+
+type EventsParams struct {
+	Type      string
+	After     int64     // Position
+	ProductId uuid.UUID // Matches product_id of event data, if passed
+	StorageId uuid.UUID // Matches storage_id of event data, if passed
+	Limit     uint64
+}
+
+func (r *repositorySql) Events(ctx context.Context, params EventsParams) ([]*models.Event, error) {
+	query := sq.Select("id", "event_id", "event_type", "payload", "created_at").
+		From("outbox").
+		Where(sq.Eq{"event_type": params.Type}).
+		Where(sq.Gt{"id": params.After}).
+		OrderBy("id").
+		Limit(params.Limit).
+		PlaceholderFormat(sq.Dollar)
+
+	if params.ProductId != uuid.Nil {
+		query = query.Where(sq.Expr("payload->>'product_id' = ?", params.ProductId.String()))
+	}
+
+	if params.StorageId != uuid.Nil {
+		query = query.Where(sq.Expr("payload->>'storage_id' = ?", params.StorageId.String()))
+	}
+
+	events, err := r.scanEvents(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch outbox events. %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *repositorySql) Publish(
+	ctx context.Context,
+	limit uint64,
+	publish func(ctx context.Context, event *models.Event) error,
+) (int, error) {
+	var (
+		published  []int64
+		publishErr error
+	)
+
+	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
+		var locked bool
+
+		lockQuery := sq.Select().
+			Column(sq.Expr("pg_try_advisory_xact_lock(?)", relayLockId)).
+			PlaceholderFormat(sq.Dollar)
+
+		if err := sqltools.QueryRow(ctx, r.Conn(ctx), lockQuery, &locked); err != nil {
+			return fmt.Errorf("error acquire outbox lock. %w", err)
+		}
+
+		if !locked {
+			return nil
+		}
+
+		events, err := r.pending(ctx, limit)
+		if err != nil {
+			return fmt.Errorf("error fetch pending events. %w", err)
+		}
+
+		for _, event := range events {
+			if publishErr = publish(ctx, event); publishErr != nil {
+				break
+			}
+
+			published = append(published, event.Position)
+		}
+
+		if len(published) == 0 {
+			return nil
+		}
+
+		markQuery := sq.Update("outbox").
+			Set("published_at", time.Now()).
+			Where(sq.Eq{"id": published}).
+			PlaceholderFormat(sq.Dollar)
+
+		if _, err = sqltools.Exec(ctx, r.Conn(ctx), markQuery); err != nil {
+			return fmt.Errorf("error mark events published. %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error execute transactional operation. %w", err)
+	}
+
+	if publishErr != nil {
+		return len(published), fmt.Errorf("error publish event. %w", publishErr)
+	}
+
+	return len(published), nil
+}
+
+func (r *repositorySql) pending(ctx context.Context, limit uint64) ([]*models.Event, error) {
+	query := sq.Select("id", "event_id", "event_type", "payload", "created_at").
+		From("outbox").
+		Where(sq.Eq{"published_at": nil}).
+		OrderBy("id").
+		Limit(limit).
+		PlaceholderFormat(sq.Dollar)
+
+	events, err := r.scanEvents(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch outbox events. %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *repositorySql) scanEvents(ctx context.Context, query sq.SelectBuilder) (events []*models.Event, err error) {
+	rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+		}
+	}()
+
+	for rows.Next() {
+		var event models.Event
+
+		if err := rows.Scan(&event.Position, &event.Id, &event.Type, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scan row. %w", err)
+		}
+
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error process rows. %w", err)
+	}
+
+	return events, nil
+}
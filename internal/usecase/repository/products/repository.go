@@ -15,49 +15,104 @@ import (
 
 type Repository interface {
 	// List products info
-	Products(ctx context.Context, params ProductsParams) ([]*models.ProductInfo, error)
+	Products(ctx context.Context, params ProductsParams) (*models.Page[*models.ProductInfo], error)
 	// List products in a spicific storage
-	StorageProducts(ctx context.Context, params StorageProductsParams) ([]*models.StorageProduct, error)
+	StorageProducts(
+		ctx context.Context,
+		params StorageProductsParams,
+	) (*models.Page[*models.StorageProduct], error)
+	// Search products by name. Results are ranked by full-text and trigram similarity
+	SearchProducts(ctx context.Context, params SearchProductsParams) (*models.Page[*models.ProductInfo], error)
 }
 
-func NewRepository(db *sql.DB) Repository {
-	return &repositorySql{db}
+// NewRepository creates a repository. Listings run on replica, if passed
+func NewRepository(db, replica *sql.DB) Repository {
+	return &repositorySql{db, replica}
 }
 
 type repositorySql struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB // nil if no replica configured
 }
 
-type txCtxKey struct{}
-
 func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
-	if tx, ok := ctx.Value(txCtxKey{}).(*sql.Tx); ok {
-		return tx
-	}
+	return sqltools.Conn(ctx, s.db)
+}
 
-	return s.db
+// Fields available for products filtering and sorting
+var productsFilterSpec = sqltools.FilterSpec{
+	Fields: map[string]string{
+		"name":       "coalesce(p.name, '')",
+		"size":       "p.size",
+		"created_at": "p.created_at",
+		"updated_at": "p.updated_at",
+		"available": `(select coalesce(sum(d.available), 0) from products_distribution as d
+			where d.product_id = p.id)`,
+	},
+	CreatedAt: "p.created_at",
+	Id:        "p.id",
 }
 
+// Fields available for products filtering and sorting at a point in time. Available amounts are
+// summed over stockAsOf joined as pa.
+var productsAsOfFilterSpec = sqltools.FilterSpec{
+	Fields: map[string]string{
+		"name":       "coalesce(p.name, '')",
+		"size":       "p.size",
+		"created_at": "p.created_at",
+		"updated_at": "p.updated_at",
+		"available":  "coalesce(pa.available, 0)",
+	},
+	CreatedAt: "p.created_at",
+	Id:        "p.id",
+}
+
+// stockAsOf selects versions of products distribution valid at a point in time. Placeholders are
+// the point in time twice.
+const stockAsOf = `select storage_id, product_id, amount, reserved, available
+	from products_distribution_history
+	where valid_from <= ? and (valid_to is null or valid_to > ?)`
+
 type ProductsParams struct {
-	Ids    uuid.UUIDs
-	Limit  uint32
-	Offset uint32
+	Ids        uuid.UUIDs
+	AsOf       *time.Time           // Filter by available amounts at the point in time, if passed
+	Conditions []sqltools.Condition // Over productsFilterSpec fields
+	Pagination sqltools.Pagination
+}
+
+func (p ProductsParams) filterSpec() sqltools.FilterSpec {
+	if p.AsOf != nil {
+		return productsAsOfFilterSpec
+	}
+
+	return productsFilterSpec
 }
 
 // List products info
-func (r *repositorySql) Products(ctx context.Context, params ProductsParams) ([]*models.ProductInfo, error) {
-	batchSize := sqltools.DefaultLimit
+func (r *repositorySql) Products(
+	ctx context.Context,
+	params ProductsParams,
+) (*models.Page[*models.ProductInfo], error) {
+	batchSize := params.Pagination.Size()
 
-	if len(params.Ids) > 0 {
-		batchSize = uint32(len(params.Ids))
+	if len(params.Ids) > 0 && uint64(len(params.Ids)) < batchSize {
+		batchSize = uint64(len(params.Ids))
 	}
 
 	products := make([]*models.ProductInfo, 0, batchSize)
 
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
-		var err error
+	var (
+		total *int64
+		keys  []sqltools.Cursor
+	)
+
+	err := sqltools.ReadTransaction(ctx, r.db, r.replica, func(ctx context.Context) error {
+		query, err := buildSelectProductsQuery(params)
+		if err != nil {
+			return fmt.Errorf("error build products query. %w", err)
+		}
 
-		rows, err := buildSelectProductsQuery(params).RunWith(r.Conn(ctx)).QueryContext(ctx)
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
 		if err != nil {
 			return fmt.Errorf("error fetch products from database. %w", err)
 		}
@@ -75,12 +130,15 @@ func (r *repositorySql) Products(ctx context.Context, params ProductsParams) ([]
 				size      int64
 				createdAt time.Time
 				updatedAt time.Time
+				sortKey   string
 			)
 
-			if err = rows.Scan(&id, &name, &size, &createdAt, &updatedAt); err != nil {
+			if err = rows.Scan(&id, &name, &size, &createdAt, &updatedAt, &sortKey); err != nil {
 				return fmt.Errorf("error scan row. %w", err)
 			}
 
+			keys = append(keys, sqltools.Cursor{Value: sortKey, CreatedAt: createdAt, Id: id})
+
 			products = append(products, &models.ProductInfo{
 				Id:        id,
 				Name:      name,
@@ -94,6 +152,20 @@ func (r *repositorySql) Products(ctx context.Context, params ProductsParams) ([]
 			return fmt.Errorf("error process rows. %w", err)
 		}
 
+		if params.Pagination.WithTotal {
+			filterQuery, err := filterProductsQuery(params)
+			if err != nil {
+				return fmt.Errorf("error build products filter query. %w", err)
+			}
+
+			count, err := sqltools.Count(ctx, r.Conn(ctx), filterQuery)
+			if err != nil {
+				return fmt.Errorf("error count products. %w", err)
+			}
+
+			total = &count
+		}
+
 		return err
 	})
 
@@ -101,145 +173,133 @@ func (r *repositorySql) Products(ctx context.Context, params ProductsParams) ([]
 		return nil, fmt.Errorf("error execute transactional operation. %w", err)
 	}
 
-	return products, nil
+	page := sqltools.NewPage(params.Pagination, products, keys)
+	page.Total = total
+
+	return page, nil
 }
 
-func buildSelectProductsQuery(params ProductsParams) sq.SelectBuilder {
-	selectQuery := sq.Select("id", "name", "size", "created_at", "updated_at").
-		From("products").PlaceholderFormat(sq.Dollar)
+func filterProductsQuery(params ProductsParams) (sq.SelectBuilder, error) {
+	selectQuery := sq.Select("p.id", "p.name", "p.size", "p.created_at", "p.updated_at").
+		From("products as p").PlaceholderFormat(sq.Dollar)
 
 	if len(params.Ids) > 0 {
 		selectQuery = selectQuery.Where(sq.Eq{
-			"id": params.Ids,
+			"p.id": params.Ids,
 		})
 	}
 
-	if params.Limit > 0 && params.Limit < sqltools.DefaultLimit {
-		selectQuery = selectQuery.Limit(uint64(params.Limit))
-	} else {
-		selectQuery = selectQuery.Limit(uint64(sqltools.DefaultLimit))
+	// Available amounts are summed at the point in time. Products created later did not exist then
+	if params.AsOf != nil {
+		selectQuery = selectQuery.
+			LeftJoin(
+				"(select product_id, sum(available) as available from ("+stockAsOf+") as v group by product_id) as pa"+
+					" on pa.product_id = p.id",
+				*params.AsOf,
+				*params.AsOf,
+			).
+			Where(sq.LtOrEq{"p.created_at": *params.AsOf})
 	}
 
-	if params.Offset > 0 {
-		selectQuery = selectQuery.Offset(uint64(params.Offset))
+	selectQuery, err := params.filterSpec().Apply(selectQuery, params.Conditions)
+	if err != nil {
+		return selectQuery, fmt.Errorf("error apply products filter. %w", err)
+	}
+
+	return selectQuery, nil
+}
+
+func buildSelectProductsQuery(params ProductsParams) (sq.SelectBuilder, error) {
+	selectQuery, err := filterProductsQuery(params)
+	if err != nil {
+		return selectQuery, err
 	}
 
-	return selectQuery
+	return params.Pagination.Apply(selectQuery, params.filterSpec())
+}
+
+// Fields available for storage products filtering and sorting
+var storageProductsFilterSpec = sqltools.FilterSpec{
+	Fields: map[string]string{
+		"name":       "coalesce(p.name, '')",
+		"size":       "p.size",
+		"created_at": "p.created_at",
+		"updated_at": "p.updated_at",
+		"available":  "pd.available",
+	},
+	CreatedAt: "p.created_at",
+	Id:        "p.id",
 }
 
 type StorageProductsParams struct {
 	Ids             uuid.UUIDs
 	StorageId       uuid.UUID
+	AsOf            *time.Time // Amounts at the point in time instead of current ones, if passed
 	WithUnavailable bool
-	Limit           uint64
-	Offset          uint64
+	Conditions      []sqltools.Condition // Over storageProductsFilterSpec fields
+	Pagination      sqltools.Pagination
 }
 
 // List products in a spicific storage
 func (r *repositorySql) StorageProducts(
 	ctx context.Context,
 	params StorageProductsParams,
-) ([]*models.StorageProduct, error) {
-	storageProducts := make([]*models.StorageProduct, 0, len(params.Ids))
-
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
-		var err error
-		if len(params.Ids) > 0 {
-			storageProducts, err = r.storageProductsByIds(ctx, params)
-			if err != nil {
-				return fmt.Errorf("error fetch storage profucts by ids. %w", err)
-			}
-
-			return nil
-		}
-
-		storageProducts, err = r.allStorageProducts(ctx, params)
+) (*models.Page[*models.StorageProduct], error) {
+	var (
+		storageProducts []*models.StorageProduct
+		total           *int64
+		keys            []sqltools.Cursor
+	)
+
+	err := sqltools.ReadTransaction(ctx, r.db, r.replica, func(ctx context.Context) error {
+		query, err := buildSelectStorageProductQuery(params)
 		if err != nil {
-			return fmt.Errorf("error fetch storage profucts. %w", err)
+			return fmt.Errorf("error build storage products query. %w", err)
 		}
 
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error execute transactional operation. %w", err)
-	}
-
-	return storageProducts, nil
-}
-
-func (r *repositorySql) allStorageProducts(
-	ctx context.Context,
-	params StorageProductsParams,
-) ([]*models.StorageProduct, error) {
-	storageProducts := make([]*models.StorageProduct, 0, len(params.Ids))
-
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
-		query := buildSelectStorageProductQuery(buildSelectStorageProductQueryParams{
-			storageId:       params.StorageId,
-			withUnavailable: params.WithUnavailable,
-			limit:           params.Limit,
-			offset:          params.Offset,
-		})
-
-		var err error
-
-		storageProducts, err = r.fetchStorageProductsWithQuery(ctx, query)
+		storageProducts, keys, err = r.fetchStorageProductsWithQuery(ctx, query)
 		if err != nil {
-			return fmt.Errorf("error fetch products. %w", err)
+			return fmt.Errorf("error fetch storage profucts. %w", err)
 		}
 
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("error execute transactional operation. %w", err)
-	}
-
-	return storageProducts, nil
-}
-
-func (r *repositorySql) storageProductsByIds(
-	ctx context.Context,
-	params StorageProductsParams,
-) ([]*models.StorageProduct, error) {
-	storageProducts := make([]*models.StorageProduct, 0, len(params.Ids))
-
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
-		for _, productId := range params.Ids {
-			query := buildSelectStorageProductQuery(buildSelectStorageProductQueryParams{
-				productId:       productId,
-				storageId:       params.StorageId,
-				withUnavailable: params.WithUnavailable,
-				limit:           params.Limit,
-				offset:          params.Offset,
-			})
-
-			var err error
+		if params.Pagination.WithTotal {
+			filterQuery, err := filterStorageProductQuery(params)
+			if err != nil {
+				return fmt.Errorf("error build storage products filter query. %w", err)
+			}
 
-			products, err := r.fetchStorageProductsWithQuery(ctx, query)
+			count, err := sqltools.Count(ctx, r.Conn(ctx), filterQuery)
 			if err != nil {
-				return fmt.Errorf("error fetch products. %w", err)
+				return fmt.Errorf("error count storage products. %w", err)
 			}
 
-			storageProducts = append(storageProducts, products...)
+			total = &count
 		}
 
 		return nil
 	})
+
 	if err != nil {
 		return nil, fmt.Errorf("error execute transactional operation. %w", err)
 	}
 
-	return storageProducts, nil
+	page := sqltools.NewPage(params.Pagination, storageProducts, keys)
+	page.Total = total
+
+	return page, nil
 }
 
+// fetchStorageProductsWithQuery fetches storage products and their keyset positions
 func (r *repositorySql) fetchStorageProductsWithQuery(
 	ctx context.Context,
 	query sq.SelectBuilder,
-) ([]*models.StorageProduct, error) {
+) ([]*models.StorageProduct, []sqltools.Cursor, error) {
 	storageProducts := make([]*models.StorageProduct, 0)
+
+	var keys []sqltools.Cursor
+
 	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
-		rows, err := query.RunWith(r.Conn(ctx)).QueryContext(ctx)
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
 		if err != nil {
 			return fmt.Errorf("error fetch data from database. %w", err)
 		}
@@ -268,6 +328,8 @@ func (r *repositorySql) fetchStorageProductsWithQuery(
 				productsDistributionReserved  int64
 				productsDistributionAmount    int64
 				productsDistributionAvailable int64
+
+				sortKey string
 			)
 
 			if err = rows.Scan(
@@ -285,10 +347,13 @@ func (r *repositorySql) fetchStorageProductsWithQuery(
 				&productsDistributionAmount,
 				&productsDistributionReserved,
 				&productsDistributionAvailable,
+				&sortKey,
 			); err != nil {
 				return fmt.Errorf("error scan row. %w", err)
 			}
 
+			keys = append(keys, sqltools.Cursor{Value: sortKey, CreatedAt: productCreatedAt, Id: productId})
+
 			storageProducts = append(storageProducts, &models.StorageProduct{
 				ProductInfo: models.ProductInfo{
 					Id:        productId,
@@ -321,21 +386,13 @@ func (r *repositorySql) fetchStorageProductsWithQuery(
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("error execute transactional operation. %w", err)
+		return nil, nil, fmt.Errorf("error execute transactional operation. %w", err)
 	}
 
-	return storageProducts, nil
-}
-
-type buildSelectStorageProductQueryParams struct {
-	productId       uuid.UUID
-	storageId       uuid.UUID
-	withUnavailable bool
-	limit           uint64
-	offset          uint64
+	return storageProducts, keys, nil
 }
 
-func buildSelectStorageProductQuery(params buildSelectStorageProductQueryParams) sq.SelectBuilder {
+func filterStorageProductQuery(params StorageProductsParams) (sq.SelectBuilder, error) {
 	query := sq.Select(
 		// storages
 		"s.id",
@@ -356,39 +413,192 @@ func buildSelectStorageProductQuery(params buildSelectStorageProductQueryParams)
 		"pd.available",
 	).
 		From("products as p").
-		InnerJoin(
-			"products_distribution as pd on pd.product_id = p.id",
-		).
-		InnerJoin("storages as s on pd.storage_id = s.id").
 		PlaceholderFormat(sq.Dollar)
 
-	if !params.withUnavailable {
+	if params.AsOf != nil {
+		query = query.InnerJoin("("+stockAsOf+") as pd on pd.product_id = p.id", *params.AsOf, *params.AsOf)
+	} else {
+		query = query.InnerJoin("products_distribution as pd on pd.product_id = p.id")
+	}
+
+	query = query.InnerJoin("storages as s on pd.storage_id = s.id")
+
+	if !params.WithUnavailable {
 		query = query.Where(sq.Gt{
 			"pd.available": 0,
 		})
 	}
 
-	if params.productId != uuid.Nil {
+	if len(params.Ids) > 0 {
 		query = query.Where(sq.Eq{
-			"p.id": params.productId,
+			"p.id": params.Ids,
 		})
 	}
 
-	if params.storageId != uuid.Nil {
+	if params.StorageId != uuid.Nil {
 		query = query.Where(sq.Eq{
-			"s.id": params.storageId,
+			"s.id": params.StorageId,
 		})
 	}
 
-	if params.limit > 0 && params.limit < 500 {
-		query = query.Limit(params.limit)
-	} else {
-		query = query.Limit(uint64(sqltools.DefaultLimit))
+	query, err := storageProductsFilterSpec.Apply(query, params.Conditions)
+	if err != nil {
+		return query, fmt.Errorf("error apply storage products filter. %w", err)
 	}
 
-	if params.offset > 0 {
-		query = query.Offset(params.offset)
+	return query, nil
+}
+
+func buildSelectStorageProductQuery(params StorageProductsParams) (sq.SelectBuilder, error) {
+	query, err := filterStorageProductQuery(params)
+	if err != nil {
+		return query, err
+	}
+
+	return params.Pagination.Apply(query, storageProductsFilterSpec)
+}
+
+type SearchProductsParams struct {
+	Query      string
+	StorageId  uuid.UUID // If passed, only products available in the storage will be found
+	Pagination sqltools.Pagination
+}
+
+// Search results are ordered by rank, then by the default ordering
+var searchFilterSpec = sqltools.FilterSpec{
+	Fields:    map[string]string{"rank": "s.rank"},
+	CreatedAt: "s.created_at",
+	Id:        "s.id",
+}
+
+// Search products by name. Results are ranked by full-text and trigram similarity
+func (r *repositorySql) SearchProducts(
+	ctx context.Context,
+	params SearchProductsParams,
+) (*models.Page[*models.ProductInfo], error) {
+	// Most relevant products go first
+	params.Pagination.Sort = &sqltools.Sort{Field: "rank", Desc: true}
+
+	products := make([]*models.ProductInfo, 0, params.Pagination.Size())
+
+	var (
+		total *int64
+		keys  []sqltools.Cursor
+	)
+
+	err := sqltools.ReadTransaction(ctx, r.db, r.replica, func(ctx context.Context) error {
+		query, err := buildSearchProductsQuery(params)
+		if err != nil {
+			return fmt.Errorf("error build search products query. %w", err)
+		}
+
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+		if err != nil {
+			return fmt.Errorf("error search products in database. %w", err)
+		}
+
+		defer func() {
+			if closeErr := rows.Close(); closeErr != nil {
+				err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+			}
+		}()
+
+		for rows.Next() {
+			var (
+				id        uuid.UUID
+				name      string
+				size      int64
+				createdAt time.Time
+				updatedAt time.Time
+				sortKey   string
+			)
+
+			if err = rows.Scan(&id, &name, &size, &createdAt, &updatedAt, &sortKey); err != nil {
+				return fmt.Errorf("error scan row. %w", err)
+			}
+
+			keys = append(keys, sqltools.Cursor{Value: sortKey, CreatedAt: createdAt, Id: id})
+
+			products = append(products, &models.ProductInfo{
+				Id:        id,
+				Name:      name,
+				Size:      size,
+				CreatedAt: createdAt,
+				UpdatedAt: updatedAt,
+			})
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error process rows. %w", err)
+		}
+
+		if params.Pagination.WithTotal {
+			count, err := sqltools.Count(ctx, r.Conn(ctx), filterSearchProductsQuery(params))
+			if err != nil {
+				return fmt.Errorf("error count found products. %w", err)
+			}
+
+			total = &count
+		}
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error execute transactional operation. %w", err)
+	}
+
+	page := sqltools.NewPage(params.Pagination, products, keys)
+	page.Total = total
+
+	return page, nil
+}
+
+const (
+	searchDocument = "to_tsvector('simple', coalesce(p.name, ''))"
+	searchName     = "coalesce(p.name, '')"
+)
+
+// filterSearchProductsQuery selects ranked products matching a search query
+func filterSearchProductsQuery(params SearchProductsParams) sq.SelectBuilder {
+	query := sq.Select("p.id", "p.name", "p.size", "p.created_at", "p.updated_at").
+		Column(
+			sq.Expr(
+				fmt.Sprintf(
+					"ts_rank(%s, websearch_to_tsquery('simple', ?)) + word_similarity(?, %s) as rank",
+					searchDocument,
+					searchName,
+				),
+				params.Query,
+				params.Query,
+			),
+		).
+		From("products as p").
+		Where(sq.Or{
+			sq.Expr(fmt.Sprintf("%s @@ websearch_to_tsquery('simple', ?)", searchDocument), params.Query),
+			sq.Expr(fmt.Sprintf("? <%% %s", searchName), params.Query),
+			sq.ILike{searchName: "%" + sqltools.EscapeLike(params.Query) + "%"},
+		}).
+		PlaceholderFormat(sq.Dollar)
+
+	if params.StorageId != uuid.Nil {
+		query = query.Where(
+			sq.Expr(
+				`exists (select 1 from products_distribution as pd
+				where pd.product_id = p.id and pd.storage_id = ? and pd.available > 0)`,
+				params.StorageId,
+			),
+		)
 	}
 
 	return query
 }
+
+// buildSearchProductsQuery pages search results by rank. Rank is computed in a subquery, so pages
+// are keyed by it as by a sort field.
+func buildSearchProductsQuery(params SearchProductsParams) (sq.SelectBuilder, error) {
+	query := sq.Select("s.id", "s.name", "s.size", "s.created_at", "s.updated_at").
+		FromSelect(filterSearchProductsQuery(params), "s").
+		PlaceholderFormat(sq.Dollar)
+
+	return params.Pagination.Apply(query, searchFilterSpec)
+}
@@ -1,8 +1,12 @@
 package reservations
 
 import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/metrics"
 	"cernunnos/internal/pkg/models"
 	"cernunnos/internal/pkg/sqltools"
+	outboxRepo "cernunnos/internal/usecase/repository/outbox"
+	stockRepo "cernunnos/internal/usecase/repository/stock"
 	"context"
 	"database/sql"
 	"errors"
@@ -14,7 +18,7 @@ import (
 )
 
 type Repository interface {
-	Reservations(ctx context.Context, params ReservationsParams) ([]*models.Reservation, error)
+	Reservations(ctx context.Context, params ReservationsParams) (*models.Page[*models.Reservation], error)
 	Reserve(ctx context.Context, params ReserveParams) error
 	// Cancels product reservation. If StorageId is passed, then cancellation will be
 	// performed in a storage specified only. Reserved products will be available for reservation again.
@@ -24,41 +28,59 @@ type Repository interface {
 	Release(ctx context.Context, params ReleaseParams) error
 }
 
-func NewRepository(db *sql.DB) Repository {
-	return &repositorySql{db}
+// NewRepository creates a repository. Listings run on replica, if passed. Reservation changes
+// write domain events to outbox at once, right before they commit, and notify stock listeners
+// on commit.
+func NewRepository(
+	db, replica *sql.DB,
+	outbox outboxRepo.Repository,
+	stock stockRepo.Repository,
+) Repository {
+	return &repositorySql{db, replica, outbox, stock}
 }
 
 type repositorySql struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB // nil if no replica configured
+	outbox  outboxRepo.Repository
+	stock   stockRepo.Repository
 }
 
-type txCtxKey struct{}
-
 func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
-	if tx, ok := ctx.Value(txCtxKey{}).(*sql.Tx); ok {
-		return tx
-	}
+	return sqltools.Conn(ctx, s.db)
+}
 
-	return s.db
+// Reservations are listed in creation order only
+var reservationsFilterSpec = sqltools.FilterSpec{
+	CreatedAt: "r.created_at",
+	Id:        "r.id",
 }
 
 type ReservationsParams struct {
 	ProductId  uuid.UUID
 	StorageId  uuid.UUID
 	ShippingId uuid.UUID
-	Limit      uint64
-	Offset     uint64
+	Pagination sqltools.Pagination
 }
 
 func (r *repositorySql) Reservations(
 	ctx context.Context,
 	params ReservationsParams,
-) ([]*models.Reservation, error) {
+) (*models.Page[*models.Reservation], error) {
 	reservations := make([]*models.Reservation, 0)
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
-		var err error
 
-		rows, err := buildSelectReservationsQuery(params).RunWith(r.Conn(ctx)).QueryContext(ctx)
+	var (
+		total *int64
+		keys  []sqltools.Cursor
+	)
+
+	err := sqltools.ReadTransaction(ctx, r.db, r.replica, func(ctx context.Context) error {
+		query, err := buildSelectReservationsQuery(params)
+		if err != nil {
+			return fmt.Errorf("error build reservations query. %w", err)
+		}
+
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
 		if err != nil {
 			return fmt.Errorf("error fetch reservations data from database. %w", err)
 		}
@@ -71,6 +93,7 @@ func (r *repositorySql) Reservations(
 
 		for rows.Next() {
 			var (
+				reservationId        uuid.UUID
 				shippingId           uuid.UUID
 				reserved             int64
 				reservationCreatedAt time.Time
@@ -92,9 +115,12 @@ func (r *repositorySql) Reservations(
 				productsDistributionReserved  int64
 				productsDistributionAmount    int64
 				productsDistributionAvailable int64
+
+				sortKey string
 			)
 
 			if err = rows.Scan(
+				&reservationId,
 				&shippingId,
 				&reserved,
 				&reservationCreatedAt,
@@ -113,11 +139,15 @@ func (r *repositorySql) Reservations(
 				&productsDistributionReserved,
 				&productsDistributionAmount,
 				&productsDistributionAvailable,
+				&sortKey,
 			); err != nil {
 				return fmt.Errorf("error scan row. %w", err)
 			}
 
+			keys = append(keys, sqltools.Cursor{Value: sortKey, CreatedAt: reservationCreatedAt, Id: reservationId})
+
 			reservations = append(reservations, &models.Reservation{
+				Id:        reservationId,
 				StorageId: storageId,
 				ReservedProduct: &models.StorageProduct{
 					ProductInfo: models.ProductInfo{
@@ -152,6 +182,15 @@ func (r *repositorySql) Reservations(
 			return fmt.Errorf("error process rows. %w", err)
 		}
 
+		if params.Pagination.WithTotal {
+			count, err := sqltools.Count(ctx, r.Conn(ctx), filterReservationsQuery(params))
+			if err != nil {
+				return fmt.Errorf("error count reservations. %w", err)
+			}
+
+			total = &count
+		}
+
 		return nil
 	})
 
@@ -159,12 +198,16 @@ func (r *repositorySql) Reservations(
 		return nil, fmt.Errorf("error execure transactional operation. %w", err)
 	}
 
-	return reservations, nil
+	page := sqltools.NewPage(params.Pagination, reservations, keys)
+	page.Total = total
+
+	return page, nil
 }
 
-func buildSelectReservationsQuery(params ReservationsParams) sq.SelectBuilder {
+func filterReservationsQuery(params ReservationsParams) sq.SelectBuilder {
 	query := sq.Select(
 		// reservations
+		"r.id",
 		"r.shipping_id",
 		"r.reserved",
 		"r.created_at",
@@ -213,19 +256,13 @@ func buildSelectReservationsQuery(params ReservationsParams) sq.SelectBuilder {
 		})
 	}
 
-	if params.Limit > 0 && params.Limit < 500 {
-		query = query.Limit(params.Limit)
-	} else {
-		query = query.Limit(uint64(sqltools.DefaultLimit))
-	}
-
-	if params.Offset > 0 {
-		query = query.Offset(params.Offset)
-	}
-
 	return query
 }
 
+func buildSelectReservationsQuery(params ReservationsParams) (sq.SelectBuilder, error) {
+	return params.Pagination.Apply(filterReservationsQuery(params), reservationsFilterSpec)
+}
+
 type ReserveParams struct {
 	ProductIds uuid.UUIDs
 	StorageId  uuid.UUID
@@ -234,7 +271,9 @@ type ReserveParams struct {
 }
 
 func (r *repositorySql) Reserve(ctx context.Context, params ReserveParams) error {
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
+	err := r.change(ctx, func(ctx context.Context) error {
+		reserved := make(map[uuid.UUID]int64)
+
 		for _, productId := range params.ProductIds {
 			storagesToReserve, err := r.storagesToReserveIn(ctx, storagesToReserveInParams{
 				productId: productId,
@@ -255,12 +294,25 @@ func (r *repositorySql) Reserve(ctx context.Context, params ReserveParams) error
 				if err != nil {
 					return fmt.Errorf("error reserve slots in %s. %w", storageId.String(), err)
 				}
+
+				reserved[storageId] += availableSlots
 			}
 		}
 
+		// Units are counted once changes commit, as the transaction may join an outer one
+		sqltools.AfterCommit(ctx, func() {
+			for storageId, amount := range reserved {
+				metrics.UnitsReserved(storageId.String(), amount)
+			}
+		})
+
 		return nil
 	})
 	if err != nil {
+		if errors.Is(err, ErrorNotEnoughProducts) {
+			metrics.OutOfStockRejected()
+		}
+
 		return fmt.Errorf("error execure transactional operation. %w", err)
 	}
 
@@ -285,9 +337,13 @@ func (r *repositorySql) reserve(ctx context.Context, params reserveParams) error
 				"storage_id": params.storageId,
 				"product_id": params.productId,
 			}).
+			Suffix("returning amount, reserved, available").
 			PlaceholderFormat(sq.Dollar)
 
-		if _, err := updateQuery.RunWith(r.Conn(ctx)).ExecContext(ctx); err != nil {
+		var level dto.StockLevelEvent
+
+		err := sqltools.QueryRow(ctx, r.Conn(ctx), updateQuery, &level.Amount, &level.Reserved, &level.Available)
+		if err != nil {
 			return fmt.Errorf(
 				"error update product %s distribution data at storage %s. %w",
 				params.productId.String(),
@@ -299,6 +355,7 @@ func (r *repositorySql) reserve(ctx context.Context, params reserveParams) error
 		now := time.Now()
 
 		insertQuery := sq.Insert("products_reservations").Columns(
+			"id",
 			"storage_id",
 			"product_id",
 			"shipping_id",
@@ -306,6 +363,7 @@ func (r *repositorySql) reserve(ctx context.Context, params reserveParams) error
 			"created_at",
 			"updated_at",
 		).Values(
+			uuid.New(),
 			params.storageId,
 			params.productId,
 			params.shippingId,
@@ -314,7 +372,7 @@ func (r *repositorySql) reserve(ctx context.Context, params reserveParams) error
 			now,
 		).PlaceholderFormat(sq.Dollar)
 
-		if _, err := insertQuery.RunWith(r.Conn(ctx)).ExecContext(ctx); err != nil {
+		if _, err := sqltools.Exec(ctx, r.Conn(ctx), insertQuery); err != nil {
 			return fmt.Errorf(
 				"error add product %s reservations data for storage %s. %w",
 				params.productId.String(),
@@ -323,6 +381,16 @@ func (r *repositorySql) reserve(ctx context.Context, params reserveParams) error
 			)
 		}
 
+		err = r.collectEvents(ctx, models.EventReservationCreated, reservationEvent{
+			productId:  params.productId,
+			storageId:  params.storageId,
+			shippingId: params.shippingId,
+			amount:     params.amount,
+		}, level)
+		if err != nil {
+			return fmt.Errorf("error add reservation events. %w", err)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -367,7 +435,7 @@ func (r *repositorySql) storagesToReserveIn(
 			})
 		}
 
-		rows, err := isSpaceAvailableQuery.RunWith(r.Conn(ctx)).QueryContext(ctx)
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), isSpaceAvailableQuery)
 		if err != nil {
 			return fmt.Errorf("error fetch available storages from database. %w", err)
 		}
@@ -428,7 +496,9 @@ type CancelParams struct {
 }
 
 func (r *repositorySql) Cancel(ctx context.Context, params CancelParams) error {
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
+	err := r.change(ctx, func(ctx context.Context) error {
+		freed := make(map[uuid.UUID]int64)
+
 		for _, productId := range params.ProductIds {
 			reservations, err := r.reservedByStorage(ctx, reservedByStorageParams{
 				storageId:  params.StorageId,
@@ -451,9 +521,18 @@ func (r *repositorySql) Cancel(ctx context.Context, params CancelParams) error {
 						"error cancel product reservation at %s. %w", storage.String(), err,
 					)
 				}
+
+				freed[storage] += reserved
 			}
 		}
 
+		// Units are counted once changes commit, as the transaction may join an outer one
+		sqltools.AfterCommit(ctx, func() {
+			for storageId, amount := range freed {
+				metrics.UnitsCancelled(storageId.String(), amount)
+			}
+		})
+
 		return nil
 	})
 	if err != nil {
@@ -470,7 +549,9 @@ type ReleaseParams struct {
 }
 
 func (r *repositorySql) Release(ctx context.Context, params ReleaseParams) error {
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
+	err := r.change(ctx, func(ctx context.Context) error {
+		freed := make(map[uuid.UUID]int64)
+
 		for _, productId := range params.ProductIds {
 			reservations, err := r.reservedByStorage(ctx, reservedByStorageParams{
 				storageId:  params.StorageId,
@@ -494,9 +575,18 @@ func (r *repositorySql) Release(ctx context.Context, params ReleaseParams) error
 						"error release product reservation at %s. %w", storage.String(), err,
 					)
 				}
+
+				freed[storage] += reserved
 			}
 		}
 
+		// Units are counted once changes commit, as the transaction may join an outer one
+		sqltools.AfterCommit(ctx, func() {
+			for storageId, amount := range freed {
+				metrics.UnitsReleased(storageId.String(), amount)
+			}
+		})
+
 		return nil
 	})
 	if err != nil {
@@ -534,7 +624,7 @@ func (r *repositorySql) reservedByStorage(
 			})
 		}
 
-		rows, err := selectReservations.RunWith(r.Conn(ctx)).QueryContext(ctx)
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), selectReservations)
 		if err != nil {
 			return fmt.Errorf("error fetch reservations from database. %w", err)
 		}
@@ -599,7 +689,7 @@ func (r *repositorySql) freeReservation(ctx context.Context, params cancelReserv
 				}).
 				PlaceholderFormat(sq.Dollar)
 
-			if _, err := updateStorage.RunWith(r.Conn(ctx)).ExecContext(ctx); err != nil {
+			if _, err := sqltools.Exec(ctx, r.Conn(ctx), updateStorage); err != nil {
 				return fmt.Errorf("error update storage availability status. %w", err)
 			}
 		}
@@ -610,7 +700,12 @@ func (r *repositorySql) freeReservation(ctx context.Context, params cancelReserv
 			query = query.Set("amount", sq.Expr("amount - ?", params.amount))
 		}
 
-		if _, err := query.RunWith(r.Conn(ctx)).ExecContext(ctx); err != nil {
+		var level dto.StockLevelEvent
+
+		query = query.Suffix("returning amount, reserved, available")
+
+		err := sqltools.QueryRow(ctx, r.Conn(ctx), query, &level.Amount, &level.Reserved, &level.Available)
+		if err != nil {
 			return fmt.Errorf("error update amount of an available items. %w", err)
 		}
 
@@ -620,10 +715,25 @@ func (r *repositorySql) freeReservation(ctx context.Context, params cancelReserv
 				"shipping_id": params.shippingId,
 				"storage_id":  params.storageId,
 			}).PlaceholderFormat(sq.Dollar)
-		if _, err := delete.RunWith(r.Conn(ctx)).ExecContext(ctx); err != nil {
+		if _, err := sqltools.Exec(ctx, r.Conn(ctx), delete); err != nil {
 			return fmt.Errorf("error delete reservation. %w", err)
 		}
 
+		eventType := models.EventReservationCancelled
+		if params.writeOff {
+			eventType = models.EventReservationReleased
+		}
+
+		err = r.collectEvents(ctx, eventType, reservationEvent{
+			productId:  params.productId,
+			storageId:  params.storageId,
+			shippingId: params.shippingId,
+			amount:     params.amount,
+		}, level)
+		if err != nil {
+			return fmt.Errorf("error add reservation events. %w", err)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -632,3 +742,103 @@ func (r *repositorySql) freeReservation(ctx context.Context, params cancelReserv
 
 	return nil
 }
+
+type reservationEvent struct {
+	productId  uuid.UUID
+	storageId  uuid.UUID
+	shippingId uuid.UUID
+	amount     int64
+}
+
+// changedEvents collects events of a reservation change. They are written to outbox right before
+// the change commits, as adding events blocks other transactions adding them until it ends.
+type changedEvents struct {
+	events []*models.Event
+	levels []*models.Event // Stock level changes to notify listeners of
+}
+
+type changedEventsCtxKey struct{}
+
+// change runs fn in a transaction and adds events collected by fn at once. Changes made within
+// another change add their events with the outer one.
+func (r *repositorySql) change(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(changedEventsCtxKey{}).(*changedEvents); ok {
+		return sqltools.Transaction(ctx, r.db, fn)
+	}
+
+	return sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
+		changed := new(changedEvents)
+
+		if err := fn(context.WithValue(ctx, changedEventsCtxKey{}, changed)); err != nil {
+			return err
+		}
+
+		return r.addEvents(ctx, changed)
+	})
+}
+
+// collectEvents collects a reservation event and the stock level change it caused. StockDepleted
+// is added, if the reservation took the last available products. Events of changes made outside
+// of change are added right away.
+func (r *repositorySql) collectEvents(
+	ctx context.Context,
+	eventType string,
+	reservation reservationEvent,
+	level dto.StockLevelEvent,
+) error {
+	reservationChanged, err := models.NewEvent(eventType, dto.ReservationEvent{
+		ShippingId: reservation.shippingId.String(),
+		ProductId:  reservation.productId.String(),
+		StorageId:  reservation.storageId.String(),
+		Amount:     reservation.amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	level.ProductId = reservation.productId.String()
+	level.StorageId = reservation.storageId.String()
+
+	levelChanged, err := models.NewEvent(models.EventStockLevelChanged, level)
+	if err != nil {
+		return err
+	}
+
+	collected := &changedEvents{
+		events: []*models.Event{reservationChanged, levelChanged},
+		levels: []*models.Event{levelChanged},
+	}
+
+	if level.Available == 0 && eventType == models.EventReservationCreated {
+		depleted, err := models.NewEvent(models.EventStockDepleted, level)
+		if err != nil {
+			return err
+		}
+
+		collected.events = append(collected.events, depleted)
+	}
+
+	if changed, ok := ctx.Value(changedEventsCtxKey{}).(*changedEvents); ok {
+		changed.events = append(changed.events, collected.events...)
+		changed.levels = append(changed.levels, collected.levels...)
+
+		return nil
+	}
+
+	return r.addEvents(ctx, collected)
+}
+
+// addEvents writes collected events to outbox and notifies stock listeners of level changes
+func (r *repositorySql) addEvents(ctx context.Context, changed *changedEvents) error {
+	if err := r.outbox.Add(ctx, changed.events...); err != nil {
+		return fmt.Errorf("error add events to outbox. %w", err)
+	}
+
+	for _, level := range changed.levels {
+		if err := r.stock.Notify(ctx, level); err != nil {
+			return fmt.Errorf("error notify stock level change. %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package stock
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// channel carries stock level changes between instances
+const channel = "stock_changes"
+
+// Listener reconnection and liveness check intervals
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+	pingInterval         = time.Minute
+)
+
+// Stock changes repository
+type Repository interface {
+	// Notifies listeners of a stock level changed event when the transaction carried by ctx commits.
+	// The event must be added to outbox, so it has a position.
+	Notify(ctx context.Context, event *models.Event) error
+	// Passes notified events to handle until ctx is done. Events notified while connection is lost
+	// are missed, so nil event is passed after every reconnection.
+	Listen(ctx context.Context, handle func(ctx context.Context, event *models.Event)) error
+}
+
+// NewRepository creates a repository. Listening takes a dedicated connection to dsn.
+func NewRepository(db *sql.DB, dsn string) Repository {
+	return &repositorySql{db, dsn}
+}
+
+type repositorySql struct {
+	db  *sql.DB
+	dsn string
+}
+
+func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
+	return sqltools.Conn(ctx, s.db)
+}
+
+// notification is a payload of stock_changes notifications. Payloads are limited with 8000 bytes,
+// and event data is way smaller.
+type notification struct {
+	Position  int64           `json:"position"`
+	Id        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (r *repositorySql) Notify(ctx context.Context, event *models.Event) error {
+	payload, err := json.Marshal(notification{
+		Position:  event.Position,
+		Id:        event.Id,
+		Type:      event.Type,
+		Payload:   event.Payload,
+		CreatedAt: event.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshal notification. %w", err)
+	}
+
+	query := sq.Select().
+		Column(sq.Expr("pg_notify(?, ?)", channel, string(payload))).
+		PlaceholderFormat(sq.Dollar)
+
+	if _, err = sqltools.Exec(ctx, r.Conn(ctx), query); err != nil {
+		return fmt.Errorf("error notify stock change. %w", err)
+	}
+
+	return nil
+}
+
+func (r *repositorySql) Listen(ctx context.Context, handle func(ctx context.Context, event *models.Event)) error {
+	listener := pq.NewListener(r.dsn, minReconnectInterval, maxReconnectInterval, nil)
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		return fmt.Errorf("error listen to %s. %w", channel, err)
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			// Detects a silently dropped connection, so it is reestablished
+			_ = listener.Ping()
+		case received := <-listener.Notify:
+			if received == nil {
+				handle(ctx, nil)
+
+				continue
+			}
+
+			var payload notification
+
+			// Foreign payloads are skipped, as notifying the channel is not limited to Notify
+			if err := json.Unmarshal([]byte(received.Extra), &payload); err != nil {
+				continue
+			}
+
+			handle(ctx, &models.Event{
+				Position:  payload.Position,
+				Id:        payload.Id,
+				Type:      payload.Type,
+				Payload:   payload.Payload,
+				CreatedAt: payload.CreatedAt,
+			})
+		}
+	}
+}
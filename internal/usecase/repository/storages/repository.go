@@ -13,46 +13,68 @@ import (
 	"github.com/google/uuid"
 )
 
+// Fields available for storages filtering and sorting
+var storagesFilterSpec = sqltools.FilterSpec{
+	Fields: map[string]string{
+		"name":       "coalesce(name, '')",
+		"available":  "available",
+		"reserved":   "reserved",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	CreatedAt: "created_at",
+	Id:        "id",
+}
+
 // Filter
 type StoragesParams struct {
-	Ids    []uuid.UUID // If passed, only listed storages will be fetched
-	Limit  uint64      // Limit. Max: 500
-	Offset uint64      // Offset
+	Ids        []uuid.UUID          // If passed, only listed storages will be fetched
+	Conditions []sqltools.Condition // Over storagesFilterSpec fields
+	Pagination sqltools.Pagination  // Cursor or offset, limit and sort. Max limit: 500
 }
 
 // Storages repository
 type Repository interface {
 	// Fetch storages by filter
-	Storages(ctx context.Context, params StoragesParams) ([]*models.Storage, error)
+	Storages(ctx context.Context, params StoragesParams) (*models.Page[*models.Storage], error)
 }
 
-func NewRepository(db *sql.DB) Repository {
-	return &repositorySql{db}
+// NewRepository creates a repository. Listings run on replica, if passed
+func NewRepository(db, replica *sql.DB) Repository {
+	return &repositorySql{db, replica}
 }
 
 type repositorySql struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB // nil if no replica configured
 }
 
-type txCtxKey struct{}
-
 func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
-	if tx, ok := ctx.Value(txCtxKey{}).(*sql.Tx); ok {
-		return tx
-	}
-
-	return s.db
+	return sqltools.Conn(ctx, s.db)
 }
 
-func (r *repositorySql) Storages(ctx context.Context, params StoragesParams) ([]*models.Storage, error) {
+func (r *repositorySql) Storages(
+	ctx context.Context,
+	params StoragesParams,
+) (*models.Page[*models.Storage], error) {
 	storages := make([]*models.Storage, 0, len(params.Ids))
 
-	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
-		var err error
+	var (
+		total *int64
+		keys  []sqltools.Cursor
+	)
+
+	err := sqltools.ReadTransaction(ctx, r.db, r.replica, func(ctx context.Context) error {
+		query, err := buildStoragesQuery(params)
+		if err != nil {
+			return fmt.Errorf("error build storages query. %w", err)
+		}
 
-		query := buildStoragesQuery(params)
+		if !sqltools.OnReplica(ctx) {
+			query = query.Suffix("for update")
+		}
 
-		rows, err := query.RunWith(r.Conn(ctx)).QueryContext(ctx)
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
 		if err != nil {
 			return fmt.Errorf("error fetch rows from database. %w", err)
 		}
@@ -71,12 +93,15 @@ func (r *repositorySql) Storages(ctx context.Context, params StoragesParams) ([]
 				reserved  int64
 				createdAt time.Time
 				updatedAt time.Time
+				sortKey   string
 			)
 
-			if err := rows.Scan(&id, &name, &available, &reserved, &createdAt, &updatedAt); err != nil {
+			if err := rows.Scan(&id, &name, &available, &reserved, &createdAt, &updatedAt, &sortKey); err != nil {
 				return fmt.Errorf("error scan rows. %w", err)
 			}
 
+			keys = append(keys, sqltools.Cursor{Value: sortKey, CreatedAt: createdAt, Id: id})
+
 			storages = append(storages, &models.Storage{
 				Id:        id,
 				Name:      name,
@@ -91,26 +116,37 @@ func (r *repositorySql) Storages(ctx context.Context, params StoragesParams) ([]
 			return fmt.Errorf("error process rows. %w", err)
 		}
 
+		if params.Pagination.WithTotal {
+			filterQuery, err := filterStoragesQuery(params)
+			if err != nil {
+				return fmt.Errorf("error build storages filter query. %w", err)
+			}
+
+			count, err := sqltools.Count(ctx, r.Conn(ctx), filterQuery)
+			if err != nil {
+				return fmt.Errorf("error count storages. %w", err)
+			}
+
+			total = &count
+		}
+
 		return nil
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error execute transactional operation. %w", err)
 	}
 
-	return storages, nil
+	page := sqltools.NewPage(params.Pagination, storages, keys)
+	page.Total = total
+
+	return page, nil
 }
 
-func buildStoragesQuery(params StoragesParams) sq.SelectBuilder {
+func filterStoragesQuery(params StoragesParams) (sq.SelectBuilder, error) {
 	selectQuery := sq.Select("id", "name", "available", "reserved", "created_at", "updated_at").
 		From("storages").
-		Limit(uint64(sqltools.DefaultLimit)).
-		Suffix("for update").
 		PlaceholderFormat(sq.Dollar)
 
-	if params.Limit != 0 && params.Limit < uint64(sqltools.DefaultLimit) {
-		selectQuery = selectQuery.Limit(params.Limit)
-	}
-
 	if len(params.Ids) > 0 {
 		selectQuery = selectQuery.Where(
 			sq.Eq{
@@ -119,5 +155,19 @@ func buildStoragesQuery(params StoragesParams) sq.SelectBuilder {
 		)
 	}
 
-	return selectQuery
+	selectQuery, err := storagesFilterSpec.Apply(selectQuery, params.Conditions)
+	if err != nil {
+		return selectQuery, fmt.Errorf("error apply storages filter. %w", err)
+	}
+
+	return selectQuery, nil
+}
+
+func buildStoragesQuery(params StoragesParams) (sq.SelectBuilder, error) {
+	selectQuery, err := filterStoragesQuery(params)
+	if err != nil {
+		return selectQuery, err
+	}
+
+	return params.Pagination.Apply(selectQuery, storagesFilterSpec)
 }
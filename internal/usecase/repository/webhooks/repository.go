@@ -0,0 +1,457 @@
+package webhooks
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrorSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrorDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+// Fields available for deliveries sorting
+var deliveriesFilterSpec = sqltools.FilterSpec{
+	Fields: map[string]string{
+		"created_at":      "d.created_at",
+		"updated_at":      "d.updated_at",
+		"next_attempt_at": "d.next_attempt_at",
+	},
+	CreatedAt: "d.created_at",
+	Id:        "d.id",
+}
+
+// Webhooks repository
+type Repository interface {
+	Create(ctx context.Context, params CreateParams) (*models.WebhookSubscription, error)
+	Subscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	// Deletes a subscription with its deliveries
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Adds pending deliveries of an event to every matching subscription. An event is enqueued to
+	// a subscription once, so enqueuing a redelivered event does nothing.
+	Enqueue(ctx context.Context, params EnqueueParams) (int64, error)
+	// Passes up to limit due pending deliveries to attempt and stores the outcomes attempt sets.
+	// Deliveries are locked while attempted, so concurrent senders attempt different ones.
+	Attempt(
+		ctx context.Context,
+		limit uint64,
+		attempt func(ctx context.Context, delivery *models.WebhookDelivery) error,
+	) (int, error)
+	// Fetch deliveries log of a subscription
+	Deliveries(ctx context.Context, params DeliveriesParams) (*models.Page[*models.WebhookDelivery], error)
+	// Makes a delivery pending again with attempts reset
+	Replay(ctx context.Context, subscriptionId, deliveryId uuid.UUID) error
+}
+
+func NewRepository(db *sql.DB) Repository {
+	return &repositorySql{db}
+}
+
+type repositorySql struct {
+	db *sql.DB
+}
+
+func (s *repositorySql) Conn(ctx context.Context) sqltools.DBTX {
+	return sqltools.Conn(ctx, s.db)
+}
+
+type CreateParams struct {
+	URL        string
+	Secret     string
+	EventTypes []string
+	ProductId  uuid.UUID // uuid.Nil matches any product
+	StorageId  uuid.UUID // uuid.Nil matches any storage
+}
+
+func (r *repositorySql) Create(ctx context.Context, params CreateParams) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{
+		Id:         uuid.New(),
+		URL:        params.URL,
+		Secret:     params.Secret,
+		EventTypes: params.EventTypes,
+		ProductId:  params.ProductId,
+		StorageId:  params.StorageId,
+		CreatedAt:  time.Now(),
+	}
+
+	query := sq.Insert("webhook_subscriptions").
+		Columns("id", "url", "secret", "event_types", "product_id", "storage_id", "created_at").
+		Values(
+			subscription.Id,
+			subscription.URL,
+			subscription.Secret,
+			pq.Array(subscription.EventTypes),
+			nullUUID(subscription.ProductId),
+			nullUUID(subscription.StorageId),
+			subscription.CreatedAt,
+		).
+		PlaceholderFormat(sq.Dollar)
+
+	if _, err := sqltools.Exec(ctx, r.Conn(ctx), query); err != nil {
+		return nil, fmt.Errorf("error insert webhook subscription. %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (r *repositorySql) Subscriptions(ctx context.Context) (subscriptions []*models.WebhookSubscription, err error) {
+	query := sq.Select("id", "url", "secret", "event_types", "product_id", "storage_id", "created_at").
+		From("webhook_subscriptions").
+		OrderBy("created_at", "id").
+		PlaceholderFormat(sq.Dollar)
+
+	rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return nil, fmt.Errorf("error fetch webhook subscriptions. %w", err)
+	}
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			subscription models.WebhookSubscription
+			productId    uuid.NullUUID
+			storageId    uuid.NullUUID
+		)
+
+		err := rows.Scan(
+			&subscription.Id,
+			&subscription.URL,
+			&subscription.Secret,
+			pq.Array(&subscription.EventTypes),
+			&productId,
+			&storageId,
+			&subscription.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scan row. %w", err)
+		}
+
+		subscription.ProductId, subscription.StorageId = productId.UUID, storageId.UUID
+
+		subscriptions = append(subscriptions, &subscription)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error process rows. %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *repositorySql) Delete(ctx context.Context, id uuid.UUID) error {
+	query := sq.Delete("webhook_subscriptions").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar)
+
+	result, err := sqltools.Exec(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return fmt.Errorf("error delete webhook subscription. %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("error webhook subscription %s. %w", id.String(), ErrorSubscriptionNotFound)
+	}
+
+	return nil
+}
+
+type EnqueueParams struct {
+	EventId   uuid.UUID
+	EventType string
+	ProductId uuid.UUID // uuid.Nil if the event is not related to a product
+	StorageId uuid.UUID // uuid.Nil if the event is not related to a storage
+	Payload   []byte
+}
+
+func (r *repositorySql) Enqueue(ctx context.Context, params EnqueueParams) (int64, error) {
+	now := time.Now()
+
+	matching := sq.Select("gen_random_uuid()", "s.id").
+		Column(sq.Expr("?::uuid", params.EventId)).
+		Column(sq.Expr("?::varchar", params.EventType)).
+		Column(sq.Expr("?::jsonb", string(params.Payload))).
+		Column(sq.Expr("?::varchar", models.DeliveryPending)).
+		Column(sq.Expr("?::timestamp", now)).
+		From("webhook_subscriptions as s").
+		Where(sq.Or{
+			sq.Expr("cardinality(s.event_types) = 0"),
+			sq.Expr("?::text = any(s.event_types)", params.EventType),
+		}).
+		Where(sq.Or{
+			sq.Eq{"s.product_id": nil},
+			sq.Expr("s.product_id = ?", nullUUID(params.ProductId)),
+		}).
+		Where(sq.Or{
+			sq.Eq{"s.storage_id": nil},
+			sq.Expr("s.storage_id = ?", nullUUID(params.StorageId)),
+		})
+
+	query := sq.Insert("webhook_deliveries").
+		Columns("id", "subscription_id", "event_id", "event_type", "payload", "status", "next_attempt_at").
+		Select(matching).
+		Suffix("on conflict (subscription_id, event_id) do nothing").
+		PlaceholderFormat(sq.Dollar)
+
+	result, err := sqltools.Exec(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return 0, fmt.Errorf("error insert webhook deliveries. %w", err)
+	}
+
+	enqueued, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error count enqueued deliveries. %w", err)
+	}
+
+	return enqueued, nil
+}
+
+func selectDeliveriesQuery() sq.SelectBuilder {
+	return sq.Select(
+		"d.id",
+		"d.subscription_id",
+		"s.url",
+		"s.secret",
+		"d.event_id",
+		"d.event_type",
+		"d.payload",
+		"d.status",
+		"d.attempts",
+		"d.next_attempt_at",
+		"coalesce(d.last_status_code, 0)",
+		"coalesce(d.last_error, '')",
+		"d.created_at",
+		"d.updated_at",
+		"d.delivered_at",
+	).
+		From("webhook_deliveries as d").
+		InnerJoin("webhook_subscriptions as s on s.id = d.subscription_id").
+		PlaceholderFormat(sq.Dollar)
+}
+
+func (r *repositorySql) Attempt(
+	ctx context.Context,
+	limit uint64,
+	attempt func(ctx context.Context, delivery *models.WebhookDelivery) error,
+) (int, error) {
+	var attempted int
+
+	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
+		query := selectDeliveriesQuery().
+			Where(sq.Eq{"d.status": models.DeliveryPending}).
+			Where(sq.LtOrEq{"d.next_attempt_at": time.Now()}).
+			OrderBy("d.next_attempt_at", "d.created_at").
+			Limit(limit).
+			Suffix("for update of d skip locked")
+
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+		if err != nil {
+			return fmt.Errorf("error fetch due deliveries. %w", err)
+		}
+
+		deliveries, err := scanDeliveries(
+			rows,
+			func(rows *sqltools.Rows, _ *models.WebhookDelivery, fields ...any) error {
+				return rows.Scan(fields...)
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, delivery := range deliveries {
+			if err = attempt(ctx, delivery); err != nil {
+				return fmt.Errorf("error attempt delivery %s. %w", delivery.Id.String(), err)
+			}
+
+			if err = r.saveAttempt(ctx, delivery); err != nil {
+				return err
+			}
+
+			attempted++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error execute transactional operation. %w", err)
+	}
+
+	return attempted, nil
+}
+
+func (r *repositorySql) saveAttempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := sq.Update("webhook_deliveries").
+		SetMap(sq.Eq{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt,
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"delivered_at":     delivery.DeliveredAt,
+			"updated_at":       time.Now(),
+		}).
+		Where(sq.Eq{"id": delivery.Id}).
+		PlaceholderFormat(sq.Dollar)
+
+	if _, err := sqltools.Exec(ctx, r.Conn(ctx), query); err != nil {
+		return fmt.Errorf("error save delivery %s attempt. %w", delivery.Id.String(), err)
+	}
+
+	return nil
+}
+
+type DeliveriesParams struct {
+	SubscriptionId uuid.UUID
+	Status         string // If passed, only deliveries in the status are fetched
+	Pagination     sqltools.Pagination
+}
+
+func (r *repositorySql) Deliveries(
+	ctx context.Context,
+	params DeliveriesParams,
+) (*models.Page[*models.WebhookDelivery], error) {
+	var (
+		deliveries []*models.WebhookDelivery
+		keys       []sqltools.Cursor
+	)
+
+	err := sqltools.Transaction(ctx, r.db, func(ctx context.Context) error {
+		query := selectDeliveriesQuery().Where(sq.Eq{"d.subscription_id": params.SubscriptionId})
+
+		if params.Status != "" {
+			query = query.Where(sq.Eq{"d.status": params.Status})
+		}
+
+		query, err := params.Pagination.Apply(query, deliveriesFilterSpec)
+		if err != nil {
+			return fmt.Errorf("error build deliveries query. %w", err)
+		}
+
+		rows, err := sqltools.Query(ctx, r.Conn(ctx), query)
+		if err != nil {
+			return fmt.Errorf("error fetch deliveries. %w", err)
+		}
+
+		deliveries, err = scanDeliveries(
+			rows,
+			func(rows *sqltools.Rows, delivery *models.WebhookDelivery, fields ...any) error {
+				var sortKey string
+
+				if err := rows.Scan(append(fields, &sortKey)...); err != nil {
+					return err
+				}
+
+				keys = append(keys, sqltools.Cursor{Value: sortKey, CreatedAt: delivery.CreatedAt, Id: delivery.Id})
+
+				return nil
+			},
+		)
+
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error execute transactional operation. %w", err)
+	}
+
+	return sqltools.NewPage(params.Pagination, deliveries, keys), nil
+}
+
+func (r *repositorySql) Replay(ctx context.Context, subscriptionId, deliveryId uuid.UUID) error {
+	query := sq.Update("webhook_deliveries").
+		SetMap(sq.Eq{
+			"status":          models.DeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"delivered_at":    nil,
+			"updated_at":      time.Now(),
+		}).
+		Where(sq.Eq{
+			"id":              deliveryId,
+			"subscription_id": subscriptionId,
+		}).
+		PlaceholderFormat(sq.Dollar)
+
+	result, err := sqltools.Exec(ctx, r.Conn(ctx), query)
+	if err != nil {
+		return fmt.Errorf("error replay delivery. %w", err)
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("error delivery %s. %w", deliveryId.String(), ErrorDeliveryNotFound)
+	}
+
+	return nil
+}
+
+// scanDeliveries scans rows of selectDeliveriesQuery. scan receives the delivery and its fields and
+// may scan extra columns after them.
+func scanDeliveries(
+	rows *sqltools.Rows,
+	scan func(rows *sqltools.Rows, delivery *models.WebhookDelivery, fields ...any) error,
+) (deliveries []*models.WebhookDelivery, err error) {
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			err = errors.Join(fmt.Errorf("error close rows. %w", closeErr), err)
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			delivery    models.WebhookDelivery
+			deliveredAt sql.NullTime
+		)
+
+		err := scan(
+			rows,
+			&delivery,
+			&delivery.Id,
+			&delivery.SubscriptionId,
+			&delivery.URL,
+			&delivery.Secret,
+			&delivery.EventId,
+			&delivery.EventType,
+			&delivery.Payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttemptAt,
+			&delivery.LastStatusCode,
+			&delivery.LastError,
+			&delivery.CreatedAt,
+			&delivery.UpdatedAt,
+			&deliveredAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scan row. %w", err)
+		}
+
+		if deliveredAt.Valid {
+			delivery.DeliveredAt = &deliveredAt.Time
+		}
+
+		deliveries = append(deliveries, &delivery)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error process rows. %w", err)
+	}
+
+	return deliveries, nil
+}
+
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
@@ -0,0 +1,54 @@
+package workers
+
+import (
+	"cernunnos/internal/pkg/logger"
+	idempotencyRepo "cernunnos/internal/usecase/repository/idempotency"
+	"context"
+	"log/slog"
+	"time"
+)
+
+const idempotencyPurgeInterval = time.Hour
+
+// IdempotencyPurger drops expired idempotency keys. Expired keys are never replayed, so purging
+// only keeps the table small.
+type IdempotencyPurger struct {
+	log        *slog.Logger
+	repository idempotencyRepo.Repository
+	ttl        time.Duration
+}
+
+func NewIdempotencyPurger(
+	log *slog.Logger,
+	repository idempotencyRepo.Repository,
+	ttl time.Duration,
+) *IdempotencyPurger {
+	return &IdempotencyPurger{
+		log:        log.WithGroup("idempotency_purger"),
+		repository: repository,
+		ttl:        ttl,
+	}
+}
+
+// Run purges expired keys hourly until ctx is done
+func (p *IdempotencyPurger) Run(ctx context.Context) error {
+	ticker := time.NewTicker(idempotencyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		purged, err := p.repository.Purge(ctx, time.Now().UTC().Add(-p.ttl))
+		if err != nil && ctx.Err() == nil {
+			p.log.ErrorContext(ctx, "error purge idempotency keys", logger.Err(err))
+		}
+
+		if purged > 0 {
+			p.log.DebugContext(ctx, "idempotency keys purged", slog.Int64("amount", purged))
+		}
+	}
+}
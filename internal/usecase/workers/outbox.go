@@ -0,0 +1,88 @@
+package workers
+
+import (
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/publisher"
+	outboxRepo "cernunnos/internal/usecase/repository/outbox"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// OutboxRelay publishes domain events written to outbox in order. A failed event is retried on the
+// next poll, events after it wait for it.
+type OutboxRelay struct {
+	log          *slog.Logger
+	repository   outboxRepo.Repository
+	publisher    publisher.Publisher
+	pollInterval time.Duration
+	batchSize    uint64
+}
+
+func NewOutboxRelay(
+	log *slog.Logger,
+	repository outboxRepo.Repository,
+	publisher publisher.Publisher,
+	pollInterval time.Duration,
+	batchSize int,
+) *OutboxRelay {
+	return &OutboxRelay{
+		log:          log.WithGroup("outbox_relay"),
+		repository:   repository,
+		publisher:    publisher,
+		pollInterval: pollInterval,
+		batchSize:    uint64(batchSize),
+	}
+}
+
+// Run polls outbox until ctx is done
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.relay(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// relay publishes batches until outbox is drained or publishing fails
+func (r *OutboxRelay) relay(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.repository.Publish(ctx, r.batchSize, r.publish)
+		if err != nil && ctx.Err() == nil {
+			r.log.ErrorContext(ctx, "error relay outbox events", logger.Err(err))
+		}
+
+		if err != nil {
+			return
+		}
+
+		if published > 0 {
+			r.log.DebugContext(ctx, "outbox events published", slog.Int("amount", published))
+		}
+
+		if uint64(published) < r.batchSize {
+			return
+		}
+	}
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, event *models.Event) error {
+	if err := r.publisher.Publish(ctx, event); err != nil {
+		metrics.EventPublishFailed(event.Type)
+
+		return err
+	}
+
+	metrics.EventPublished(event.Type)
+
+	return nil
+}
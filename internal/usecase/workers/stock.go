@@ -0,0 +1,98 @@
+package workers
+
+import (
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/usecase/interactors"
+	outboxRepo "cernunnos/internal/usecase/repository/outbox"
+	stockRepo "cernunnos/internal/usecase/repository/stock"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Changes fetched from outbox at once after reconnection
+const stockResyncBatch = 500
+
+// StockListener broadcasts stock level changes notified by any instance to stream subscribers of
+// this one. Changes missed while the listening connection was lost are read from outbox. Outbox
+// positions follow commit order, so changes after the last broadcast position are all the missed
+// ones.
+type StockListener struct {
+	log         *slog.Logger
+	repository  stockRepo.Repository
+	outbox      outboxRepo.Repository
+	interactor  interactors.StockInteractor
+	lastChanged int64 // Position of the last broadcast change
+}
+
+func NewStockListener(
+	log *slog.Logger,
+	repository stockRepo.Repository,
+	outbox outboxRepo.Repository,
+	interactor interactors.StockInteractor,
+) *StockListener {
+	return &StockListener{
+		log:        log.WithGroup("stock_listener"),
+		repository: repository,
+		outbox:     outbox,
+		interactor: interactor,
+	}
+}
+
+// Run listens to stock changes until ctx is done
+func (l *StockListener) Run(ctx context.Context) error {
+	if err := l.repository.Listen(ctx, l.handle); err != nil {
+		return fmt.Errorf("error listen to stock changes. %w", err)
+	}
+
+	return nil
+}
+
+func (l *StockListener) handle(ctx context.Context, event *models.Event) {
+	if event == nil {
+		l.resync(ctx)
+
+		return
+	}
+
+	// Changes committed while resyncing are both read from outbox and notified
+	if event.Position <= l.lastChanged {
+		return
+	}
+
+	l.broadcast(ctx, event)
+}
+
+// resync broadcasts changes after the last broadcast one. Nothing was broadcast before the first
+// connection, so nothing is missed until then.
+func (l *StockListener) resync(ctx context.Context) {
+	for l.lastChanged > 0 {
+		events, err := l.outbox.Events(ctx, outboxRepo.EventsParams{
+			Type:  models.EventStockLevelChanged,
+			After: l.lastChanged,
+			Limit: stockResyncBatch,
+		})
+		if err != nil {
+			l.log.ErrorContext(ctx, "error fetch missed stock changes", logger.Err(err))
+
+			return
+		}
+
+		for _, event := range events {
+			l.broadcast(ctx, event)
+		}
+
+		if len(events) < stockResyncBatch {
+			return
+		}
+	}
+}
+
+func (l *StockListener) broadcast(ctx context.Context, event *models.Event) {
+	if err := l.interactor.Broadcast(ctx, event); err != nil {
+		l.log.ErrorContext(ctx, "error broadcast stock change", logger.Err(err))
+	}
+
+	l.lastChanged = max(l.lastChanged, event.Position)
+}
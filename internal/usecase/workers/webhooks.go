@@ -0,0 +1,169 @@
+package workers
+
+import (
+	"bytes"
+	"cernunnos/internal/pkg/logger"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/publisher"
+	webhooksRepo "cernunnos/internal/usecase/repository/webhooks"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WebhookSenderOptions tune delivery retries
+type WebhookSenderOptions struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int           // A delivery is dead after this amount of failed attempts
+	BackoffBase  time.Duration // Delay after the first failed attempt. Doubled after every next one
+	BackoffMax   time.Duration
+}
+
+// WebhookSender delivers enqueued webhook deliveries. Failed deliveries are retried with
+// exponential backoff until attempts are exhausted.
+type WebhookSender struct {
+	log        *slog.Logger
+	repository webhooksRepo.Repository
+	client     *http.Client
+	options    WebhookSenderOptions
+}
+
+func NewWebhookSender(
+	log *slog.Logger,
+	repository webhooksRepo.Repository,
+	client *http.Client,
+	options WebhookSenderOptions,
+) *WebhookSender {
+	return &WebhookSender{
+		log:        log.WithGroup("webhook_sender"),
+		repository: repository,
+		client:     client,
+		options:    options,
+	}
+}
+
+// Run polls due deliveries until ctx is done
+func (s *WebhookSender) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *WebhookSender) sendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		attempted, err := s.repository.Attempt(ctx, uint64(s.options.BatchSize), s.attempt)
+		if err != nil && ctx.Err() == nil {
+			s.log.ErrorContext(ctx, "error send webhook deliveries", logger.Err(err))
+		}
+
+		if err != nil || attempted < s.options.BatchSize {
+			return
+		}
+	}
+}
+
+// attempt posts a delivery and records the outcome in it. Failures are recorded, not returned,
+// so other deliveries of the batch are saved.
+func (s *WebhookSender) attempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.Attempts++
+
+	statusCode, err := s.post(ctx, delivery)
+
+	delivery.LastStatusCode = statusCode
+
+	if err == nil {
+		now := time.Now()
+
+		delivery.Status = models.DeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+
+		metrics.WebhookAttempted(metrics.WebhookDelivered)
+
+		return nil
+	}
+
+	delivery.LastError = err.Error()
+
+	if delivery.Attempts >= s.options.MaxAttempts {
+		delivery.Status = models.DeliveryDead
+
+		metrics.WebhookAttempted(metrics.WebhookDead)
+		s.log.WarnContext(
+			ctx,
+			"webhook delivery is dead",
+			slog.String("delivery_id", delivery.Id.String()),
+			slog.String("webhook_id", delivery.SubscriptionId.String()),
+			logger.Err(err),
+		)
+
+		return nil
+	}
+
+	delivery.NextAttemptAt = time.Now().Add(Backoff(delivery.Attempts, s.options.BackoffBase, s.options.BackoffMax))
+
+	metrics.WebhookAttempted(metrics.WebhookFailed)
+
+	return nil
+}
+
+func (s *WebhookSender) post(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("error create request. %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Event-Id", delivery.EventId.String())
+	request.Header.Set("X-Event-Type", delivery.EventType)
+	request.Header.Set("X-Webhook-Id", delivery.SubscriptionId.String())
+	request.Header.Set("X-Webhook-Delivery", delivery.Id.String())
+	request.Header.Set(publisher.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(publisher.SignatureHeader, publisher.Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("error post delivery. %w", err)
+	}
+
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf(
+			"error webhook responded %d. %w", response.StatusCode, publisher.ErrorDeliveryFailed,
+		)
+	}
+
+	return response.StatusCode, nil
+}
+
+// Backoff returns a delay before the next attempt after attempts failed ones: base doubled for
+// every attempt after the first one, up to limit
+func Backoff(attempts int, base time.Duration, limit time.Duration) time.Duration {
+	delay := base
+
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+
+	return min(delay, limit)
+}
@@ -0,0 +1,22 @@
+alter table products_reservations
+        add column if not exists id UUID not null default gen_random_uuid();
+
+create unique index if not exists index_products_reservations_id
+on products_reservations (
+        id
+);
+
+create index if not exists index_products_reservations_created_at_id
+on products_reservations (
+        created_at, id
+);
+
+create index if not exists index_products_created_at_id
+on products (
+        created_at, id
+);
+
+create index if not exists index_storages_created_at_id
+on storages (
+        created_at, id
+);
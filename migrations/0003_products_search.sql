@@ -0,0 +1,11 @@
+create extension if not exists pg_trgm;
+
+create index if not exists index_products_name_fts
+on products using gin (
+        to_tsvector('simple', coalesce(name, ''))
+);
+
+create index if not exists index_products_name_trgm
+on products using gin (
+        coalesce(name, '') gin_trgm_ops
+);
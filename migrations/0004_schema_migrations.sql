@@ -0,0 +1,7 @@
+create table if not exists schema_migrations (
+    version integer primary key,
+    applied_at timestamp not null default now()
+);
+
+insert into schema_migrations (version) values (1), (2), (3), (4)
+on conflict do nothing;
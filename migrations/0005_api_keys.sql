@@ -0,0 +1,11 @@
+create table if not exists api_keys (
+        id UUID primary key,
+        name varchar(300) not null,
+        key_hash char(64) not null unique,
+        scopes text[] not null default '{}',
+        created_at timestamp default current_timestamp,
+        revoked_at timestamp
+);
+
+insert into schema_migrations (version) values (5)
+on conflict do nothing;
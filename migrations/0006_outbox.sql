@@ -0,0 +1,13 @@
+create table if not exists outbox (
+        id bigserial primary key,
+        event_id UUID not null unique,
+        event_type varchar(100) not null,
+        payload jsonb not null,
+        created_at timestamp default current_timestamp,
+        published_at timestamp
+);
+
+create index if not exists outbox_unpublished_idx on outbox (id) where published_at is null;
+
+insert into schema_migrations (version) values (6)
+on conflict do nothing;
@@ -0,0 +1,34 @@
+create table if not exists webhook_subscriptions (
+        id UUID primary key,
+        url text not null,
+        secret varchar(100) not null,
+        event_types text[] not null default '{}',
+        product_id UUID,
+        storage_id UUID,
+        created_at timestamp default current_timestamp
+);
+
+create table if not exists webhook_deliveries (
+        id UUID primary key,
+        subscription_id UUID not null references webhook_subscriptions (id) on delete cascade,
+        event_id UUID not null,
+        event_type varchar(100) not null,
+        payload jsonb not null,
+        status varchar(20) not null,
+        attempts int not null default 0,
+        next_attempt_at timestamp not null,
+        last_status_code int,
+        last_error text,
+        created_at timestamp default current_timestamp,
+        updated_at timestamp default current_timestamp,
+        delivered_at timestamp,
+        unique (subscription_id, event_id)
+);
+
+create index if not exists webhook_deliveries_due_idx on webhook_deliveries (next_attempt_at)
+where status = 'pending';
+
+create index if not exists webhook_deliveries_log_idx on webhook_deliveries (subscription_id, created_at, id);
+
+insert into schema_migrations (version) values (7)
+on conflict do nothing;
@@ -0,0 +1,14 @@
+create table if not exists idempotency_keys (
+        client varchar(100) not null,
+        key varchar(255) not null,
+        request_hash varchar(64) not null,
+        status_code int not null default 0,
+        response bytea,
+        created_at timestamp default current_timestamp,
+        primary key (client, key)
+);
+
+create index if not exists idempotency_keys_created_at_idx on idempotency_keys (created_at);
+
+insert into schema_migrations (version) values (8)
+on conflict do nothing;
@@ -0,0 +1,84 @@
+-- Versions of products distribution rows. A version is valid from valid_from until valid_to, the
+-- current one has no valid_to. Versions are written by a trigger, so every change of stock is kept.
+create table if not exists products_distribution_history (
+        id bigserial primary key,
+        storage_id UUID not null,
+        product_id UUID not null,
+        amount bigint not null default 0,
+        reserved bigint not null default 0,
+        available bigint not null default 0,
+        valid_from timestamptz not null,
+        valid_to timestamptz
+);
+
+create index if not exists products_distribution_history_storage_id_product_id_valid_from_idx
+on products_distribution_history (storage_id, product_id, valid_from);
+
+create index if not exists products_distribution_history_product_id_valid_from_idx
+on products_distribution_history (product_id, valid_from);
+
+create unique index if not exists products_distribution_history_current_idx
+on products_distribution_history (storage_id, product_id) where valid_to is null;
+
+-- A version is opened when the transaction writing it starts, but not before the last version of
+-- the row ends: a transaction started before another one committed may still write after it, and
+-- versions must not overlap or go back in time. Versions replaced within the transaction that
+-- opened them, or by a transaction started earlier, are closed at their start. Such versions are
+-- left empty and never match a point in time.
+create or replace function products_distribution_version() returns trigger as $$
+declare
+        target products_distribution;
+        changed_at timestamptz;
+begin
+        if tg_op = 'UPDATE'
+                and (old.amount, old.reserved, old.available) is not distinct from
+                        (new.amount, new.reserved, new.available) then
+                return null;
+        end if;
+
+        if tg_op = 'INSERT' then
+                target := new;
+        else
+                target := old;
+        end if;
+
+        changed_at := greatest(now(), (
+                select coalesce(valid_to, valid_from) from products_distribution_history
+                where storage_id = target.storage_id and product_id = target.product_id
+                order by valid_from desc, id desc
+                limit 1
+        ));
+
+        if tg_op in ('UPDATE', 'DELETE') then
+                update products_distribution_history set valid_to = changed_at
+                where storage_id = old.storage_id and product_id = old.product_id and valid_to is null;
+        end if;
+
+        if tg_op in ('INSERT', 'UPDATE') then
+                insert into products_distribution_history
+                        (storage_id, product_id, amount, reserved, available, valid_from)
+                values (new.storage_id, new.product_id, new.amount, new.reserved, new.available, changed_at);
+        end if;
+
+        return null;
+end;
+$$ language plpgsql;
+
+-- Stock held before history was kept is known since its last update only
+insert into products_distribution_history (storage_id, product_id, amount, reserved, available, valid_from)
+select storage_id, product_id, amount, reserved, available, coalesce(updated_at, created_at, now())
+from products_distribution
+where not exists (
+        select 1 from products_distribution_history as h
+        where h.storage_id = products_distribution.storage_id
+                and h.product_id = products_distribution.product_id
+);
+
+drop trigger if exists products_distribution_version on products_distribution;
+
+create trigger products_distribution_version
+after insert or update or delete on products_distribution
+for each row execute function products_distribution_version();
+
+insert into schema_migrations (version) values (9)
+on conflict do nothing;
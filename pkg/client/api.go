@@ -0,0 +1,318 @@
+package client
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) Storages(ctx context.Context, request *StoragesRequest) (*StoragesResponse, error) {
+	response := new(StoragesResponse)
+
+	if err := c.do(ctx, http.MethodGet, "/storages", dto.EncodeQuery(request), nil, response); err != nil {
+		return nil, fmt.Errorf("error fetch storages. %w", err)
+	}
+
+	return response, nil
+}
+
+// StoragesIterator iterates over storages matching the request, starting at its cursor or offset
+func (c *Client) StoragesIterator(request *StoragesRequest) *Iterator[*Storage] {
+	r := copyRequest(request)
+
+	return newIterator(r.Cursor, func(
+		ctx context.Context,
+		position page[*Storage],
+	) (page[*Storage], error) {
+		r.Cursor = position.cursor
+
+		response, err := c.Storages(ctx, &r)
+		if err != nil {
+			return page[*Storage]{}, err
+		}
+
+		return page[*Storage]{
+			items:  response.Storages,
+			cursor: response.NextCursor,
+			last:   response.NextCursor == "",
+		}, nil
+	})
+}
+
+// StorageProducts lists products of request.StorageId storage
+func (c *Client) StorageProducts(
+	ctx context.Context,
+	request *StorageProductsRequest,
+) (*StorageProductsResponse, error) {
+	response := new(StorageProductsResponse)
+
+	path := "/storages/" + url.PathEscape(request.StorageId) + "/products"
+
+	if err := c.do(ctx, http.MethodGet, path, dto.EncodeQuery(request), nil, response); err != nil {
+		return nil, fmt.Errorf("error fetch storage products. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *Client) StorageProductsIterator(request *StorageProductsRequest) *Iterator[*StorageProduct] {
+	r := copyRequest(request)
+
+	return newIterator(r.Cursor, func(
+		ctx context.Context,
+		position page[*StorageProduct],
+	) (page[*StorageProduct], error) {
+		r.Cursor = position.cursor
+
+		response, err := c.StorageProducts(ctx, &r)
+		if err != nil {
+			return page[*StorageProduct]{}, err
+		}
+
+		return page[*StorageProduct]{
+			items:  response.Products,
+			cursor: response.NextCursor,
+			last:   response.NextCursor == "",
+		}, nil
+	})
+}
+
+func (c *Client) Products(ctx context.Context, request *ProductsRequest) (*ProductsResponse, error) {
+	response := new(ProductsResponse)
+
+	if err := c.do(ctx, http.MethodGet, "/products", dto.EncodeQuery(request), nil, response); err != nil {
+		return nil, fmt.Errorf("error fetch products. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *Client) ProductsIterator(request *ProductsRequest) *Iterator[*ProductInfo] {
+	r := copyRequest(request)
+
+	return newIterator(r.Cursor, func(
+		ctx context.Context,
+		position page[*ProductInfo],
+	) (page[*ProductInfo], error) {
+		r.Cursor = position.cursor
+
+		response, err := c.Products(ctx, &r)
+		if err != nil {
+			return page[*ProductInfo]{}, err
+		}
+
+		return page[*ProductInfo]{
+			items:  response.Products,
+			cursor: response.NextCursor,
+			last:   response.NextCursor == "",
+		}, nil
+	})
+}
+
+func (c *Client) SearchProducts(ctx context.Context, request *SearchProductsRequest) (*ProductsResponse, error) {
+	response := new(ProductsResponse)
+
+	if err := c.do(ctx, http.MethodGet, "/products/search", dto.EncodeQuery(request), nil, response); err != nil {
+		return nil, fmt.Errorf("error search products. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *Client) SearchProductsIterator(request *SearchProductsRequest) *Iterator[*ProductInfo] {
+	r := copyRequest(request)
+
+	return newIterator(r.Cursor, func(
+		ctx context.Context,
+		position page[*ProductInfo],
+	) (page[*ProductInfo], error) {
+		r.Cursor = position.cursor
+
+		response, err := c.SearchProducts(ctx, &r)
+		if err != nil {
+			return page[*ProductInfo]{}, err
+		}
+
+		return page[*ProductInfo]{
+			items:  response.Products,
+			cursor: response.NextCursor,
+			last:   response.NextCursor == "",
+		}, nil
+	})
+}
+
+func (c *Client) Reservations(ctx context.Context, request *ReservationsRequest) (*ReservationsResponse, error) {
+	response := new(ReservationsResponse)
+
+	if err := c.do(ctx, http.MethodGet, "/reservations", dto.EncodeQuery(request), nil, response); err != nil {
+		return nil, fmt.Errorf("error fetch reservations. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *Client) ReservationsIterator(request *ReservationsRequest) *Iterator[*Reservation] {
+	r := copyRequest(request)
+
+	return newIterator(r.Cursor, func(
+		ctx context.Context,
+		position page[*Reservation],
+	) (page[*Reservation], error) {
+		r.Cursor = position.cursor
+
+		response, err := c.Reservations(ctx, &r)
+		if err != nil {
+			return page[*Reservation]{}, err
+		}
+
+		return page[*Reservation]{
+			items:  response.Reservations,
+			cursor: response.NextCursor,
+			last:   response.NextCursor == "",
+		}, nil
+	})
+}
+
+// Reserve reserves products for a shipping. Fails with ErrNotEnoughProducts if products are not
+// available or ErrNotEnoughSpace if storages can not fit them.
+func (c *Client) Reserve(ctx context.Context, request *ReserveRequest) (*ReserveResponse, error) {
+	response := new(ReserveResponse)
+
+	if err := c.do(ctx, http.MethodPost, "/reservations/new", nil, request, response); err != nil {
+		return nil, fmt.Errorf("error reserve products. %w", err)
+	}
+
+	return response, nil
+}
+
+// Cancel cancels a reservation. Reserved products are available for reservation again
+func (c *Client) Cancel(ctx context.Context, request *CancelRequest) (*CancelResponse, error) {
+	response := new(CancelResponse)
+
+	if err := c.do(ctx, http.MethodDelete, "/reservations/cancel", nil, request, response); err != nil {
+		return nil, fmt.Errorf("error cancel reservation. %w", err)
+	}
+
+	return response, nil
+}
+
+// Release releases a reservation. Reserved products are written off from stock
+func (c *Client) Release(ctx context.Context, request *ReleaseRequest) (*ReleaseResponse, error) {
+	response := new(ReleaseResponse)
+
+	if err := c.do(ctx, http.MethodDelete, "/reservations/release", nil, request, response); err != nil {
+		return nil, fmt.Errorf("error release reservation. %w", err)
+	}
+
+	return response, nil
+}
+
+// CreateWebhook subscribes to events. The secret is returned only once
+func (c *Client) CreateWebhook(ctx context.Context, request *CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	response := new(CreateWebhookResponse)
+
+	if err := c.do(ctx, http.MethodPost, "/webhooks", nil, request, response); err != nil {
+		return nil, fmt.Errorf("error create webhook. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *Client) Webhooks(ctx context.Context) (*WebhooksResponse, error) {
+	response := new(WebhooksResponse)
+
+	if err := c.do(ctx, http.MethodGet, "/webhooks", nil, nil, response); err != nil {
+		return nil, fmt.Errorf("error fetch webhooks. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, webhookId string) (*DeleteWebhookResponse, error) {
+	response := new(DeleteWebhookResponse)
+
+	if err := c.do(ctx, http.MethodDelete, "/webhooks/"+url.PathEscape(webhookId), nil, nil, response); err != nil {
+		return nil, fmt.Errorf("error delete webhook. %w", err)
+	}
+
+	return response, nil
+}
+
+// WebhookDeliveries lists deliveries of request.WebhookId subscription
+func (c *Client) WebhookDeliveries(
+	ctx context.Context,
+	request *WebhookDeliveriesRequest,
+) (*WebhookDeliveriesResponse, error) {
+	response := new(WebhookDeliveriesResponse)
+
+	path := "/webhooks/" + url.PathEscape(request.WebhookId) + "/deliveries"
+
+	if err := c.do(ctx, http.MethodGet, path, dto.EncodeQuery(request), nil, response); err != nil {
+		return nil, fmt.Errorf("error fetch webhook deliveries. %w", err)
+	}
+
+	return response, nil
+}
+
+func (c *Client) WebhookDeliveriesIterator(request *WebhookDeliveriesRequest) *Iterator[*WebhookDelivery] {
+	r := copyRequest(request)
+
+	return newIterator(r.Cursor, func(
+		ctx context.Context,
+		position page[*WebhookDelivery],
+	) (page[*WebhookDelivery], error) {
+		r.Cursor = position.cursor
+
+		response, err := c.WebhookDeliveries(ctx, &r)
+		if err != nil {
+			return page[*WebhookDelivery]{}, err
+		}
+
+		return page[*WebhookDelivery]{
+			items:  response.Deliveries,
+			cursor: response.NextCursor,
+			last:   response.NextCursor == "",
+		}, nil
+	})
+}
+
+// ReplayWebhookDelivery delivers the event again, even if delivery is dead
+func (c *Client) ReplayWebhookDelivery(
+	ctx context.Context,
+	webhookId, deliveryId string,
+) (*ReplayWebhookDeliveryResponse, error) {
+	response := new(ReplayWebhookDeliveryResponse)
+
+	path := "/webhooks/" + url.PathEscape(webhookId) + "/deliveries/" + url.PathEscape(deliveryId) + "/replay"
+
+	if err := c.do(ctx, http.MethodPost, path, nil, nil, response); err != nil {
+		return nil, fmt.Errorf("error replay webhook delivery. %w", err)
+	}
+
+	return response, nil
+}
+
+// Status reports build version, uptime and readiness checks of the service. Fails with
+// ErrUnavailable if the service is not ready.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	response := new(Status)
+
+	if err := c.do(ctx, http.MethodGet, "/status", nil, nil, response); err != nil {
+		return nil, fmt.Errorf("error fetch status. %w", err)
+	}
+
+	return response, nil
+}
+
+// copyRequest copies a request, so iterators do not change requests passed by callers
+func copyRequest[R any](request *R) R {
+	var r R
+
+	if request != nil {
+		r = *request
+	}
+
+	return r
+}
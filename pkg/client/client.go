@@ -0,0 +1,291 @@
+// Package client is a Go client of Cernunnos HTTP API.
+//
+// Failed requests are retried on network errors, rate limiting and unavailability of the service.
+// Writes are sent with an Idempotency-Key header, generated once per call, so a retried write is
+// applied once even if the response to a previous attempt was lost. Error responses are returned
+// as *APIError, matching errors of this package with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMaxRetries   = 3
+	DefaultRetryBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff   = 5 * time.Second
+
+	apiKeyHeader         = "X-API-Key"
+	idempotencyKeyHeader = "Idempotency-Key"
+	userAgent            = "cernunnos-go-client"
+)
+
+type Options struct {
+	HTTPClient *http.Client // http.DefaultClient, if nil
+	APIKey     string       // Passed with X-API-Key header
+	Token      string       // Bearer token. Overrides APIKey
+	// Retries of a failed request. DefaultMaxRetries, if zero. Negative disables retries
+	MaxRetries int
+	// Delay before the first retry, doubled for every next one. DefaultRetryBackoff, if zero
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration // DefaultMaxBackoff, if zero
+	// Timeout of every attempt. Context deadline limits all attempts along with delays between them
+	Timeout time.Duration
+}
+
+type Client struct {
+	baseURL *url.URL
+	options Options
+}
+
+// New builds a client of the service served at baseURL, e.g. http://localhost:8080
+func New(baseURL string, options Options) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("error parse base url. %w", err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("error base url %q must be absolute", baseURL)
+	}
+
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+
+	switch {
+	case options.MaxRetries == 0:
+		options.MaxRetries = DefaultMaxRetries
+	case options.MaxRetries < 0:
+		options.MaxRetries = 0
+	}
+
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = DefaultRetryBackoff
+	}
+
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = DefaultMaxBackoff
+	}
+
+	return &Client{baseURL: u, options: options}, nil
+}
+
+// transportError is a failure to send a request or to read a response. The request may have been
+// handled or not.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transportError) Unwrap() error {
+	return e.err
+}
+
+// do calls the API, retrying failed attempts, and decodes the response into out, if passed
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+
+	if body != nil {
+		var err error
+
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshal request. %w", err)
+		}
+	}
+
+	// The key is kept for all attempts, so the service applies the write once
+	var key string
+
+	if method != http.MethodGet {
+		key = uuid.NewString()
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.attempt(ctx, method, path, query, payload, key, out)
+		if err == nil {
+			return nil
+		}
+
+		delay, retry := c.retryDelay(ctx, err, attempt)
+		if !retry {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(
+	ctx context.Context,
+	method, path string,
+	query url.Values,
+	payload []byte,
+	key string,
+	out any,
+) error {
+	if c.options.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.options.Timeout)
+		defer cancel()
+	}
+
+	request, err := c.newRequest(ctx, method, path, query, payload)
+	if err != nil {
+		return err
+	}
+
+	if key != "" {
+		request.Header.Set(idempotencyKeyHeader, key)
+	}
+
+	response, err := c.options.HTTPClient.Do(request)
+	if err != nil {
+		return &transportError{fmt.Errorf("error send request. %w", err)}
+	}
+
+	defer response.Body.Close()
+
+	raw, err := io.ReadAll(response.Body)
+	if err != nil {
+		return &transportError{fmt.Errorf("error read response. %w", err)}
+	}
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return newAPIError(response, raw)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err = json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("error unmarshal response. %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) newRequest(
+	ctx context.Context,
+	method, path string,
+	query url.Values,
+	payload []byte,
+) (*http.Request, error) {
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	var body io.Reader
+
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("error build request. %w", err)
+	}
+
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	request.Header.Set("User-Agent", userAgent)
+
+	switch {
+	case c.options.Token != "":
+		request.Header.Set("Authorization", "Bearer "+c.options.Token)
+	case c.options.APIKey != "":
+		request.Header.Set(apiKeyHeader, c.options.APIKey)
+	}
+
+	return request, nil
+}
+
+func newAPIError(response *http.Response, raw []byte) *APIError {
+	apiErr := &APIError{}
+
+	// Responses of proxies may be not JSON
+	if err := json.Unmarshal(raw, apiErr); err != nil || apiErr.Details == "" {
+		apiErr.Details = http.StatusText(response.StatusCode)
+	}
+
+	apiErr.StatusCode = response.StatusCode
+
+	if seconds, err := strconv.Atoi(response.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return apiErr
+}
+
+// retryDelay returns a delay before the next attempt, if the failed one may be retried. A retry is
+// not made if it can not start before ctx deadline.
+func (c *Client) retryDelay(ctx context.Context, err error, attempt int) (time.Duration, bool) {
+	if attempt >= c.options.MaxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+
+	var (
+		apiErr       *APIError
+		transportErr *transportError
+		retryAfter   time.Duration
+	)
+
+	switch {
+	case errors.As(err, &transportErr):
+	case errors.As(err, &apiErr) && retryable(apiErr):
+		retryAfter = apiErr.RetryAfter
+	default:
+		return 0, false
+	}
+
+	// Exponential backoff with jitter, so clients failed at once do not retry at once
+	backoff := c.options.MaxBackoff
+	if attempt < 32 {
+		backoff = min(c.options.RetryBackoff<<attempt, backoff)
+	}
+
+	delay := max(backoff/2+rand.N(backoff/2+1), retryAfter)
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+func retryable(apiErr *APIError) bool {
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return errors.Is(apiErr, ErrIdempotencyKeyInProgress)
+	}
+}
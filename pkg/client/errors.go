@@ -0,0 +1,82 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Errors matched by APIError with errors.Is:
+//
+//	if errors.Is(err, client.ErrNotEnoughProducts) {...}
+var (
+	ErrInvalidRequest           = errors.New("invalid request")
+	ErrUnauthorized             = errors.New("unauthorized")
+	ErrForbidden                = errors.New("forbidden")
+	ErrNotFound                 = errors.New("not found")
+	ErrRateLimited              = errors.New("rate limited")
+	ErrNotEnoughProducts        = errors.New("not enough products")
+	ErrNotEnoughSpace           = errors.New("not enough space")
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key is used with another request")
+	ErrIdempotencyKeyInProgress = errors.New("request with the idempotency key is in progress")
+	ErrUnavailable              = errors.New("service unavailable")
+	ErrInternal                 = errors.New("internal server error")
+)
+
+// reasonErrors maps reasons of API errors to errors
+var reasonErrors = map[string]error{
+	"bad_request":                 ErrInvalidRequest,
+	"invalid_cursor":              ErrInvalidRequest,
+	"invalid_filter":              ErrInvalidRequest,
+	"invalid_field":               ErrInvalidRequest,
+	"field_required":              ErrInvalidRequest,
+	"unauthorized":                ErrUnauthorized,
+	"forbidden":                   ErrForbidden,
+	"not_found":                   ErrNotFound,
+	"rate_limited":                ErrRateLimited,
+	"not_enough_products":         ErrNotEnoughProducts,
+	"not_enough_space":            ErrNotEnoughSpace,
+	"idempotency_key_mismatch":    ErrIdempotencyKeyMismatch,
+	"idempotency_key_in_progress": ErrIdempotencyKeyInProgress,
+	"internal":                    ErrInternal,
+}
+
+// statusErrors maps status codes of responses without a known reason, e.g. of proxies
+var statusErrors = map[int]error{
+	http.StatusBadRequest:          ErrInvalidRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrForbidden,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusTooManyRequests:     ErrRateLimited,
+	http.StatusInternalServerError: ErrInternal,
+	http.StatusBadGateway:          ErrUnavailable,
+	http.StatusServiceUnavailable:  ErrUnavailable,
+	http.StatusGatewayTimeout:      ErrUnavailable,
+}
+
+// APIError is an error response of the service
+type APIError struct {
+	StatusCode int    `json:"code"`
+	Reason     string `json:"reason"`  // Stable machine readable identifier, e.g. not_enough_products
+	Details    string `json:"details"` // Human readable description
+	// Delay requested with Retry-After header. Zero if not passed
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("cernunnos: %d %s", e.StatusCode, e.Details)
+	}
+
+	return fmt.Sprintf("cernunnos: %d %s: %s", e.StatusCode, e.Reason, e.Details)
+}
+
+// Unwrap returns the error of the reason, or of the status code if the reason is unknown
+func (e *APIError) Unwrap() error {
+	if err, ok := reasonErrors[e.Reason]; ok {
+		return err
+	}
+
+	return statusErrors[e.StatusCode]
+}
@@ -0,0 +1,76 @@
+package client
+
+import "context"
+
+// Iterator walks through items of a listing, fetching pages as items are consumed:
+//
+//	it := c.StoragesIterator(&client.StoragesRequest{Limit: 100})
+//	for it.Next(ctx) {
+//		storage := it.Item()
+//	}
+//	if err := it.Err(); err != nil {...}
+type Iterator[T any] struct {
+	fetch  func(ctx context.Context, position page[T]) (page[T], error)
+	page   page[T]
+	index  int
+	item   T
+	err    error
+	loaded bool
+}
+
+// page is a fetched page along with the position of the next one
+type page[T any] struct {
+	items  []T
+	cursor string // Cursor of the next page
+	last   bool
+}
+
+// newIterator builds an iterator starting at a cursor. Requests without a cursor start at their
+// offset, following pages are fetched by cursors.
+func newIterator[T any](
+	cursor string,
+	fetch func(ctx context.Context, position page[T]) (page[T], error),
+) *Iterator[T] {
+	return &Iterator[T]{fetch: fetch, page: page[T]{cursor: cursor}}
+}
+
+// Next advances to the next item. Returns false when items are over or fetching a page fails.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for it.err == nil {
+		if it.index < len(it.page.items) {
+			it.item = it.page.items[it.index]
+			it.index++
+
+			return true
+		}
+
+		if it.loaded && it.page.last {
+			return false
+		}
+
+		next, err := it.fetch(ctx, it.page)
+		if err != nil {
+			it.err = err
+
+			return false
+		}
+
+		it.page, it.index, it.loaded = next, 0, true
+
+		if len(next.items) == 0 {
+			return false
+		}
+	}
+
+	return false
+}
+
+// Item returns the current item
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Err returns the error stopped iteration, if any
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"bufio"
+	"cernunnos/internal/pkg/dto"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// StockStream reads stock changes pushed by the service. The stream is not reconnected, a new one
+// continues from LastEventId:
+//
+//	stream, err := c.StreamStock(ctx, &client.StockStreamRequest{ProductId: id})
+//	defer stream.Close()
+//	for stream.Next() {
+//		change := stream.Change()
+//	}
+type StockStream struct {
+	body        io.ReadCloser
+	scanner     *bufio.Scanner
+	change      *StockChange
+	lastEventId string
+	err         error
+}
+
+// StreamStock subscribes to stock changes. Cancelling ctx ends the stream. Options.Timeout is not
+// applied to streams, as they last for long.
+func (c *Client) StreamStock(ctx context.Context, request *StockStreamRequest) (*StockStream, error) {
+	httpRequest, err := c.newRequest(ctx, http.MethodGet, "/stream/stock", dto.EncodeQuery(request), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error subscribe to stock changes. %w", err)
+	}
+
+	httpRequest.Header.Set("Accept", "text/event-stream")
+
+	response, err := c.options.HTTPClient.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("error subscribe to stock changes. %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+
+		raw, _ := io.ReadAll(response.Body)
+
+		return nil, fmt.Errorf("error subscribe to stock changes. %w", newAPIError(response, raw))
+	}
+
+	stream := &StockStream{body: response.Body, scanner: bufio.NewScanner(response.Body)}
+
+	if request != nil {
+		stream.lastEventId = request.LastEventId
+	}
+
+	return stream, nil
+}
+
+// Next waits for the next change. Returns false when the stream ends
+func (s *StockStream) Next() bool {
+	var (
+		id, event string
+		data      strings.Builder
+	)
+
+	for s.err == nil && s.scanner.Scan() {
+		line := s.scanner.Text()
+
+		// An empty line ends an event. Comments and retry hints are skipped
+		if line == "" {
+			if event != "" && data.Len() > 0 {
+				change := new(StockChange)
+				if err := json.Unmarshal([]byte(data.String()), change); err != nil {
+					s.err = fmt.Errorf("error unmarshal stock change. %w", err)
+
+					return false
+				}
+
+				s.change, s.lastEventId = change, id
+
+				return true
+			}
+
+			id, event = "", ""
+			data.Reset()
+
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			event = value
+		case "data":
+			data.WriteString(value)
+		}
+	}
+
+	if err := s.scanner.Err(); err != nil && s.err == nil && !errors.Is(err, context.Canceled) {
+		s.err = fmt.Errorf("error read stock stream. %w", err)
+	}
+
+	return false
+}
+
+// Change returns the current change
+func (s *StockStream) Change() *StockChange {
+	return s.change
+}
+
+// LastEventId returns the id of the last received change, to continue a new stream from
+func (s *StockStream) LastEventId() string {
+	return s.lastEventId
+}
+
+// Err returns the error ended the stream, if any
+func (s *StockStream) Err() error {
+	return s.err
+}
+
+func (s *StockStream) Close() error {
+	return s.body.Close()
+}
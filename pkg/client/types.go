@@ -0,0 +1,49 @@
+package client
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/health"
+)
+
+// Requests and responses are the ones of the service, so they never drift apart
+type (
+	StoragesFilter          = dto.StoragesFilter
+	StoragesRequest         = dto.StoragesRequest
+	StoragesResponse        = dto.StoragesResponse
+	Storage                 = dto.Storage
+	StorageProductsRequest  = dto.StorageProductsRequest
+	StorageProductsResponse = dto.StorageProductsResponse
+	StorageProduct          = dto.StorageProduct
+	ProductsFilter          = dto.ProductsFilter
+	ProductsRequest         = dto.ProductsRequest
+	SearchProductsRequest   = dto.SearchProductsRequest
+	ProductsResponse        = dto.ProductsResponse
+	ProductInfo             = dto.ProductInfo
+	ProductDestribution     = dto.ProductDestribution
+
+	Reservation          = dto.Reservation
+	ReservationsRequest  = dto.ReservationsRequest
+	ReservationsResponse = dto.ReservationsResponse
+	ReserveRequest       = dto.ReserveRequest
+	ReserveResponse      = dto.ReserveResponse
+	CancelRequest        = dto.CancelRequest
+	CancelResponse       = dto.CancelResponse
+	ReleaseRequest       = dto.ReleaseRequest
+	ReleaseResponse      = dto.ReleaseResponse
+
+	Webhook                       = dto.Webhook
+	CreateWebhookRequest          = dto.CreateWebhookRequest
+	CreateWebhookResponse         = dto.CreateWebhookResponse
+	WebhooksResponse              = dto.WebhooksResponse
+	DeleteWebhookResponse         = dto.DeleteWebhookResponse
+	WebhookDeliveriesRequest      = dto.WebhookDeliveriesRequest
+	WebhookDeliveriesResponse     = dto.WebhookDeliveriesResponse
+	WebhookDelivery               = dto.WebhookDelivery
+	ReplayWebhookDeliveryResponse = dto.ReplayWebhookDeliveryResponse
+
+	StockStreamRequest = dto.StockStreamRequest
+	StockChange        = dto.StockChange
+
+	Status      = health.Report
+	StatusCheck = health.CheckResult
+)
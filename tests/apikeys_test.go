@@ -0,0 +1,319 @@
+package tests
+
+import (
+	"cernunnos/internal/middleware"
+	"cernunnos/internal/pkg/auth"
+	"cernunnos/internal/pkg/config"
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/server"
+	"cernunnos/internal/server/interface/controllers"
+	"cernunnos/internal/usecase/interactors"
+	apikeysRepo "cernunnos/internal/usecase/repository/apikeys"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// memoryAPIKeysRepository keeps keys by their hashes
+type memoryAPIKeysRepository struct {
+	keys   map[string]*models.APIKey
+	hashes map[uuid.UUID]string
+}
+
+func newMemoryAPIKeysRepository() *memoryAPIKeysRepository {
+	return &memoryAPIKeysRepository{
+		keys:   make(map[string]*models.APIKey),
+		hashes: make(map[uuid.UUID]string),
+	}
+}
+
+func (r *memoryAPIKeysRepository) Create(
+	ctx context.Context,
+	params apikeysRepo.CreateParams,
+) (*models.APIKey, error) {
+	key := &models.APIKey{Id: uuid.New(), Name: params.Name, Scopes: params.Scopes, CreatedAt: time.Now()}
+
+	r.keys[params.KeyHash], r.hashes[key.Id] = key, params.KeyHash
+
+	return key, nil
+}
+
+func (r *memoryAPIKeysRepository) KeyByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	key, ok := r.keys[hash]
+	if !ok || key.RevokedAt != nil {
+		return nil, apikeysRepo.ErrorKeyNotFound
+	}
+
+	return key, nil
+}
+
+func (r *memoryAPIKeysRepository) Keys(ctx context.Context) ([]*models.APIKey, error) {
+	keys := make([]*models.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func (r *memoryAPIKeysRepository) Revoke(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
+	r.keys[r.hashes[id]].RevokedAt = &now
+
+	return nil
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	t.Log("Test: api key authentication\n")
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	interactor := interactors.NewAPIKeyInteractor(log, newMemoryAPIKeysRepository())
+
+	create := func(t *testing.T, scopes ...string) (string, *models.APIKey) {
+		key, apiKey, err := interactor.Create(context.Background(), interactors.CreateAPIKeyParams{
+			Name:   "client-" + strings.Join(scopes, "-"),
+			Scopes: scopes,
+		})
+		if err != nil {
+			t.Fatal("error create key", err)
+		}
+
+		return key, apiKey
+	}
+
+	builder := middleware.NewMiddlewareBuilder(log, errs.NewErrorHandler(), interactor, nil, 0)
+	handler := builder.Authentication(builder.RequireScope(auth.ScopeReserve)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				t.Fatal("error principal is not passed")
+			}
+
+			_, _ = io.WriteString(w, principal.Name)
+		}),
+	))
+
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/reservations/new", nil)
+		if header != "" {
+			request.Header.Set(header, value)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		return recorder
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Keys are random and stored by hash": func(t *testing.T) {
+			first, err := auth.GenerateKey()
+			if err != nil {
+				t.Fatal("error generate key", err)
+			}
+
+			second, err := auth.GenerateKey()
+			if err != nil {
+				t.Fatal("error generate key", err)
+			}
+
+			if first == second || !strings.HasPrefix(first, "cern_") || len(first) < 40 {
+				t.Fatal("error weak keys", first, second)
+			}
+
+			hash := auth.HashKey(first)
+			if hash != auth.HashKey(first) || hash == auth.HashKey(second) || len(hash) != 64 {
+				t.Fatal("error wrong hash", hash)
+			}
+
+			if strings.Contains(hash, strings.TrimPrefix(first, "cern_")) {
+				t.Fatal("error key is stored in hash")
+			}
+		},
+		"Missing key": func(t *testing.T) {
+			_, err := interactor.Authenticate(context.Background(), "")
+			if !errors.Is(err, auth.ErrorUnauthorized) {
+				t.Fatal("error missing key accepted", err)
+			}
+
+			if response := serve("", ""); response.Code != http.StatusUnauthorized {
+				t.Fatal("error request without key accepted", response.Code)
+			}
+		},
+		"Wrong key": func(t *testing.T) {
+			key, _ := create(t, auth.ScopeReserve)
+
+			_, err := interactor.Authenticate(context.Background(), key+"x")
+			if !errors.Is(err, auth.ErrorUnauthorized) {
+				t.Fatal("error wrong key accepted", err)
+			}
+
+			if response := serve(middleware.APIKeyHeader, "cern_unknown"); response.Code != http.StatusUnauthorized {
+				t.Fatal("error unknown key accepted", response.Code)
+			}
+		},
+		"Revoked key": func(t *testing.T) {
+			key, apiKey := create(t, auth.ScopeReserve)
+
+			if response := serve(middleware.APIKeyHeader, key); response.Code != http.StatusOK {
+				t.Fatal("error key rejected before revocation", response.Code)
+			}
+
+			if err := interactor.Revoke(context.Background(), apiKey.Id.String()); err != nil {
+				t.Fatal("error revoke key", err)
+			}
+
+			_, err := interactor.Authenticate(context.Background(), key)
+			if !errors.Is(err, auth.ErrorUnauthorized) {
+				t.Fatal("error revoked key accepted", err)
+			}
+
+			if response := serve(middleware.APIKeyHeader, key); response.Code != http.StatusUnauthorized {
+				t.Fatal("error request with revoked key accepted", response.Code)
+			}
+		},
+		"Missing scope": func(t *testing.T) {
+			key, _ := create(t, auth.ScopeRead, auth.ScopeRelease)
+
+			if response := serve(middleware.APIKeyHeader, key); response.Code != http.StatusForbidden {
+				t.Fatal("error request without scope accepted", response.Code)
+			}
+		},
+		"Accepted key": func(t *testing.T) {
+			key, apiKey := create(t, auth.ScopeReserve)
+
+			principal, err := interactor.Authenticate(context.Background(), key)
+			if err != nil {
+				t.Fatal("error authenticate", err)
+			}
+
+			if principal.Id != apiKey.Id.String() || !principal.Has(auth.ScopeReserve) || !principal.AllStorages {
+				t.Fatal("error wrong principal", principal)
+			}
+
+			response := serve(middleware.APIKeyHeader, key)
+			if response.Code != http.StatusOK || response.Body.String() != apiKey.Name {
+				t.Fatal("error key rejected", response.Code, response.Body.String())
+			}
+
+			if response = serve("Authorization", "Bearer "+key); response.Code != http.StatusOK {
+				t.Fatal("error bearer key rejected", response.Code)
+			}
+
+			admin, _ := create(t, auth.ScopeAdmin)
+
+			if response = serve(middleware.APIKeyHeader, admin); response.Code != http.StatusOK {
+				t.Fatal("error admin key rejected", response.Code)
+			}
+		},
+		"Route scopes": func(t *testing.T) {
+			cfg := config.Default()
+
+			// Handlers are not reached by forbidden requests, so controllers are not needed
+			api := server.New(
+				&cfg, log, controllers.NewRootController(nil, nil, nil, nil, nil, nil, nil), nil, interactor, nil, nil,
+			)
+
+			routes := []struct {
+				method, path string
+				allowed      []string
+				forbidden    []string
+			}{
+				{http.MethodGet, "/storages", []string{auth.ScopeRead, auth.ScopeAdmin}, []string{auth.ScopeReserve}},
+				{
+					http.MethodPost, "/reservations/new",
+					[]string{auth.ScopeReserve, auth.ScopeAdmin}, []string{auth.ScopeRead, auth.ScopeRelease},
+				},
+				{
+					http.MethodDelete, "/reservations/cancel",
+					[]string{auth.ScopeReserve}, []string{auth.ScopeRead, auth.ScopeRelease},
+				},
+				{
+					http.MethodDelete, "/reservations/release",
+					[]string{auth.ScopeRelease}, []string{auth.ScopeRead, auth.ScopeReserve},
+				},
+				{http.MethodGet, "/webhooks", []string{auth.ScopeAdmin}, []string{auth.ScopeRead, auth.ScopeReserve}},
+				{http.MethodPost, "/imports/storages", []string{auth.ScopeAdmin}, []string{auth.ScopeReserve}},
+			}
+
+			serveRoute := func(method, path, scope string) int {
+				key, _ := create(t, scope)
+
+				request := httptest.NewRequest(method, path, strings.NewReader("{}"))
+				request.Header.Set(middleware.APIKeyHeader, key)
+
+				recorder := httptest.NewRecorder()
+				api.ServeHTTP(recorder, request)
+
+				return recorder.Code
+			}
+
+			for _, route := range routes {
+				for _, scope := range route.forbidden {
+					if code := serveRoute(route.method, route.path, scope); code != http.StatusForbidden {
+						t.Fatal("error route", route.method, route.path, "accepted scope", scope, code)
+					}
+				}
+
+				for _, scope := range route.allowed {
+					code := serveRoute(route.method, route.path, scope)
+					if code == http.StatusForbidden || code == http.StatusUnauthorized {
+						t.Fatal("error route", route.method, route.path, "rejected scope", scope, code)
+					}
+				}
+			}
+		},
+		"Webhook listings are limited as reads": func(t *testing.T) {
+			cfg := config.Default()
+			cfg.RateLimits.WriteRPS = 0.001
+			cfg.RateLimits.WriteBurst = 1
+
+			api := server.New(
+				&cfg, log, controllers.NewRootController(nil, nil, nil, nil, nil, nil, nil), nil, interactor, nil, nil,
+			)
+
+			key, _ := create(t, auth.ScopeAdmin)
+
+			serve := func(method, path string) int {
+				request := httptest.NewRequest(method, path, strings.NewReader("{}"))
+				request.Header.Set(middleware.APIKeyHeader, key)
+
+				recorder := httptest.NewRecorder()
+				api.ServeHTTP(recorder, request)
+
+				return recorder.Code
+			}
+
+			for range cfg.RateLimits.WriteBurst + 1 {
+				if code := serve(http.MethodGet, "/webhooks"); code == http.StatusTooManyRequests {
+					t.Fatal("error webhooks listing is limited as write")
+				}
+
+				deliveries := "/webhooks/" + uuid.NewString() + "/deliveries"
+				if code := serve(http.MethodGet, deliveries); code == http.StatusTooManyRequests {
+					t.Fatal("error deliveries listing is limited as write")
+				}
+			}
+
+			serve(http.MethodPost, "/webhooks")
+
+			if code := serve(http.MethodPost, "/webhooks"); code != http.StatusTooManyRequests {
+				t.Fatal("error webhook creation is not limited as write", code)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
@@ -0,0 +1,307 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/auth"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/usecase/interactors"
+	reservationsRepo "cernunnos/internal/usecase/repository/reservations"
+	storagesRepo "cernunnos/internal/usecase/repository/storages"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestJWTAuthentication(t *testing.T) {
+	t.Log("Test: jwt authentication\n")
+
+	const secret = "secret"
+
+	storageId := uuid.New()
+
+	authenticator, err := auth.NewJWTAuthenticator(auth.JWTOptions{Secret: secret, Issuer: "cernunnos-test"})
+	if err != nil {
+		t.Fatal("error create authenticator", err)
+	}
+
+	sign := func(claims auth.Claims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal("error sign token", err)
+		}
+
+		return token
+	}
+
+	registered := jwt.RegisteredClaims{
+		Subject:   "operator",
+		Issuer:    "cernunnos-test",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Normal case": func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.TODO(), sign(auth.Claims{
+				RegisteredClaims: registered,
+				Scope:            "read reserve",
+				Storages:         []string{storageId.String()},
+			}))
+			if err != nil {
+				t.Fatal("error authenticate", err)
+			}
+
+			if !principal.Has(auth.ScopeReserve) || principal.Has(auth.ScopeRelease) {
+				t.Fatal("error wrong scopes", principal.Scopes)
+			}
+
+			if !principal.StorageAllowed(storageId) || principal.StorageAllowed(uuid.New()) {
+				t.Fatal("error wrong storages", principal.Storages)
+			}
+		},
+		"Admin may act in any storage": func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.TODO(), sign(auth.Claims{
+				RegisteredClaims: registered,
+				Scope:            "admin",
+			}))
+			if err != nil {
+				t.Fatal("error authenticate", err)
+			}
+
+			if !principal.StorageAllowed(uuid.New()) {
+				t.Fatal("error admin storage access denied")
+			}
+		},
+		"Expired token": func(t *testing.T) {
+			expired := registered
+			expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+
+			_, err := authenticator.Authenticate(context.TODO(), sign(auth.Claims{RegisteredClaims: expired}))
+			if !errors.Is(err, auth.ErrorUnauthorized) {
+				t.Fatal("error expired token accepted", err)
+			}
+		},
+		"Wrong issuer": func(t *testing.T) {
+			foreign := registered
+			foreign.Issuer = "foreign"
+
+			_, err := authenticator.Authenticate(context.TODO(), sign(auth.Claims{RegisteredClaims: foreign}))
+			if !errors.Is(err, auth.ErrorUnauthorized) {
+				t.Fatal("error foreign token accepted", err)
+			}
+		},
+		"JWKS": func(t *testing.T) {
+			key, err := rsa.GenerateKey(rand.Reader, 2048)
+			if err != nil {
+				t.Fatal("error generate key", err)
+			}
+
+			jwks, err := json.Marshal(map[string]any{
+				"keys": []map[string]string{{
+					"kty": "RSA",
+					"kid": "test",
+					"use": "sig",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+			if err != nil {
+				t.Fatal("error marshal jwks", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			if err = os.WriteFile(path, jwks, 0o600); err != nil {
+				t.Fatal("error write jwks", err)
+			}
+
+			jwksAuthenticator, err := auth.NewJWTAuthenticator(auth.JWTOptions{KeysFile: path})
+			if err != nil {
+				t.Fatal("error create authenticator", err)
+			}
+
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, auth.Claims{RegisteredClaims: registered, Scope: "read"})
+			token.Header["kid"] = "test"
+
+			signed, err := token.SignedString(key)
+			if err != nil {
+				t.Fatal("error sign token", err)
+			}
+
+			if _, err = jwksAuthenticator.Authenticate(context.TODO(), signed); err != nil {
+				t.Fatal("error authenticate", err)
+			}
+
+			hmacToken := sign(auth.Claims{RegisteredClaims: registered})
+
+			if _, err = jwksAuthenticator.Authenticate(context.TODO(), hmacToken); err == nil {
+				t.Fatal("error hmac token accepted by jwks authenticator")
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
+
+// recordingReservationsRepository records reservations passed to repository
+type recordingReservationsRepository struct {
+	reserved []reservationsRepo.ReserveParams
+}
+
+func (r *recordingReservationsRepository) Reservations(
+	ctx context.Context,
+	params reservationsRepo.ReservationsParams,
+) (*models.Page[*models.Reservation], error) {
+	return &models.Page[*models.Reservation]{}, nil
+}
+
+func (r *recordingReservationsRepository) Reserve(ctx context.Context, params reservationsRepo.ReserveParams) error {
+	r.reserved = append(r.reserved, params)
+
+	return nil
+}
+
+func (r *recordingReservationsRepository) Cancel(ctx context.Context, params reservationsRepo.CancelParams) error {
+	return nil
+}
+
+func (r *recordingReservationsRepository) Release(ctx context.Context, params reservationsRepo.ReleaseParams) error {
+	return nil
+}
+
+// recordingStoragesRepository records storages filter passed to repository
+type recordingStoragesRepository struct {
+	ids uuid.UUIDs
+}
+
+func (r *recordingStoragesRepository) Storages(
+	ctx context.Context,
+	params storagesRepo.StoragesParams,
+) (*models.Page[*models.Storage], error) {
+	r.ids = params.Ids
+
+	return &models.Page[*models.Storage]{}, nil
+}
+
+func TestStorageAuthorization(t *testing.T) {
+	t.Log("Test: storage authorization\n")
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	permitted, other := uuid.New(), uuid.New()
+
+	restricted := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Name:     "restricted",
+		Scopes:   []string{auth.ScopeRead, auth.ScopeReserve},
+		Storages: uuid.UUIDs{permitted},
+	})
+	unrestricted := auth.WithPrincipal(context.Background(), &auth.Principal{
+		Name:        "unrestricted",
+		Scopes:      []string{auth.ScopeRead, auth.ScopeReserve},
+		AllStorages: true,
+	})
+
+	reserve := func(ctx context.Context, storageId uuid.UUID) (*recordingReservationsRepository, error) {
+		repository := &recordingReservationsRepository{}
+
+		params := interactors.ReserveParams{
+			ProductIds: []string{uuid.NewString()},
+			ShippingId: uuid.NewString(),
+			Amount:     1,
+		}
+		if storageId != uuid.Nil {
+			params.StorageId = storageId.String()
+		}
+
+		err := interactors.NewReservationInteractor(log, repository).Reserve(ctx, params)
+
+		return repository, err
+	}
+
+	storages := func(ctx context.Context, ids ...string) (*recordingStoragesRepository, error) {
+		repository := &recordingStoragesRepository{}
+
+		_, err := interactors.NewStorageInteractor(log, repository).Storages(ctx, interactors.StoragesParams{Ids: ids})
+
+		return repository, err
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Permitted storage": func(t *testing.T) {
+			repository, err := reserve(restricted, permitted)
+			if err != nil {
+				t.Fatal("error reserve in permitted storage", err)
+			}
+
+			if len(repository.reserved) != 1 || repository.reserved[0].StorageId != permitted {
+				t.Fatal("error wrong reservation", repository.reserved)
+			}
+		},
+		"Other storage": func(t *testing.T) {
+			repository, err := reserve(restricted, other)
+			if !errors.Is(err, auth.ErrorForbidden) {
+				t.Fatal("error forbidden expected", err)
+			}
+
+			if len(repository.reserved) != 0 {
+				t.Fatal("error reserved in other storage")
+			}
+		},
+		"Missing storage": func(t *testing.T) {
+			if _, err := reserve(restricted, uuid.Nil); !errors.Is(err, auth.ErrorForbidden) {
+				t.Fatal("error forbidden expected", err)
+			}
+		},
+		"All storages": func(t *testing.T) {
+			if _, err := reserve(unrestricted, other); err != nil {
+				t.Fatal("error reserve in any storage", err)
+			}
+
+			if _, err := reserve(unrestricted, uuid.Nil); err != nil {
+				t.Fatal("error reserve without storage", err)
+			}
+		},
+		"Authentication disabled": func(t *testing.T) {
+			if _, err := reserve(context.Background(), other); err != nil {
+				t.Fatal("error reserve without principal", err)
+			}
+		},
+		"Listing is narrowed to permitted storages": func(t *testing.T) {
+			repository, err := storages(restricted)
+			if err != nil {
+				t.Fatal("error list storages", err)
+			}
+
+			if len(repository.ids) != 1 || repository.ids[0] != permitted {
+				t.Fatal("error listing is not narrowed", repository.ids)
+			}
+
+			if _, err = storages(restricted, permitted.String(), other.String()); !errors.Is(err, auth.ErrorForbidden) {
+				t.Fatal("error forbidden expected", err)
+			}
+
+			if repository, err = storages(unrestricted); err != nil || len(repository.ids) != 0 {
+				t.Fatal("error listing is narrowed for unrestricted client", repository.ids, err)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
@@ -0,0 +1,475 @@
+package tests
+
+import (
+	"bytes"
+	"cernunnos/internal/middleware"
+	"cernunnos/internal/pkg/config"
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/server"
+	"cernunnos/internal/server/interface/controllers"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	"cernunnos/internal/usecase/repository/reservations"
+	"cernunnos/pkg/client"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pagedStorageInteractor serves storages it holds by pages. Cursor is the index of the next storage
+type pagedStorageInteractor struct {
+	interactors.StorageInteractor
+
+	storages []*models.Storage
+}
+
+func (i *pagedStorageInteractor) Storages(
+	ctx context.Context,
+	params interactors.StoragesParams,
+) (*models.Page[*models.Storage], error) {
+	start := 0
+
+	if params.Cursor != "" {
+		var err error
+
+		start, err = strconv.Atoi(params.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("error parse cursor. %w", interactors.ErrorInvalidField)
+		}
+	}
+
+	end := min(start+int(params.Limit), len(i.storages))
+	page := &models.Page[*models.Storage]{Items: i.storages[start:end]}
+
+	if end < len(i.storages) {
+		page.NextCursor = strconv.Itoa(end)
+	}
+
+	return page, nil
+}
+
+// stockReservationInteractor reserves products while the stock lasts
+type stockReservationInteractor struct {
+	interactors.ReservationInteractor
+
+	available atomic.Int64
+	reserved  atomic.Int32 // Successful reservations
+}
+
+func (i *stockReservationInteractor) Reserve(ctx context.Context, params interactors.ReserveParams) error {
+	if i.available.Add(-params.Amount) < 0 {
+		i.available.Add(params.Amount)
+
+		return fmt.Errorf("error reserve. %w", reservations.ErrorNotEnoughProducts)
+	}
+
+	i.reserved.Add(1)
+
+	return nil
+}
+
+// memoryIdempotencyStore keeps idempotent requests in memory. Completed keys never expire
+type memoryIdempotencyStore struct {
+	mu       sync.Mutex
+	requests map[string]*models.IdempotentRequest
+}
+
+func (s *memoryIdempotencyStore) Claim(
+	ctx context.Context,
+	request *models.IdempotentRequest,
+	expiredBefore, abandonedBefore time.Time,
+) (*models.IdempotentRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.requests[request.Client+request.Key]; ok &&
+		(stored.StatusCode != 0 || !stored.CreatedAt.Before(abandonedBefore)) {
+		return stored, nil
+	}
+
+	claimed := *request
+	s.requests[request.Client+request.Key] = &claimed
+
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(
+	ctx context.Context,
+	claim *models.IdempotentRequest,
+	statusCode int,
+	response []byte,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.requests[claim.Client+claim.Key]
+	if !ok || !stored.CreatedAt.Equal(claim.CreatedAt) {
+		return errs.ErrorIdempotencyClaimLost
+	}
+
+	stored.StatusCode, stored.Response = statusCode, bytes.Clone(response)
+
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, claim *models.IdempotentRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.requests[claim.Client+claim.Key]
+	if !ok || !stored.CreatedAt.Equal(claim.CreatedAt) {
+		return errs.ErrorIdempotencyClaimLost
+	}
+
+	delete(s.requests, claim.Client+claim.Key)
+
+	return nil
+}
+
+// lossyTransport loses responses of the first requests, as if connections were broken after the
+// requests were handled. Responses of the last request are kept.
+type lossyTransport struct {
+	lose atomic.Int32 // Responses left to lose
+
+	mu        sync.Mutex
+	keys      []string // Idempotency keys of sent requests
+	responses []*http.Response
+}
+
+func (t *lossyTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.keys = append(t.keys, request.Header.Get(middleware.IdempotencyKeyHeader))
+	t.mu.Unlock()
+
+	response, err := http.DefaultTransport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.lose.Add(-1) >= 0 {
+		response.Body.Close()
+
+		return nil, errors.New("connection reset by peer")
+	}
+
+	t.mu.Lock()
+	t.responses = append(t.responses, response)
+	t.mu.Unlock()
+
+	return response, nil
+}
+
+// unavailableTransport responds as an overloaded proxy, asking to retry later
+type unavailableTransport struct {
+	attempts atomic.Int32
+}
+
+func (t *unavailableTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.attempts.Add(1)
+
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": {"5"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte("upstream is overloaded"))),
+		Request:    request,
+	}, nil
+}
+
+// newAPIServer serves HTTP API over the interactors without authentication
+func newAPIServer(
+	storageInteractor interactors.StorageInteractor,
+	reservationInteractor interactors.ReservationInteractor,
+	idempotency *memoryIdempotencyStore,
+) *httptest.Server {
+	cfg := config.Default()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return httptest.NewServer(server.New(
+		&cfg,
+		log,
+		controllers.NewRootController(
+			nil,
+			controllers.NewReservationController(log, reservationInteractor, presenters.NewReservationPresenter()),
+			controllers.NewStorageController(log, storageInteractor, presenters.NewStoragePresenter()),
+			nil,
+			nil,
+			nil,
+			nil,
+		),
+		health.NewChecker("test"),
+		nil,
+		idempotency,
+		nil,
+	))
+}
+
+func TestClient(t *testing.T) {
+	t.Log("Test: go client against in-process server\n")
+
+	storages := make([]*models.Storage, 5)
+	for i := range storages {
+		storages[i] = &models.Storage{Id: uuid.New(), Name: fmt.Sprint("Storage ", i), Available: 10}
+	}
+
+	reservationInteractor := &stockReservationInteractor{}
+	reservationInteractor.available.Store(10)
+
+	idempotency := &memoryIdempotencyStore{requests: make(map[string]*models.IdempotentRequest)}
+
+	httpServer := newAPIServer(&pagedStorageInteractor{storages: storages}, reservationInteractor, idempotency)
+	defer httpServer.Close()
+
+	newClient := func(t *testing.T, transport http.RoundTripper) *client.Client {
+		c, err := client.New(httpServer.URL, client.Options{
+			HTTPClient:   &http.Client{Transport: transport},
+			RetryBackoff: time.Millisecond,
+		})
+		if err != nil {
+			t.Fatal("error create client", err)
+		}
+
+		return c
+	}
+
+	reserve := func(amount int64) *client.ReserveRequest {
+		return &client.ReserveRequest{
+			Products:   []string{uuid.NewString()},
+			ShippingId: uuid.NewString(),
+			Amount:     amount,
+		}
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Iterator walks all pages": func(t *testing.T) {
+			it := newClient(t, http.DefaultTransport).StoragesIterator(&client.StoragesRequest{Limit: 2})
+
+			var names []string
+
+			for it.Next(context.Background()) {
+				names = append(names, it.Item().Name)
+			}
+
+			if err := it.Err(); err != nil {
+				t.Fatal("error iterate storages", err)
+			}
+
+			if len(names) != len(storages) || names[0] != "Storage 0" || names[4] != "Storage 4" {
+				t.Fatal("error wrong storages", names)
+			}
+		},
+		"API errors are typed": func(t *testing.T) {
+			_, err := newClient(t, http.DefaultTransport).Reserve(context.Background(), reserve(100))
+			if !errors.Is(err, client.ErrNotEnoughProducts) {
+				t.Fatal("error not enough products expected", err)
+			}
+
+			var apiErr *client.APIError
+
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict || apiErr.Details == "" {
+				t.Fatal("error wrong api error", err)
+			}
+
+			_, err = newClient(t, http.DefaultTransport).Storages(
+				context.Background(), &client.StoragesRequest{Cursor: "not a number"},
+			)
+			if !errors.Is(err, client.ErrInvalidRequest) {
+				t.Fatal("error invalid request expected", err)
+			}
+		},
+		"Retried write is applied once": func(t *testing.T) {
+			transport := &lossyTransport{}
+			transport.lose.Store(2)
+
+			reservedBefore := reservationInteractor.reserved.Load()
+
+			response, err := newClient(t, transport).Reserve(context.Background(), reserve(1))
+			if err != nil || !response.Ok {
+				t.Fatal("error reserve", err)
+			}
+
+			if reserved := reservationInteractor.reserved.Load() - reservedBefore; reserved != 1 {
+				t.Fatal("error write applied", reserved, "times")
+			}
+
+			if len(transport.keys) != 3 || transport.keys[0] == "" ||
+				transport.keys[0] != transport.keys[1] || transport.keys[1] != transport.keys[2] {
+				t.Fatal("error attempts sent with different keys", transport.keys)
+			}
+
+			if transport.responses[0].Header.Get(middleware.IdempotentReplayedHeader) != "true" {
+				t.Fatal("error response is not replayed")
+			}
+		},
+		"Reused key is rejected": func(t *testing.T) {
+			send := func(body string) int {
+				request, err := http.NewRequest(
+					http.MethodPost, httpServer.URL+"/reservations/new", bytes.NewReader([]byte(body)),
+				)
+				if err != nil {
+					t.Fatal("error build request", err)
+				}
+
+				request.Header.Set(middleware.IdempotencyKeyHeader, "reused")
+
+				response, err := http.DefaultClient.Do(request)
+				if err != nil {
+					t.Fatal("error send request", err)
+				}
+
+				response.Body.Close()
+
+				return response.StatusCode
+			}
+
+			body := fmt.Sprintf(`{"products":["%s"],"shipping_id":"%s","amount":1}`, uuid.New(), uuid.New())
+
+			if code := send(body); code != http.StatusOK {
+				t.Fatal("error reserve", code)
+			}
+
+			if code := send(body); code != http.StatusOK {
+				t.Fatal("error replay", code)
+			}
+
+			if code := send(`{"amount":2}`); code != http.StatusUnprocessableEntity {
+				t.Fatal("error key reused with another request", code)
+			}
+		},
+		"Key left by crashed request is claimed again": func(t *testing.T) {
+			body := fmt.Sprintf(`{"products":["%s"],"shipping_id":"%s","amount":1}`, uuid.New(), uuid.New())
+
+			send := func(key string) int {
+				request, err := http.NewRequest(
+					http.MethodPost, httpServer.URL+"/reservations/new", bytes.NewReader([]byte(body)),
+				)
+				if err != nil {
+					t.Fatal("error build request", err)
+				}
+
+				request.Header.Set(middleware.IdempotencyKeyHeader, key)
+
+				response, err := http.DefaultClient.Do(request)
+				if err != nil {
+					t.Fatal("error send request", err)
+				}
+
+				response.Body.Close()
+
+				return response.StatusCode
+			}
+
+			hash := sha256.Sum256([]byte("POST /reservations/new\n" + body))
+
+			// Claims of requests crashed before completing or releasing keys
+			claim := func(key string, at time.Time) {
+				idempotency.mu.Lock()
+				defer idempotency.mu.Unlock()
+
+				idempotency.requests["ip:127.0.0.1"+key] = &models.IdempotentRequest{
+					Client: "ip:127.0.0.1", Key: key, RequestHash: hex.EncodeToString(hash[:]), CreatedAt: at,
+				}
+			}
+
+			claim("crashed-now", time.Now().UTC())
+			claim("crashed-before", time.Now().UTC().Add(-time.Hour))
+
+			if code := send("crashed-now"); code != http.StatusConflict {
+				t.Fatal("error key in progress expected", code)
+			}
+
+			if code := send("crashed-before"); code != http.StatusOK {
+				t.Fatal("error reclaim abandoned key", code)
+			}
+
+			if code := send("crashed-before"); code != http.StatusOK {
+				t.Fatal("error replay reclaimed key", code)
+			}
+		},
+		"Request outliving its lease keeps reclaimed key": func(t *testing.T) {
+			idempotency := &memoryIdempotencyStore{requests: make(map[string]*models.IdempotentRequest)}
+			builder := middleware.NewMiddlewareBuilder(
+				slog.New(slog.NewTextHandler(io.Discard, nil)), errs.NewErrorHandler(), nil, idempotency, time.Hour,
+			)
+
+			// reclaimed is a claim of a later request, made while the stale request is handled
+			var reclaimed *models.IdempotentRequest
+
+			reclaim := func(key string) {
+				idempotency.mu.Lock()
+				defer idempotency.mu.Unlock()
+
+				claimed := *idempotency.requests["ip:192.0.2.1"+key]
+				claimed.CreatedAt = claimed.CreatedAt.Add(time.Minute)
+
+				reclaimed = &claimed
+				idempotency.requests["ip:192.0.2.1"+key] = reclaimed
+			}
+
+			serve := func(key string, handler http.HandlerFunc) {
+				defer func() { _ = recover() }()
+
+				request := httptest.NewRequest(http.MethodPost, "/reservations/new", strings.NewReader("{}"))
+				request.Header.Set(middleware.IdempotencyKeyHeader, key)
+
+				builder.Idempotency(handler).ServeHTTP(httptest.NewRecorder(), request)
+			}
+
+			serve("completed", func(w http.ResponseWriter, r *http.Request) {
+				reclaim("completed")
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			if stored := idempotency.requests["ip:192.0.2.1completed"]; stored != reclaimed || stored.StatusCode != 0 {
+				t.Fatal("error stale request completed reclaimed key", stored)
+			}
+
+			serve("panicked", func(w http.ResponseWriter, r *http.Request) {
+				reclaim("panicked")
+				panic("handler failed")
+			})
+
+			if stored := idempotency.requests["ip:192.0.2.1panicked"]; stored != reclaimed {
+				t.Fatal("error stale request released reclaimed key")
+			}
+		},
+		"Retries do not outlive deadline": func(t *testing.T) {
+			transport := &unavailableTransport{}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+
+			_, err := newClient(t, transport).Storages(ctx, nil)
+			if !errors.Is(err, client.ErrUnavailable) {
+				t.Fatal("error unavailable expected", err)
+			}
+
+			// Retry-After exceeds the deadline, so the request is not retried
+			if attempts := transport.attempts.Load(); attempts != 1 || time.Since(start) > time.Second {
+				t.Fatal("error retried past deadline", attempts, time.Since(start))
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
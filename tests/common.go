@@ -14,6 +14,11 @@ var cfg config.Config = config.Config{
 	DatabaseHost:     "cernunnos-db:5432",
 	DatabaseUser:     "cernunnos",
 	DatabasePassword: "cernunnos",
+	Database: config.Database{
+		Name:            "cernunnos",
+		SSLMode:         "disable",
+		ConnectAttempts: 1,
+	},
 }
 
 type insertProductsParams struct {
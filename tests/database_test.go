@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/usecase/repository"
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDatabaseConnection(t *testing.T) {
+	t.Log("Test: database connection options\n")
+
+	parseDSN := func(t *testing.T, cfg *config.Config, host string) *url.URL {
+		dsn, err := url.Parse(repository.DSN(cfg, host))
+		if err != nil {
+			t.Fatal("error parse dsn", err)
+		}
+
+		return dsn
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Default options": func(t *testing.T) {
+			cfg := config.Default()
+			cfg.DatabaseUser = "cernunnos"
+			cfg.DatabasePassword = "password"
+
+			dsn := parseDSN(t, &cfg, "db:5432")
+
+			password, _ := dsn.User.Password()
+			if dsn.Scheme != "postgresql" || dsn.Host != "db:5432" || dsn.User.Username() != "cernunnos" ||
+				password != "password" || dsn.Path != "/"+cfg.Database.Name {
+				t.Fatal("error wrong dsn", dsn.Redacted())
+			}
+
+			query := dsn.Query()
+			if query.Get("sslmode") != cfg.Database.SSLMode {
+				t.Fatal("error wrong sslmode", query)
+			}
+
+			for _, key := range []string{"sslrootcert", "sslcert", "sslkey"} {
+				if query.Has(key) {
+					t.Fatal("error empty option passed", key)
+				}
+			}
+		},
+		"Credentials are escaped": func(t *testing.T) {
+			cfg := config.Default()
+			cfg.DatabaseUser = "user@corp"
+			cfg.DatabasePassword = "p@ss/word?#"
+
+			dsn := parseDSN(t, &cfg, "db")
+
+			password, _ := dsn.User.Password()
+			if dsn.User.Username() != "user@corp" || password != "p@ss/word?#" || dsn.Host != "db" {
+				t.Fatal("error credentials are not escaped", dsn.Redacted())
+			}
+		},
+		"TLS and session options": func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Database.SSLMode = "verify-full"
+			cfg.Database.SSLRootCert = "/etc/ssl/ca.pem"
+			cfg.Database.SSLCert = "/etc/ssl/client.pem"
+			cfg.Database.SSLKey = "/etc/ssl/client.key"
+			cfg.Database.ApplicationName = "cernunnos api"
+			cfg.Database.StatementTimeout = 1500 * time.Millisecond
+
+			query := parseDSN(t, &cfg, "db").Query()
+
+			expected := map[string]string{
+				"sslmode":           "verify-full",
+				"sslrootcert":       "/etc/ssl/ca.pem",
+				"sslcert":           "/etc/ssl/client.pem",
+				"sslkey":            "/etc/ssl/client.key",
+				"application_name":  "cernunnos api",
+				"statement_timeout": "1500",
+			}
+
+			for key, value := range expected {
+				if query.Get(key) != value {
+					t.Fatal("error wrong option", key, query.Get(key))
+				}
+			}
+		},
+		"No statement timeout": func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Database.StatementTimeout = 0
+
+			if query := parseDSN(t, &cfg, "db").Query(); query.Has("statement_timeout") {
+				t.Fatal("error statement timeout passed", query)
+			}
+		},
+		"Replica host": func(t *testing.T) {
+			cfg := config.Default()
+			cfg.DatabaseHost = "primary"
+			cfg.Database.ReplicaHost = "replica"
+
+			if dsn := parseDSN(t, &cfg, cfg.Database.ReplicaHost); dsn.Host != "replica" {
+				t.Fatal("error wrong replica host", dsn.Host)
+			}
+		},
+		"No replica configured": func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Database.ReplicaHost = ""
+
+			replica, cleanup, err := repository.ProvideReplicaConnection(&cfg)
+			if err != nil {
+				t.Fatal("error provide replica", err)
+			}
+
+			defer cleanup()
+
+			if replica.DB != nil {
+				t.Fatal("error replica connected")
+			}
+		},
+		"Pool limits": func(t *testing.T) {
+			db := openStubDB(t, "primary")
+
+			repository.ConfigurePool(db, config.Database{
+				MaxOpenConns: 3, MaxIdleConns: 1, ConnMaxLifetime: time.Minute,
+			})
+
+			conns := make([]*sql.Conn, 0, 3)
+
+			for range 3 {
+				conn, err := db.Conn(context.Background())
+				if err != nil {
+					t.Fatal("error open connection", err)
+				}
+
+				conns = append(conns, conn)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			if _, err := db.Conn(ctx); err == nil {
+				t.Fatal("error connection opened over max open connections")
+			}
+
+			for _, conn := range conns {
+				conn.Close()
+			}
+
+			stats := db.Stats()
+			if stats.MaxOpenConnections != 3 || stats.Idle != 1 || stats.MaxIdleClosed != 2 {
+				t.Fatal("error wrong pool stats", stats)
+			}
+		},
+		"Unreachable database": func(t *testing.T) {
+			cfg := config.Default()
+			cfg.DatabaseHost = "127.0.0.1:1"
+			cfg.Database.ConnectAttempts = 2
+			cfg.Database.ConnectRetryDelay = 10 * time.Millisecond
+
+			_, cleanup, err := repository.ProvideDatabaseConnection(&cfg)
+			defer cleanup()
+
+			if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+				t.Fatal("error unreachable database connected", err)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
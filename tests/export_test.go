@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"bytes"
+	"cernunnos/commands/exports"
+	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/server"
+	"cernunnos/internal/server/interface/controllers"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	exportsRepo "cernunnos/internal/usecase/repository/exports"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// memoryExportsRepository streams stock rows from memory. Streaming fails after failAfter rows,
+// if it is set.
+type memoryExportsRepository struct {
+	rows      []*models.StorageProduct
+	failAfter int
+	filter    exportsRepo.StockFilter
+}
+
+func (r *memoryExportsRepository) Stock(
+	ctx context.Context,
+	filter exportsRepo.StockFilter,
+	fn func(product *models.StorageProduct) error,
+) error {
+	r.filter = filter
+
+	for i, row := range r.rows {
+		if r.failAfter > 0 && i == r.failAfter {
+			return errors.New("connection lost")
+		}
+
+		if len(filter.StorageIds) > 0 && !slices.Contains(filter.StorageIds, row.Storage.Id) {
+			continue
+		}
+
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	t.Log("Test: stock export to CSV and JSONL\n")
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	storages := []*models.Storage{
+		{Id: uuid.New(), Name: "Main", Available: 100, Reserved: 5},
+		{Id: uuid.New(), Name: "North, spare", Available: 50},
+	}
+
+	repository := new(memoryExportsRepository)
+
+	for i, storage := range storages {
+		for range 3 {
+			product := &models.StorageProduct{ProductDestribution: models.ProductDestribution{Storage: storage}}
+			product.Id, product.Name, product.Size = uuid.New(), "Chair", 12
+			product.Amount, product.Reserved, product.Available = 10, int64(i), 10-int64(i)
+
+			repository.rows = append(repository.rows, product)
+		}
+	}
+
+	interactor := interactors.NewExportInteractor(log, repository)
+
+	cfg := config.Default()
+	httpServer := httptest.NewServer(server.New(
+		&cfg,
+		log,
+		controllers.NewRootController(
+			nil, nil, nil, nil, nil, nil,
+			controllers.NewExportController(log, interactor, presenters.NewExportPresenter()),
+		),
+		health.NewChecker("test"),
+		nil,
+		nil,
+		nil,
+	))
+	defer httpServer.Close()
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"CSV export": func(t *testing.T) {
+			response, err := http.Get(httpServer.URL + "/exports/stock")
+			if err != nil {
+				t.Fatal("error export stock", err)
+			}
+			defer response.Body.Close()
+
+			contentType := response.Header.Get("Content-Type")
+			if response.StatusCode != http.StatusOK || contentType != "text/csv; charset=utf-8" {
+				t.Fatal("error wrong response", response.StatusCode, response.Header)
+			}
+
+			if !strings.Contains(response.Header.Get("Content-Disposition"), `.csv"`) {
+				t.Fatal("error wrong file name", response.Header.Get("Content-Disposition"))
+			}
+
+			records, err := csv.NewReader(response.Body).ReadAll()
+			if err != nil {
+				t.Fatal("error read csv", err)
+			}
+
+			if len(records) != 7 || records[0][0] != "storage_id" || records[4][1] != "North, spare" {
+				t.Fatal("error wrong csv", records)
+			}
+		},
+		"JSONL export filtered by storage": func(t *testing.T) {
+			query := "?format=jsonl&storage_id=" + storages[1].Id.String()
+
+			response, err := http.Get(httpServer.URL + "/exports/stock" + query)
+			if err != nil {
+				t.Fatal("error export stock", err)
+			}
+			defer response.Body.Close()
+
+			if response.Header.Get("Content-Type") != "application/x-ndjson" {
+				t.Fatal("error wrong content type", response.Header)
+			}
+
+			decoder := json.NewDecoder(response.Body)
+
+			var rows []dto.StockRow
+
+			for decoder.More() {
+				var row dto.StockRow
+
+				if err = decoder.Decode(&row); err != nil {
+					t.Fatal("error decode row", err)
+				}
+
+				rows = append(rows, row)
+			}
+
+			if len(rows) != 3 || rows[0].StorageId != storages[1].Id.String() || rows[0].Available != 9 {
+				t.Fatal("error wrong rows", rows)
+			}
+		},
+		"Invalid request": func(t *testing.T) {
+			for _, query := range []string{"format=xlsx", "storage_id=main"} {
+				response, err := http.Get(httpServer.URL + "/exports/stock?" + query)
+				if err != nil {
+					t.Fatal("error export stock", err)
+				}
+				response.Body.Close()
+
+				if response.StatusCode != http.StatusBadRequest || response.Header.Get("Content-Disposition") != "" {
+					t.Fatal("error invalid request accepted", query, response.StatusCode)
+				}
+			}
+		},
+		"Failed export is aborted": func(t *testing.T) {
+			// Rows are flushed by chunks, so the file is started before the failure
+			for range 1000 {
+				repository.rows = append(repository.rows, repository.rows[0])
+			}
+			defer func() { repository.rows = repository.rows[:6] }()
+
+			repository.failAfter = 900
+			defer func() { repository.failAfter = 0 }()
+
+			response, err := http.Get(httpServer.URL + "/exports/stock")
+			if err != nil {
+				t.Fatal("error export stock", err)
+			}
+			defer response.Body.Close()
+
+			if _, err = io.ReadAll(response.Body); err == nil {
+				t.Fatal("error truncated export is not aborted")
+			}
+		},
+		"Command writes file": func(t *testing.T) {
+			command := exports.NewExportCommand(interactor, presenters.NewExportPresenter())
+			name := filepath.Join(t.TempDir(), "stock.jsonl")
+
+			params := interactors.ExportStockParams{}
+
+			err := command.StockFile(context.Background(), name, presenters.ExportJSONL, params)
+			if err != nil {
+				t.Fatal("error export stock", err)
+			}
+
+			file, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal("error read file", err)
+			}
+
+			if bytes.Count(file, []byte("\n")) != 6 {
+				t.Fatal("error wrong file", string(file))
+			}
+
+			repository.failAfter = 2
+			defer func() { repository.failAfter = 0 }()
+
+			err = command.StockFile(context.Background(), name, presenters.ExportCSV, params)
+			if err == nil {
+				t.Fatal("error failed export succeeded")
+			}
+
+			entries, _ := os.ReadDir(filepath.Dir(name))
+			if current, _ := os.ReadFile(name); len(entries) != 1 || !bytes.Equal(current, file) {
+				t.Fatal("error failed export replaced file", entries)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/sqltools"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+func TestFilters(t *testing.T) {
+	t.Log("Test: listings filtering and sorting\n")
+
+	spec := sqltools.FilterSpec{
+		Fields: map[string]string{
+			"name": "coalesce(p.name, '')",
+			"size": "p.size",
+		},
+		CreatedAt: "p.created_at",
+		Id:        "p.id",
+	}
+
+	query := sq.Select("p.id").From("products as p").PlaceholderFormat(sq.Dollar)
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Sort parsing": func(t *testing.T) {
+			for raw, expected := range map[string]sqltools.Sort{
+				"name":       {Field: "name"},
+				" -name ":    {Field: "name", Desc: true},
+				"size:desc":  {Field: "size", Desc: true},
+				"size:ASC":   {Field: "size"},
+				"-size:desc": {Field: "size", Desc: true},
+			} {
+				sort, err := sqltools.ParseSort(raw)
+				if err != nil {
+					t.Fatal("error parse sort", raw, err)
+				}
+
+				if *sort != expected {
+					t.Fatal("error wrong sort", raw, sort)
+				}
+			}
+
+			if sort, err := sqltools.ParseSort(""); sort != nil || err != nil {
+				t.Fatal("error empty sort is not default", sort, err)
+			}
+
+			for _, raw := range []string{"-", "name:up", ":desc"} {
+				if _, err := sqltools.ParseSort(raw); !errors.Is(err, sqltools.ErrorInvalidFilter) {
+					t.Fatal("error invalid sort accepted", raw, err)
+				}
+			}
+		},
+		"Fields are whitelisted": func(t *testing.T) {
+			_, err := spec.Apply(query, []sqltools.Condition{
+				{Field: "name; drop table products", Operator: sqltools.OperatorEq, Value: "x"},
+			})
+			if !errors.Is(err, sqltools.ErrorInvalidFilter) {
+				t.Fatal("error unknown field accepted", err)
+			}
+
+			_, err = spec.Apply(query, []sqltools.Condition{{Field: "size", Operator: "like", Value: 1}})
+			if !errors.Is(err, sqltools.ErrorInvalidFilter) {
+				t.Fatal("error unknown operator accepted", err)
+			}
+
+			_, err = sqltools.Pagination{Sort: &sqltools.Sort{Field: "reserved"}}.Apply(query, spec)
+			if !errors.Is(err, sqltools.ErrorInvalidFilter) {
+				t.Fatal("error unknown sort field accepted", err)
+			}
+		},
+		"LIKE patterns are escaped": func(t *testing.T) {
+			filtered, err := spec.Apply(query, []sqltools.Condition{
+				{Field: "name", Operator: sqltools.OperatorPrefix, Value: `50%_off\`},
+				{Field: "name", Operator: sqltools.OperatorContains, Value: "a_b"},
+				{Field: "size", Operator: sqltools.OperatorGte, Value: 10},
+			})
+			if err != nil {
+				t.Fatal("error apply filter", err)
+			}
+
+			sql, args, err := filtered.ToSql()
+			if err != nil {
+				t.Fatal("error build query", err)
+			}
+
+			if !strings.Contains(sql, "coalesce(p.name, '') ILIKE $1") || !strings.Contains(sql, "p.size >= $3") {
+				t.Fatal("error wrong predicate", sql)
+			}
+
+			if args[0] != `50\%\_off\\%` || args[1] != `%a\_b%` {
+				t.Fatal("error wrong patterns", args)
+			}
+		},
+		"Keyset paging under a custom sort": func(t *testing.T) {
+			cursor := &sqltools.Cursor{Field: "name", Value: "Chair", CreatedAt: time.Now(), Id: uuid.New()}
+
+			paginated, err := sqltools.Pagination{
+				Sort:   &sqltools.Sort{Field: "name", Desc: true},
+				Cursor: cursor,
+				Limit:  10,
+				Offset: 20,
+			}.Apply(query, spec)
+			if err != nil {
+				t.Fatal("error apply pagination", err)
+			}
+
+			sql, args, err := paginated.ToSql()
+			if err != nil {
+				t.Fatal("error build query", err)
+			}
+
+			if !strings.Contains(sql, "(coalesce(p.name, ''), p.created_at, p.id) < ($1, $2, $3)") {
+				t.Fatal("error wrong keyset condition", sql)
+			}
+
+			if !strings.Contains(sql, "ORDER BY coalesce(p.name, '') DESC, p.created_at DESC, p.id DESC") {
+				t.Fatal("error wrong ordering", sql)
+			}
+
+			if strings.Contains(sql, "OFFSET") || len(args) != 3 || args[0] != "Chair" {
+				t.Fatal("error offset applied along with cursor", sql, args)
+			}
+
+			_, err = sqltools.Pagination{Sort: &sqltools.Sort{Field: "size"}, Cursor: cursor}.Apply(query, spec)
+			if !errors.Is(err, sqltools.ErrorInvalidCursor) {
+				t.Fatal("error cursor of another sort accepted", err)
+			}
+
+			_, err = sqltools.Pagination{Cursor: cursor}.Apply(query, spec)
+			if !errors.Is(err, sqltools.ErrorInvalidCursor) {
+				t.Fatal("error cursor of a sort accepted for default ordering", err)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
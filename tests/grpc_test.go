@@ -0,0 +1,211 @@
+package tests
+
+import (
+	"cernunnos/internal/grpcserver"
+	"cernunnos/internal/pkg/auth"
+	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/usecase/interactors"
+	"cernunnos/internal/usecase/repository/reservations"
+	pb "cernunnos/pkg/pb/cernunnos/v1"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// storageInteractor serves storages it holds
+type storageInteractor struct {
+	interactors.StorageInteractor
+
+	storages []*models.Storage
+}
+
+func (i *storageInteractor) Storages(
+	ctx context.Context,
+	params interactors.StoragesParams,
+) (*models.Page[*models.Storage], error) {
+	if params.Cursor != "" {
+		return nil, fmt.Errorf("error parse cursor. %w", interactors.ErrorInvalidField)
+	}
+
+	total := int64(len(i.storages))
+
+	return &models.Page[*models.Storage]{Items: i.storages, Total: &total}, nil
+}
+
+// reservationInteractor rejects any reservation as there is no space left
+type reservationInteractor struct {
+	interactors.ReservationInteractor
+}
+
+func (i *reservationInteractor) Reserve(ctx context.Context, params interactors.ReserveParams) error {
+	return fmt.Errorf("error reserve. %w", reservations.ErrorNotEnoughSpace)
+}
+
+// keysAuthenticator authenticates clients by keys it holds
+type keysAuthenticator map[string]*auth.Principal
+
+func (a keysAuthenticator) Authenticate(ctx context.Context, credential string) (*auth.Principal, error) {
+	principal, ok := a[credential]
+	if !ok {
+		return nil, auth.ErrorUnauthorized
+	}
+
+	return principal, nil
+}
+
+func TestGRPC(t *testing.T) {
+	t.Log("Test: grpc api\n")
+
+	storage := &models.Storage{
+		Id:        uuid.New(),
+		Name:      "Main",
+		Available: 10,
+		CreatedAt: time.UnixMilli(1700000000000),
+		UpdatedAt: time.UnixMilli(1700000000000),
+	}
+
+	cfg := config.Default()
+
+	server := grpcserver.New(
+		slog.Default(),
+		grpcserver.Options{
+			Config: &cfg,
+			Authenticator: keysAuthenticator{
+				"reader": {Id: "reader", Name: "reader", Scopes: []string{auth.ScopeRead}, AllStorages: true},
+				"admin":  {Id: "admin", Name: "admin", Scopes: []string{auth.ScopeAdmin}, AllStorages: true},
+			},
+			Checker: health.NewChecker("test"),
+		},
+		&storageInteractor{storages: []*models.Storage{storage}},
+		nil,
+		&reservationInteractor{},
+	)
+
+	listener := bufconn.Listen(1 << 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	served := make(chan error, 1)
+
+	go func() {
+		served <- server.Serve(ctx, listener)
+	}()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal("error create client", err)
+	}
+
+	defer conn.Close()
+
+	storages := pb.NewStorageServiceClient(conn)
+	reservationsClient := pb.NewReservationServiceClient(conn)
+
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+
+	expectCode := func(t *testing.T, err error, code codes.Code) {
+		if status.Code(err) != code {
+			t.Fatalf("error wrong status code. expected %s, got %v", code, err)
+		}
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Normal case": func(t *testing.T) {
+			response, err := storages.ListStorages(withKey("reader"), &pb.ListStoragesRequest{
+				Page: &pb.PageRequest{WithTotal: true},
+			})
+			if err != nil {
+				t.Fatal("error list storages", err)
+			}
+
+			if len(response.Storages) != 1 || response.Storages[0].Id != storage.Id.String() ||
+				response.Storages[0].Available != 10 || response.Storages[0].CreatedAt != 1700000000000 {
+				t.Fatal("error wrong storages", response.Storages)
+			}
+
+			if response.Page.GetTotal() != 1 {
+				t.Fatal("error wrong total", response.Page)
+			}
+		},
+		"Bearer token": func(t *testing.T) {
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer reader")
+
+			if _, err := storages.ListStorages(ctx, &pb.ListStoragesRequest{}); err != nil {
+				t.Fatal("error list storages with bearer token", err)
+			}
+		},
+		"Invalid field": func(t *testing.T) {
+			_, err := storages.ListStorages(withKey("reader"), &pb.ListStoragesRequest{
+				Page: &pb.PageRequest{Cursor: "broken"},
+			})
+
+			expectCode(t, err, codes.InvalidArgument)
+		},
+		"Not enough space": func(t *testing.T) {
+			_, err := reservationsClient.Reserve(withKey("admin"), &pb.ReserveRequest{
+				ProductIds: []string{uuid.NewString()},
+				ShippingId: uuid.NewString(),
+				Amount:     1,
+			})
+
+			expectCode(t, err, codes.FailedPrecondition)
+		},
+		"Unauthenticated": func(t *testing.T) {
+			_, err := storages.ListStorages(withKey("unknown"), &pb.ListStoragesRequest{})
+
+			expectCode(t, err, codes.Unauthenticated)
+		},
+		"Scope is not granted": func(t *testing.T) {
+			_, err := reservationsClient.Reserve(withKey("reader"), &pb.ReserveRequest{})
+
+			expectCode(t, err, codes.PermissionDenied)
+		},
+		"Health check is public": func(t *testing.T) {
+			response, err := healthpb.NewHealthClient(conn).Check(
+				context.Background(),
+				&healthpb.HealthCheckRequest{},
+			)
+			if err != nil {
+				t.Fatal("error check health", err)
+			}
+
+			if response.Status != healthpb.HealthCheckResponse_SERVING {
+				t.Fatal("error wrong health status", response.Status)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+
+	cancel()
+
+	if err := <-served; err != nil {
+		t.Fatal("error stop server", err)
+	}
+}
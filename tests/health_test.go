@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/usecase/repository"
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthChecker(t *testing.T) {
+	t.Log("Test: readiness checks\n")
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Normal case": func(t *testing.T) {
+			checker := health.NewChecker("1.0.0")
+			checker.Add("ok", func(ctx context.Context) error { return nil })
+
+			report := checker.Run(context.TODO())
+
+			if report.Status != health.StatusOk || report.Version != "1.0.0" {
+				t.Fatal("error wrong report", report)
+			}
+
+			if report.Checks["ok"].Status != health.StatusOk {
+				t.Fatal("error wrong check result", report.Checks)
+			}
+		},
+		"Failing check": func(t *testing.T) {
+			checker := health.NewChecker("1.0.0")
+			checker.Add("ok", func(ctx context.Context) error { return nil })
+			checker.Add("failing", func(ctx context.Context) error { return errors.New("unavailable") })
+
+			report := checker.Run(context.TODO())
+
+			if report.Status != health.StatusFailing {
+				t.Fatal("error report is not failing", report)
+			}
+
+			if report.Checks["failing"].Error != "unavailable" || report.Checks["ok"].Status != health.StatusOk {
+				t.Fatal("error wrong check results", report.Checks)
+			}
+		},
+		"Serial check runs alone": func(t *testing.T) {
+			var running, overlapped atomic.Int32
+
+			check := func(ctx context.Context) error {
+				if running.Add(1) > 1 {
+					overlapped.Add(1)
+				}
+
+				time.Sleep(10 * time.Millisecond)
+				running.Add(-1)
+
+				return nil
+			}
+
+			checker := health.NewChecker("1.0.0")
+			checker.Add("first", check)
+			checker.AddSerial("serial", func(ctx context.Context) error {
+				if running.Load() > 0 {
+					return errors.New("other checks are running")
+				}
+
+				return nil
+			})
+			checker.Add("second", check)
+
+			report := checker.Run(context.TODO())
+
+			if report.Status != health.StatusOk || overlapped.Load() == 0 {
+				t.Fatal("error checks did not run concurrently or serial check overlapped", report.Checks)
+			}
+		},
+		"Pool held by other checks is not exhausted": func(t *testing.T) {
+			db := openStubDB(t, "primary")
+			db.SetMaxOpenConns(1)
+
+			checker := health.NewChecker("1.0.0")
+			checker.AddSerial("pool", repository.PoolCheck(db))
+			checker.Add("database", func(ctx context.Context) error {
+				conn, err := db.Conn(ctx)
+				if err != nil {
+					return err
+				}
+
+				defer conn.Close()
+
+				time.Sleep(10 * time.Millisecond)
+
+				return nil
+			})
+
+			if report := checker.Run(context.TODO()); report.Status != health.StatusOk {
+				t.Fatal("error pool reported exhausted", report.Checks)
+			}
+		},
+		"Exhausted pool": func(t *testing.T) {
+			db := openStubDB(t, "primary")
+			db.SetMaxOpenConns(1)
+
+			conn, err := db.Conn(context.TODO())
+			if err != nil {
+				t.Fatal("error take connection", err)
+			}
+
+			err = repository.PoolCheck(db)(context.TODO())
+			if !errors.Is(err, repository.ErrorPoolExhausted) {
+				t.Fatal("error exhausted pool expected", err)
+			}
+
+			conn.Close()
+
+			if err = repository.PoolCheck(db)(context.TODO()); err != nil {
+				t.Fatal("error pool with free connections", err)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
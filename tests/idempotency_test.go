@@ -0,0 +1,93 @@
+package tests
+
+import (
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/usecase/repository"
+	"cernunnos/internal/usecase/repository/idempotency"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestIdempotencyKeys(t *testing.T) {
+	db, cleanup, err := repository.ProvideDatabaseConnection(&cfg)
+	if err != nil {
+		t.Fatal("error connect to database", err)
+	}
+
+	defer cleanup()
+
+	idempotencyRepository := idempotency.NewRepository(db)
+
+	t.Log("Test: idempotency keys claims\n")
+
+	// claimAbandoned claims a key, then claims it again as if the first claim outlived its lease
+	claimAbandoned := func(t *testing.T, ctx context.Context) (stale, claim *models.IdempotentRequest) {
+		stale = &models.IdempotentRequest{
+			Client:      "test",
+			Key:         uuid.NewString(),
+			RequestHash: "hash",
+			CreatedAt:   time.Now().UTC().Add(-time.Hour).Truncate(time.Microsecond),
+		}
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		claim = &models.IdempotentRequest{
+			Client: stale.Client, Key: stale.Key, RequestHash: stale.RequestHash, CreatedAt: now,
+		}
+
+		for _, request := range []*models.IdempotentRequest{stale, claim} {
+			stored, err := idempotencyRepository.Claim(ctx, request, now.Add(-24*time.Hour), now.Add(-time.Minute))
+			if err != nil || stored != nil {
+				t.Fatal("error claim key", stored, err)
+			}
+		}
+
+		return stale, claim
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Stale claim does not complete key": func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			stale, claim := claimAbandoned(t, ctx)
+
+			err := idempotencyRepository.Complete(ctx, stale, 201, []byte("{}"))
+			if !errors.Is(err, errs.ErrorIdempotencyClaimLost) {
+				t.Fatal("error lost claim expected", err)
+			}
+
+			if err = idempotencyRepository.Complete(ctx, claim, 200, []byte("{}")); err != nil {
+				t.Fatal("error complete key", err)
+			}
+
+			stored, err := idempotencyRepository.Claim(ctx, claim, claim.CreatedAt.Add(-time.Hour), claim.CreatedAt)
+			if err != nil || stored == nil || stored.StatusCode != 200 {
+				t.Fatal("error wrong stored response", stored, err)
+			}
+		},
+		"Stale claim does not release key": func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			stale, claim := claimAbandoned(t, ctx)
+
+			if err := idempotencyRepository.Release(ctx, stale); !errors.Is(err, errs.ErrorIdempotencyClaimLost) {
+				t.Fatal("error lost claim expected", err)
+			}
+
+			if err := idempotencyRepository.Release(ctx, claim); err != nil {
+				t.Fatal("error release key", err)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
@@ -0,0 +1,344 @@
+package tests
+
+import (
+	"bytes"
+	"cernunnos/commands/imports"
+	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/server"
+	"cernunnos/internal/server/interface/controllers"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	importsRepo "cernunnos/internal/usecase/repository/imports"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// memoryImportsRepository keeps loaded rows in memory. Rows loaded in a rolled back transaction are
+// discarded.
+type memoryImportsRepository struct {
+	products map[uuid.UUID]*models.ProductInfo
+	storages map[uuid.UUID]*models.Storage
+	stock    map[[2]uuid.UUID]int64
+	reserved map[[2]uuid.UUID]int64
+
+	staged  *memoryImportsRepository
+	batches int
+}
+
+func newMemoryImportsRepository() *memoryImportsRepository {
+	return &memoryImportsRepository{
+		products: make(map[uuid.UUID]*models.ProductInfo),
+		storages: make(map[uuid.UUID]*models.Storage),
+		stock:    make(map[[2]uuid.UUID]int64),
+		reserved: make(map[[2]uuid.UUID]int64),
+	}
+}
+
+func (r *memoryImportsRepository) Load(ctx context.Context, fn func(ctx context.Context) error) error {
+	r.staged = &memoryImportsRepository{
+		products: maps.Clone(r.products),
+		storages: maps.Clone(r.storages),
+		stock:    maps.Clone(r.stock),
+		reserved: r.reserved,
+	}
+
+	err := fn(ctx)
+	if errors.Is(err, importsRepo.ErrorRollback) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.products, r.storages, r.stock = r.staged.products, r.staged.storages, r.staged.stock
+
+	return nil
+}
+
+func (r *memoryImportsRepository) UpsertStorages(ctx context.Context, storages []*models.Storage) error {
+	r.batches++
+
+	for _, storage := range storages {
+		r.staged.storages[storage.Id] = storage
+	}
+
+	return nil
+}
+
+func (r *memoryImportsRepository) UpsertProducts(ctx context.Context, products []*models.ProductInfo) error {
+	r.batches++
+
+	for _, product := range products {
+		r.staged.products[product.Id] = product
+	}
+
+	return nil
+}
+
+func (r *memoryImportsRepository) UpsertStock(
+	ctx context.Context,
+	lines []*importsRepo.StockLine,
+) (map[int]error, error) {
+	r.batches++
+
+	rejected := make(map[int]error)
+
+	for i, line := range lines {
+		key := [2]uuid.UUID{line.StorageId, line.ProductId}
+
+		switch {
+		case r.staged.storages[line.StorageId] == nil:
+			rejected[i] = importsRepo.ErrorStorageNotFound
+		case r.staged.products[line.ProductId] == nil:
+			rejected[i] = importsRepo.ErrorProductNotFound
+		case r.reserved[key] > line.Amount:
+			rejected[i] = importsRepo.ErrorAmountBelowReserved
+		default:
+			r.staged.stock[key] = line.Amount
+		}
+	}
+
+	return rejected, nil
+}
+
+func TestImport(t *testing.T) {
+	t.Log("Test: bulk import of products, storages and stock\n")
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	newInteractor := func() (interactors.ImportInteractor, *memoryImportsRepository) {
+		repository := newMemoryImportsRepository()
+
+		return interactors.NewImportInteractor(log, repository), repository
+	}
+
+	load := func(
+		t *testing.T,
+		interactor interactors.ImportInteractor,
+		kind, format, file string,
+		dryRun bool,
+	) *models.ImportResult {
+		result, err := interactor.Import(context.Background(), interactors.ImportParams{
+			Kind:   kind,
+			Format: format,
+			DryRun: dryRun,
+			Source: strings.NewReader(file),
+		})
+		if err != nil {
+			t.Fatal("error import", err)
+		}
+
+		return result
+	}
+
+	storageId, productId := uuid.New(), uuid.New()
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"CSV rows are loaded in batches": func(t *testing.T) {
+			interactor, repository := newInteractor()
+
+			var file strings.Builder
+
+			file.WriteString("\ufeffname, size\n")
+
+			for range 1200 {
+				file.WriteString("Chair,12\n")
+			}
+
+			result := load(t, interactor, interactors.ImportProducts, interactors.ImportCSV, file.String(), false)
+			if !result.Committed || result.Rows != 1200 || result.Rejected != 0 {
+				t.Fatal("error wrong result", result)
+			}
+
+			if len(repository.products) != 1200 || repository.batches != 3 {
+				t.Fatal("error wrong load", len(repository.products), repository.batches)
+			}
+		},
+		"Rejected rows roll import back": func(t *testing.T) {
+			interactor, repository := newInteractor()
+
+			file := strings.Join([]string{
+				"id,name,available",
+				storageId.String() + ",Main,100",
+				"not-an-id,Spare,10",
+				",,10",
+				uuid.NewString() + ",North,-5",
+				storageId.String() + ",Main again,100",
+				`"broken,1`,
+			}, "\n")
+
+			result := load(t, interactor, interactors.ImportStorages, interactors.ImportCSV, file, false)
+			if result.Committed || result.Rows != 6 || result.Rejected != 5 {
+				t.Fatal("error wrong result", result)
+			}
+
+			lines := make([]int64, len(result.Errors))
+			for i, rowError := range result.Errors {
+				lines[i] = rowError.Line
+			}
+
+			if len(lines) != 5 || lines[0] != 3 || lines[4] != 7 {
+				t.Fatal("error wrong rejected lines", lines)
+			}
+
+			if !strings.Contains(result.Errors[3].Error, "duplicates line 2") {
+				t.Fatal("error duplicate is not reported", result.Errors[3])
+			}
+
+			if len(repository.storages) != 0 {
+				t.Fatal("error rejected import loaded", repository.storages)
+			}
+		},
+		"Dry run loads nothing": func(t *testing.T) {
+			interactor, repository := newInteractor()
+
+			file := `{"id":"` + productId.String() + `","name":"Table","size":40}` + "\n\n" + `{"name":"Lamp"}`
+
+			result := load(t, interactor, interactors.ImportProducts, interactors.ImportJSONL, file, true)
+			if result.Committed || result.Rows != 2 || result.Rejected != 0 {
+				t.Fatal("error wrong result", result)
+			}
+
+			if len(repository.products) != 0 || repository.batches != 1 {
+				t.Fatal("error dry run loaded", repository.products)
+			}
+		},
+		"Stock rows rejected by repository": func(t *testing.T) {
+			interactor, repository := newInteractor()
+
+			repository.storages[storageId] = &models.Storage{Id: storageId}
+			repository.products[productId] = &models.ProductInfo{Id: productId}
+			repository.reserved[[2]uuid.UUID{storageId, productId}] = 10
+
+			file := strings.Join([]string{
+				`{"storage_id":"` + storageId.String() + `","product_id":"` + productId.String() + `","amount":5}`,
+				`{"storage_id":"` + uuid.NewString() + `","product_id":"` + productId.String() + `","amount":5}`,
+				`{"storage_id":"` + storageId.String() + `","product_id":"` + productId.String() + `","amount":"x"}`,
+				`{"storage_id":"` + storageId.String() + `","amount":5,"color":"red"}`,
+				`[1,2]`,
+			}, "\n")
+
+			result := load(t, interactor, interactors.ImportStock, interactors.ImportJSONL, file, false)
+			if result.Committed || result.Rejected != 5 {
+				t.Fatal("error wrong result", result)
+			}
+
+			if result.Errors[0].Line != 1 || result.Errors[0].Error != importsRepo.ErrorAmountBelowReserved.Error() {
+				t.Fatal("error wrong stock rejection", result.Errors[0])
+			}
+
+			if result.Errors[1].Error != importsRepo.ErrorStorageNotFound.Error() {
+				t.Fatal("error wrong storage rejection", result.Errors[1])
+			}
+
+			if !strings.Contains(result.Errors[3].Error, "unknown field color") {
+				t.Fatal("error unknown field accepted", result.Errors[3])
+			}
+		},
+		"Invalid header": func(t *testing.T) {
+			interactor, _ := newInteractor()
+
+			for _, file := range []string{"", "name,color\nChair,red", "storage_id,amount\n"} {
+				_, err := interactor.Import(context.Background(), interactors.ImportParams{
+					Kind:   interactors.ImportStock,
+					Format: interactors.ImportCSV,
+					Source: strings.NewReader(file),
+				})
+				if !errors.Is(err, interactors.ErrorInvalidField) && !errors.Is(err, interactors.ErrorFieldRequired) {
+					t.Fatal("error invalid header accepted", file, err)
+				}
+			}
+		},
+		"Command reports rejected rows": func(t *testing.T) {
+			interactor, _ := newInteractor()
+
+			var out bytes.Buffer
+
+			command := imports.NewImportCommand(&out, interactor)
+
+			err := command.Import(
+				context.Background(), interactors.ImportProducts, "", "products.csv",
+				strings.NewReader("name,size\nChair,big\nTable,40\n"), false,
+			)
+			if !errors.Is(err, imports.ErrorRowsRejected) {
+				t.Fatal("error rejected rows expected", err)
+			}
+
+			if !strings.Contains(out.String(), "line 2: size must be an integer") {
+				t.Fatal("error wrong report", out.String())
+			}
+
+			if imports.FormatOf("stock.NDJSON") != interactors.ImportJSONL {
+				t.Fatal("error jsonl file is not detected")
+			}
+		},
+		"Upload endpoint": func(t *testing.T) {
+			interactor, repository := newInteractor()
+
+			cfg := config.Default()
+			httpServer := httptest.NewServer(server.New(
+				&cfg,
+				log,
+				controllers.NewRootController(
+					nil, nil, nil, nil, nil,
+					controllers.NewImportController(log, interactor, presenters.NewImportPresenter()),
+					nil,
+				),
+				health.NewChecker("test"),
+				nil,
+				nil,
+				nil,
+			))
+			defer httpServer.Close()
+
+			response, err := http.Post(
+				httpServer.URL+"/imports/products?format=jsonl",
+				"application/x-ndjson",
+				strings.NewReader(`{"name":"Chair","size":12}`),
+			)
+			if err != nil {
+				t.Fatal("error upload file", err)
+			}
+			defer response.Body.Close()
+
+			var result dto.ImportResponse
+
+			if err = json.NewDecoder(response.Body).Decode(&result); err != nil {
+				t.Fatal("error decode response", err)
+			}
+
+			if response.StatusCode != http.StatusOK || !result.Committed || len(repository.products) != 1 {
+				t.Fatal("error wrong upload", response.StatusCode, result)
+			}
+
+			response, err = http.Post(httpServer.URL+"/imports/orders", "text/csv", strings.NewReader("id\n"))
+			if err != nil {
+				t.Fatal("error upload file", err)
+			}
+			response.Body.Close()
+
+			if response.StatusCode != http.StatusBadRequest {
+				t.Fatal("error unknown kind accepted", response.StatusCode)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"bytes"
+	"cernunnos/commands/inventory"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/pkg/client"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestInventoryCommand(t *testing.T) {
+	t.Log("Test: operator commands against in-process server\n")
+
+	storages := make([]*models.Storage, 3)
+	for i := range storages {
+		storages[i] = &models.Storage{Id: uuid.New(), Name: fmt.Sprint("Storage ", i), Available: 10}
+	}
+
+	reservationInteractor := &stockReservationInteractor{}
+	reservationInteractor.available.Store(10)
+
+	httpServer := newAPIServer(
+		&pagedStorageInteractor{storages: storages},
+		reservationInteractor,
+		&memoryIdempotencyStore{requests: make(map[string]*models.IdempotentRequest)},
+	)
+	defer httpServer.Close()
+
+	apiClient, err := client.New(httpServer.URL, client.Options{})
+	if err != nil {
+		t.Fatal("error create client", err)
+	}
+
+	newCommand := func(t *testing.T, output string) (*inventory.InventoryCommand, *bytes.Buffer) {
+		var out bytes.Buffer
+
+		command, err := inventory.NewInventoryCommand(&out, apiClient, output)
+		if err != nil {
+			t.Fatal("error create command", err)
+		}
+
+		return command, &out
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Table output": func(t *testing.T) {
+			command, out := newCommand(t, inventory.OutputTable)
+
+			if err := command.Storages(context.Background(), &client.StoragesRequest{}, 2); err != nil {
+				t.Fatal("error list storages", err)
+			}
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[2], "Storage 1") {
+				t.Fatal("error wrong table", out.String())
+			}
+		},
+		"JSON output lists all pages": func(t *testing.T) {
+			command, out := newCommand(t, inventory.OutputJSON)
+
+			if err := command.Storages(context.Background(), &client.StoragesRequest{}, 0); err != nil {
+				t.Fatal("error list storages", err)
+			}
+
+			var listed []*client.Storage
+
+			if err := json.Unmarshal(out.Bytes(), &listed); err != nil {
+				t.Fatal("error unmarshal output", err)
+			}
+
+			if len(listed) != len(storages) || listed[0].Id != storages[0].Id.String() {
+				t.Fatal("error wrong storages", out.String())
+			}
+		},
+		"Reservation": func(t *testing.T) {
+			command, out := newCommand(t, inventory.OutputTable)
+
+			request := &client.ReserveRequest{
+				Products:   []string{uuid.NewString()},
+				ShippingId: uuid.NewString(),
+				Amount:     4,
+			}
+
+			if err := command.Reserve(context.Background(), request); err != nil {
+				t.Fatal("error reserve", err)
+			}
+
+			if !strings.Contains(out.String(), "reserved 4") {
+				t.Fatal("error wrong output", out.String())
+			}
+
+			request.Amount = 100
+
+			if err := command.Reserve(context.Background(), request); !errors.Is(err, client.ErrNotEnoughProducts) {
+				t.Fatal("error not enough products expected", err)
+			}
+		},
+		"Unknown output": func(t *testing.T) {
+			if _, err := inventory.NewInventoryCommand(&bytes.Buffer{}, apiClient, "yaml"); err == nil {
+				t.Fatal("error unknown output accepted")
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"bytes"
+	"cernunnos/internal/pkg/logger"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+func TestContextLogger(t *testing.T) {
+	t.Log("Test: context attributes logging\n")
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Normal case": func(t *testing.T) {
+			out := new(bytes.Buffer)
+			log := slog.New(logger.NewContextHandler(slog.NewJSONHandler(out, nil)))
+
+			ctx := context.WithValue(context.TODO(), chimw.RequestIDKey, "request-1")
+			ctx = logger.WithRequestAttrs(ctx)
+
+			logger.AddAttrs(ctx, slog.String("client", "shop"))
+			log.InfoContext(ctx, "message")
+
+			record := make(map[string]any)
+
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatal("error unmarshal record", err)
+			}
+
+			if record["request_id"] != "request-1" || record["client"] != "shop" {
+				t.Fatal("error context attributes are not logged", record)
+			}
+		},
+		"Grouped logger": func(t *testing.T) {
+			out := new(bytes.Buffer)
+			log := slog.New(logger.NewContextHandler(slog.NewJSONHandler(out, nil)))
+
+			ctx := context.WithValue(context.TODO(), chimw.RequestIDKey, "request-1")
+			ctx = logger.WithRequestAttrs(ctx)
+
+			logger.AddAttrs(ctx, slog.String("client", "shop"))
+			log.With(slog.String("service", "cernunnos")).WithGroup("x").With(slog.Int("batch", 1)).
+				InfoContext(ctx, "message", slog.String("key", "value"))
+
+			record := make(map[string]any)
+
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatal("error unmarshal record", err)
+			}
+
+			if record["request_id"] != "request-1" || record["client"] != "shop" || record["service"] != "cernunnos" {
+				t.Fatal("error context attributes are not at top level", record)
+			}
+
+			group, ok := record["x"].(map[string]any)
+			if !ok || group["key"] != "value" || group["batch"] != float64(1) || group["request_id"] != nil {
+				t.Fatal("error wrong group", record)
+			}
+		},
+		"Without context attributes": func(t *testing.T) {
+			out := new(bytes.Buffer)
+			log := slog.New(logger.NewContextHandler(slog.NewJSONHandler(out, nil)))
+
+			logger.AddAttrs(context.TODO(), slog.String("client", "shop"))
+			log.Info("message")
+
+			record := make(map[string]any)
+
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatal("error unmarshal record", err)
+			}
+
+			if _, ok := record["client"]; ok {
+				t.Fatal("error unexpected attributes logged", record)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bufio"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/sqltools"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// scrapeMetric reads a sample of a series from metrics handler. Returns 0 if the series is not
+// exported yet.
+func scrapeMetric(t *testing.T, series string) float64 {
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(scanner.Text(), series+" ")
+		if !ok {
+			continue
+		}
+
+		sample, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			t.Fatal("error parse sample", series, err)
+		}
+
+		return sample
+	}
+
+	return 0
+}
+
+func reservedSeries(storageId uuid.UUID) string {
+	return `cernunnos_inventory_units_reserved_total{storage_id="` + storageId.String() + `"}`
+}
+
+func TestMetrics(t *testing.T) {
+	t.Log("Test: exported metrics\n")
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Inventory series": func(t *testing.T) {
+			storageId := uuid.New()
+
+			metrics.UnitsReserved(storageId.String(), 3)
+			metrics.UnitsReserved(storageId.String(), 2)
+			metrics.UnitsReleased(storageId.String(), 4)
+
+			if reserved := scrapeMetric(t, reservedSeries(storageId)); reserved != 5 {
+				t.Fatal("error wrong reserved units", reserved)
+			}
+
+			released := scrapeMetric(
+				t, `cernunnos_inventory_units_released_total{storage_id="`+storageId.String()+`"}`,
+			)
+			if released != 4 {
+				t.Fatal("error wrong released units", released)
+			}
+		},
+		"Recorded right away outside transaction": func(t *testing.T) {
+			storageId := uuid.New()
+
+			sqltools.AfterCommit(context.Background(), func() {
+				metrics.UnitsReserved(storageId.String(), 1)
+			})
+
+			if reserved := scrapeMetric(t, reservedSeries(storageId)); reserved != 1 {
+				t.Fatal("error wrong reserved units", reserved)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
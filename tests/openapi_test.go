@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/openapi"
+	"cernunnos/internal/server"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Log("Test: openapi document\n")
+
+	doc := server.OpenAPI("test")
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Every route is documented": func(t *testing.T) {
+			routes, err := server.Routes()
+			if err != nil {
+				t.Fatal("error list routes", err)
+			}
+
+			operations := doc.Operations()
+
+			for _, route := range routes {
+				if !slices.Contains(operations, route) {
+					t.Error("error route is not documented:", route)
+				}
+			}
+
+			for _, operation := range operations {
+				if !slices.Contains(routes, operation) {
+					t.Error("error documented operation is not served:", operation)
+				}
+			}
+		},
+		"References are resolved": func(t *testing.T) {
+			raw, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatal("error marshal document", err)
+			}
+
+			var refs []string
+
+			collectRefs(t, raw, &refs)
+
+			if len(refs) == 0 {
+				t.Fatal("error no schema references")
+			}
+
+			for _, ref := range refs {
+				name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+				if _, found := doc.Components.Schemas[name]; !ok || !found {
+					t.Error("error unresolved reference", ref)
+				}
+			}
+		},
+		"Path parameters are declared": func(t *testing.T) {
+			for path, item := range doc.Paths {
+				for method, operation := range *item {
+					for _, segment := range strings.Split(path, "/") {
+						name, ok := strings.CutPrefix(segment, "{")
+						if !ok {
+							continue
+						}
+
+						if !hasParameter(operation, strings.TrimSuffix(name, "}"), "path") {
+							t.Error("error path parameter is not declared", method, path, name)
+						}
+					}
+				}
+			}
+		},
+		"Schemas follow dto": func(t *testing.T) {
+			storages := (*doc.Paths["/storages"])["get"]
+
+			for _, name := range []string{"ids", "limit", "cursor", "name_prefix", "updated_since"} {
+				if !hasParameter(storages, name, "query") {
+					t.Error("error query parameter is not documented", name)
+				}
+			}
+
+			storage, ok := doc.Components.Schemas["Storage"]
+			if !ok {
+				t.Fatal("error storage schema is not documented")
+			}
+
+			if storage.Properties["available"].Type != "integer" || !slices.Contains(storage.Required, "id") {
+				t.Fatal("error wrong storage schema", storage.Properties, storage.Required)
+			}
+
+			// Embedded structs are flattened as encoding/json does
+			product := doc.Components.Schemas["StorageProduct"]
+			if product.Properties["name"] == nil || product.Properties["storage_id"] == nil {
+				t.Fatal("error embedded fields are not promoted", product.Properties)
+			}
+		},
+		"Docs page": func(t *testing.T) {
+			if !strings.Contains(string(openapi.DocsPage), "/openapi.json") {
+				t.Fatal("error docs page does not load the document")
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
+
+func hasParameter(operation *openapi.Operation, name, in string) bool {
+	return slices.ContainsFunc(operation.Parameters, func(parameter *openapi.Parameter) bool {
+		return parameter.Name == name && parameter.In == in
+	})
+}
+
+// collectRefs collects values of every $ref key of a JSON document
+func collectRefs(t *testing.T, raw json.RawMessage, refs *[]string) {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		t.Fatal("error unmarshal document", err)
+	}
+
+	var walk func(value any)
+
+	walk = func(value any) {
+		switch v := value.(type) {
+		case map[string]any:
+			for key, item := range v {
+				if ref, ok := item.(string); ok && key == "$ref" {
+					*refs = append(*refs, ref)
+				}
+
+				walk(item)
+			}
+		case []any:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+
+	walk(value)
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/sqltools"
+	"strings"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+)
+
+func TestPagination(t *testing.T) {
+	t.Log("Test: keyset pagination pages\n")
+
+	spec := sqltools.FilterSpec{
+		Fields:    map[string]string{"name": "p.name"},
+		CreatedAt: "p.created_at",
+		Id:        "p.id",
+	}
+
+	fetch := func(count int) ([]int, []sqltools.Cursor) {
+		items := make([]int, count)
+		keys := make([]sqltools.Cursor, count)
+
+		for i := range items {
+			items[i] = i
+			keys[i] = sqltools.Cursor{Id: uuid.New()}
+		}
+
+		return items, keys
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"One row more than a page is fetched": func(t *testing.T) {
+			query, err := sqltools.Pagination{Limit: 2}.Apply(sq.Select("p.id").From("products as p"), spec)
+			if err != nil {
+				t.Fatal("error apply pagination", err)
+			}
+
+			sql, _, err := query.ToSql()
+			if err != nil {
+				t.Fatal("error build query", err)
+			}
+
+			if !strings.HasSuffix(sql, "LIMIT 3") {
+				t.Fatal("error wrong limit", sql)
+			}
+		},
+		"Exactly full last page has no cursor": func(t *testing.T) {
+			items, keys := fetch(2)
+
+			page := sqltools.NewPage(sqltools.Pagination{Limit: 2}, items, keys)
+			if len(page.Items) != 2 || page.NextCursor != "" || page.NextOffset != 2 {
+				t.Fatal("error wrong last page", page)
+			}
+		},
+		"Extra row is trimmed": func(t *testing.T) {
+			items, keys := fetch(3)
+
+			page := sqltools.NewPage(sqltools.Pagination{Limit: 2, Offset: 4}, items, keys)
+			if len(page.Items) != 2 || page.NextOffset != 6 {
+				t.Fatal("error wrong page", page)
+			}
+
+			cursor, err := sqltools.ParseCursor(page.NextCursor)
+			if err != nil {
+				t.Fatal("error parse cursor", err)
+			}
+
+			if cursor.Id != keys[1].Id {
+				t.Fatal("error cursor does not point to the last item of the page", cursor, keys)
+			}
+		},
+		"Cursor pages have no offset": func(t *testing.T) {
+			items, keys := fetch(3)
+
+			pagination := sqltools.Pagination{Limit: 2, Cursor: &keys[0]}
+
+			page := sqltools.NewPage(pagination, items, keys)
+			if page.NextOffset != 0 || page.NextCursor == "" {
+				t.Fatal("error wrong cursor page", page)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+		test(t)
+	}
+}
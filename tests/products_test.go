@@ -2,6 +2,7 @@ package tests
 
 import (
 	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
 	"cernunnos/internal/server/interface/controllers"
 	"cernunnos/internal/server/interface/presenters"
 	"cernunnos/internal/usecase/interactors"
@@ -9,9 +10,12 @@ import (
 	"cernunnos/internal/usecase/repository/products"
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"math"
 	"math/rand"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,7 +29,7 @@ func TestProductFetching(t *testing.T) {
 		t.Fatal("error connect to database", err)
 	}
 
-	productsInteractor := interactors.NewProductInteractor(slog.Default(), products.NewRepository(db))
+	productsInteractor := interactors.NewProductInteractor(slog.Default(), products.NewRepository(db, nil))
 
 	defer cleanup()
 
@@ -89,14 +93,179 @@ func TestProductFetching(t *testing.T) {
 				t.Fatal("error fetch product", err)
 			}
 
-			if len(products) == 0 {
+			if len(products.Items) == 0 {
 				t.Fatal("error no products fetched", err)
 			}
 
-			if productId != products[0].Id {
+			if productId != products.Items[0].Id {
 				t.Fatal("error wrong product", err)
 			}
 		},
+		"Sorted cursor pagination": func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			productIds := make([]string, 0, 3)
+
+			for _, name := range []string{"Anchor", "Beacon", "Compass"} {
+				productId := uuid.New()
+
+				err = insertProducts(ctx, db, insertProductsParams{
+					storageId:   storageId,
+					productId:   productId,
+					productName: name,
+					size:        rand.Int63n(250),
+					amount:      amount,
+					reserved:    reserved,
+					available:   available,
+				})
+				if err != nil {
+					t.Fatal("error add product", err)
+				}
+
+				productIds = append(productIds, productId.String())
+			}
+
+			names := make([]string, 0, 3)
+			cursor := ""
+
+			for range 2 {
+				products, err := productsInteractor.Products(ctx, interactors.ProductsParams{
+					ProductsFilter: interactors.ProductsFilter{Sort: "-name"},
+					Ids:            productIds,
+					Limit:          2,
+					Cursor:         cursor,
+				})
+				if err != nil {
+					t.Fatal("error fetch products", err)
+				}
+
+				for _, product := range products.Items {
+					names = append(names, product.Name)
+				}
+
+				cursor = products.NextCursor
+			}
+
+			if strings.Join(names, ",") != "Compass,Beacon,Anchor" || cursor != "" {
+				t.Fatal("error wrong sorted pages", names, cursor)
+			}
+		},
+		"Search by misspelled name": func(t *testing.T) {
+			productId := uuid.New()
+			productName := "Lightbulb " + gofakeit.LetterN(12)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			err = insertProducts(ctx, db, insertProductsParams{
+				storageId:   storageId,
+				productId:   productId,
+				productName: productName,
+				size:        rand.Int63n(250),
+				amount:      amount,
+				reserved:    amount,
+				available:   0,
+			})
+			if err != nil {
+				t.Fatal("error add product", err)
+			}
+
+			found := func(products []*models.ProductInfo) bool {
+				return slices.ContainsFunc(products, func(product *models.ProductInfo) bool {
+					return product.Id == productId
+				})
+			}
+
+			products, err := productsInteractor.SearchProducts(ctx, interactors.SearchProductsParams{
+				Query: strings.Replace(productName, "Lightbulb", "Ligthbulb", 1),
+				Limit: 10,
+			})
+			if err != nil {
+				t.Fatal("error search products", err)
+			}
+
+			if !found(products.Items) {
+				t.Fatal("error product is not found by misspelled name", products.Items)
+			}
+
+			// The product is fully reserved, so it is not available in the storage
+			products, err = productsInteractor.SearchProducts(ctx, interactors.SearchProductsParams{
+				Query:     productName,
+				StorageId: storageId.String(),
+				Limit:     10,
+			})
+			if err != nil {
+				t.Fatal("error search products", err)
+			}
+
+			if found(products.Items) {
+				t.Fatal("error unavailable product found in storage", products.Items)
+			}
+
+			_, err = productsInteractor.SearchProducts(ctx, interactors.SearchProductsParams{Query: "  "})
+			if !errors.Is(err, interactors.ErrorFieldRequired) {
+				t.Fatal("error empty query accepted", err)
+			}
+		},
+		"Search pages by cursor": func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			// Products are named alike, so they are found by a single query
+			word := gofakeit.LetterN(16)
+
+			for _, name := range []string{word, word + " Lamp", word + " Lamp Shade"} {
+				err = insertProducts(ctx, db, insertProductsParams{
+					storageId:   storageId,
+					productId:   uuid.New(),
+					productName: name,
+					size:        rand.Int63n(250),
+					amount:      amount,
+					reserved:    reserved,
+					available:   available,
+				})
+				if err != nil {
+					t.Fatal("error add product", err)
+				}
+			}
+
+			var (
+				found  []string
+				cursor string
+				total  *int64
+			)
+
+			for range 3 {
+				products, err := productsInteractor.SearchProducts(ctx, interactors.SearchProductsParams{
+					Query:     word,
+					Limit:     1,
+					Cursor:    cursor,
+					WithTotal: true,
+				})
+				if err != nil {
+					t.Fatal("error search products", err)
+				}
+
+				for _, product := range products.Items {
+					found = append(found, product.Name)
+				}
+
+				cursor, total = products.NextCursor, products.Total
+			}
+
+			if len(found) != 3 || cursor != "" || total == nil || *total != 3 {
+				t.Fatal("error wrong search pages", found, cursor, total)
+			}
+
+			unique := slices.Clone(found)
+			slices.Sort(unique)
+
+			// No product is skipped or repeated across pages
+			if len(slices.Compact(unique)) != 3 {
+				t.Fatal("error wrong search pages", found)
+			}
+		},
 		"Invalid case. Product does not exists": func(t *testing.T) {
 			productId := uuid.New()
 
@@ -113,7 +282,7 @@ func TestProductFetching(t *testing.T) {
 				t.Fatal("error fetch product", err)
 			}
 
-			if len(products) != 0 {
+			if len(products.Items) != 0 {
 				t.Fatal("error products fetched, but it shouldnt", err)
 			}
 		},
@@ -147,7 +316,7 @@ func TestProductFetching(t *testing.T) {
 				t.Fatal("error fetch product", err)
 			}
 
-			if len(products) != 0 {
+			if len(products.Items) != 0 {
 				t.Fatal("error products fetched, but it shouldnt", err)
 			}
 		},
@@ -169,7 +338,7 @@ func TestStorageProductRequest(t *testing.T) {
 	productsController := controllers.NewProductController(
 		slog.Default(),
 		presenters.NewProductPresenter(),
-		interactors.NewProductInteractor(slog.Default(), products.NewRepository(db)),
+		interactors.NewProductInteractor(slog.Default(), products.NewRepository(db, nil)),
 	)
 
 	storageId := uuid.New()
@@ -253,6 +422,184 @@ func TestStorageProductRequest(t *testing.T) {
 				t.Fatal("error wrong product id")
 			}
 		},
+		"Cursor pagination": func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			productIds := make([]string, 0, 3)
+
+			for i := 0; i < 3; i++ {
+				productId := uuid.New()
+
+				err = insertProducts(ctx, db, insertProductsParams{
+					storageId:   storageId,
+					productId:   productId,
+					productName: gofakeit.ProductName(),
+					size:        rand.Int63n(250),
+					amount:      amount,
+					reserved:    reserved,
+					available:   available,
+				})
+				if err != nil {
+					t.Fatal("error add product", err)
+				}
+
+				productIds = append(productIds, productId.String())
+			}
+
+			data, err := productsController.StorageProducts(ctx, &dto.StorageProductsRequest{
+				StorageId:   storageId.String(),
+				ProductsIds: productIds,
+				Limit:       2,
+				WithTotal:   true,
+			})
+			if err != nil {
+				t.Fatal("error fetch storage products", err)
+			}
+
+			firstPage := new(dto.StorageProductsResponse)
+
+			if err = json.Unmarshal(data, firstPage); err != nil {
+				t.Fatal("error unmarshal StorageProducts response", err)
+			}
+
+			if len(firstPage.Products) != 2 || firstPage.NextCursor == "" {
+				t.Fatal("error invalid first page")
+			}
+
+			if firstPage.Total == nil || *firstPage.Total != 3 {
+				t.Fatal("error invalid total")
+			}
+
+			data, err = productsController.StorageProducts(ctx, &dto.StorageProductsRequest{
+				StorageId:   storageId.String(),
+				ProductsIds: productIds,
+				Limit:       2,
+				Cursor:      firstPage.NextCursor,
+			})
+			if err != nil {
+				t.Fatal("error fetch storage products", err)
+			}
+
+			secondPage := new(dto.StorageProductsResponse)
+
+			if err = json.Unmarshal(data, secondPage); err != nil {
+				t.Fatal("error unmarshal StorageProducts response", err)
+			}
+
+			if len(secondPage.Products) != 1 || secondPage.NextCursor != "" {
+				t.Fatal("error invalid second page")
+			}
+
+			for _, product := range firstPage.Products {
+				if product.Id == secondPage.Products[0].Id {
+					t.Fatal("error product fetched twice")
+				}
+			}
+		},
+		"Point in time": func(t *testing.T) {
+			productId := uuid.New()
+
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			beforeInsert := uint64(time.Now().UnixMilli())
+
+			time.Sleep(10 * time.Millisecond)
+
+			err = insertProducts(ctx, db, insertProductsParams{
+				storageId:   storageId,
+				productId:   productId,
+				productName: gofakeit.ProductName(),
+				size:        rand.Int63n(250),
+				amount:      amount,
+				reserved:    reserved,
+				available:   available,
+			})
+			if err != nil {
+				t.Fatal("error add product", err)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+
+			beforeUpdate := uint64(time.Now().UnixMilli())
+
+			time.Sleep(10 * time.Millisecond)
+
+			// Changes made in a transaction share a version, the replaced one is left empty
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				t.Fatal("error begin transaction", err)
+			}
+
+			for range 2 {
+				_, err = tx.ExecContext(
+					ctx,
+					`update products_distribution set amount = amount + 5, available = available + 5
+					where storage_id = $1 and product_id = $2`,
+					storageId,
+					productId,
+				)
+				if err != nil {
+					t.Fatal("error update product amount", err)
+				}
+			}
+
+			if err = tx.Commit(); err != nil {
+				t.Fatal("error commit transaction", err)
+			}
+
+			var empty, inverted int
+
+			err = db.QueryRowContext(
+				ctx,
+				`select count(*) filter (where valid_to = valid_from), count(*) filter (where valid_to < valid_from)
+				from products_distribution_history where storage_id = $1 and product_id = $2`,
+				storageId,
+				productId,
+			).Scan(&empty, &inverted)
+			if err != nil {
+				t.Fatal("error fetch product history", err)
+			}
+
+			if empty != 1 || inverted != 0 {
+				t.Fatal("error wrong product history", empty, inverted)
+			}
+
+			amounts := make(map[uint64]int, 3)
+
+			for _, asOf := range []uint64{beforeInsert, beforeUpdate, uint64(time.Now().UnixMilli())} {
+				data, err := productsController.StorageProducts(ctx, &dto.StorageProductsRequest{
+					StorageId:   storageId.String(),
+					ProductsIds: []string{productId.String()},
+					AsOf:        &asOf,
+					Limit:       1,
+				})
+				if err != nil {
+					t.Fatal("error fetch storage product", err)
+				}
+
+				response := new(dto.StorageProductsResponse)
+
+				if err = json.Unmarshal(data, response); err != nil {
+					t.Fatal("error unmarshal StorageProducts response", err)
+				}
+
+				if len(response.Products) > 0 {
+					amounts[asOf] = int(response.Products[0].Amount - amount)
+				}
+			}
+
+			if len(amounts) != 2 || amounts[beforeUpdate] != 0 {
+				t.Fatal("error wrong amounts at points in time", amounts)
+			}
+
+			for asOf, diff := range amounts {
+				if asOf != beforeUpdate && diff != 10 {
+					t.Fatal("error wrong current amount", amounts)
+				}
+			}
+		},
 		"Invalid product id": func(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
 			defer cancel()
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"bytes"
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/publisher"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPublisher(t *testing.T) {
+	t.Log("Test: domain events publishers\n")
+
+	event, err := models.NewEvent(models.EventReservationCreated, dto.ReservationEvent{
+		ShippingId: uuid.NewString(),
+		ProductId:  uuid.NewString(),
+		StorageId:  uuid.NewString(),
+		Amount:     3,
+	})
+	if err != nil {
+		t.Fatal("error create event", err)
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Writer publisher": func(t *testing.T) {
+			var out bytes.Buffer
+
+			if err := publisher.NewWriterPublisher(&out).Publish(context.TODO(), event); err != nil {
+				t.Fatal("error publish event", err)
+			}
+
+			var published dto.Event
+			if err := json.Unmarshal(out.Bytes(), &published); err != nil {
+				t.Fatal("error unmarshal published event", err)
+			}
+
+			if published.Id != event.Id.String() || published.Type != event.Type {
+				t.Fatal("error wrong published event", published)
+			}
+		},
+		"Webhook publisher": func(t *testing.T) {
+			var eventType string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				eventType = r.Header.Get("X-Event-Type")
+			}))
+			defer server.Close()
+
+			err := publisher.NewWebhookPublisher(server.URL, server.Client()).Publish(context.TODO(), event)
+			if err != nil {
+				t.Fatal("error publish event", err)
+			}
+
+			if eventType != models.EventReservationCreated {
+				t.Fatal("error wrong event type header", eventType)
+			}
+		},
+		"Webhook failure": func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			err := publisher.NewWebhookPublisher(server.URL, server.Client()).Publish(context.TODO(), event)
+			if !errors.Is(err, publisher.ErrorDeliveryFailed) {
+				t.Fatal("error failed delivery is not reported", err)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
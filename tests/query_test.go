@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"net/url"
+	"testing"
+)
+
+func TestQueryDecoding(t *testing.T) {
+	t.Log("Test: query parameters decoding\n")
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Normal case": func(t *testing.T) {
+			values, err := url.ParseQuery(
+				"ids=a,b&ids=c&storage_id=s&with_unavailable&limit=25&offset=50&cursor=abc",
+			)
+			if err != nil {
+				t.Fatal("error parse query", err)
+			}
+
+			request := new(dto.ProductsRequest)
+
+			if err = dto.DecodeQuery(values, request); err != nil {
+				t.Fatal("error decode query", err)
+			}
+
+			if len(request.Ids) != 3 || request.Ids[2] != "c" {
+				t.Fatal("error wrong ids", request.Ids)
+			}
+
+			if request.StorageId != "s" || !request.WithUnavailable || request.Cursor != "abc" {
+				t.Fatal("error wrong filter", request)
+			}
+
+			if request.Limit != 25 || request.Offset != 50 {
+				t.Fatal("error wrong pagination", request)
+			}
+		},
+		"Untagged fields are skipped": func(t *testing.T) {
+			values, err := url.ParseQuery("StorageId=s&ids=a")
+			if err != nil {
+				t.Fatal("error parse query", err)
+			}
+
+			request := new(dto.StorageProductsRequest)
+
+			if err = dto.DecodeQuery(values, request); err != nil {
+				t.Fatal("error decode query", err)
+			}
+
+			if request.StorageId != "" || len(request.ProductsIds) != 1 {
+				t.Fatal("error wrong request", request)
+			}
+		},
+		"Invalid number": func(t *testing.T) {
+			values, err := url.ParseQuery("limit=-1")
+			if err != nil {
+				t.Fatal("error parse query", err)
+			}
+
+			if err = dto.DecodeQuery(values, new(dto.StoragesRequest)); err == nil {
+				t.Fatal("error invalid limit decoded")
+			}
+		},
+		"Encoded query is decoded back": func(t *testing.T) {
+			availableGt := int64(5)
+
+			request := &dto.StoragesRequest{
+				StoragesFilter: dto.StoragesFilter{NamePrefix: "main", AvailableGt: &availableGt},
+				Ids:            []string{"a", "b"},
+				Limit:          10,
+				WithTotal:      true,
+			}
+
+			values := dto.EncodeQuery(request)
+			if values.Has("cursor") || values.Has("updated_since") {
+				t.Fatal("error empty fields encoded", values)
+			}
+
+			decoded := new(dto.StoragesRequest)
+
+			if err := dto.DecodeQuery(values, decoded); err != nil {
+				t.Fatal("error decode query", err)
+			}
+
+			if len(decoded.Ids) != 2 || decoded.NamePrefix != "main" || *decoded.AvailableGt != 5 {
+				t.Fatal("error wrong filter", decoded)
+			}
+
+			if decoded.Limit != 10 || !decoded.WithTotal {
+				t.Fatal("error wrong pagination", decoded)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/ratelimit"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Log("Test: per client rate limit\n")
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Burst exhausted": func(t *testing.T) {
+			limiter := ratelimit.New(1, 2)
+
+			for i := 0; i < 2; i++ {
+				if ok, _ := limiter.Allow("client"); !ok {
+					t.Fatal("error request within burst rejected")
+				}
+			}
+
+			ok, retryAfter := limiter.Allow("client")
+			if ok || retryAfter <= 0 {
+				t.Fatal("error request over burst allowed", retryAfter)
+			}
+
+			if ok, _ := limiter.Allow("another client"); !ok {
+				t.Fatal("error another client request rejected")
+			}
+		},
+		"Disabled limit": func(t *testing.T) {
+			limiter := ratelimit.New(0, 0)
+
+			for i := 0; i < 100; i++ {
+				if ok, _ := limiter.Allow("client"); !ok {
+					t.Fatal("error request rejected by disabled limit")
+				}
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
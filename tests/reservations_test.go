@@ -1,13 +1,20 @@
 package tests
 
 import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/sqltools"
 	"cernunnos/internal/usecase/interactors"
 	"cernunnos/internal/usecase/repository"
+	"cernunnos/internal/usecase/repository/outbox"
 	"cernunnos/internal/usecase/repository/reservations"
+	"cernunnos/internal/usecase/repository/stock"
 	"context"
+	"errors"
 	"log/slog"
 	"math"
 	"math/rand"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
@@ -15,6 +22,32 @@ import (
 	"github.com/google/uuid"
 )
 
+// recordingOutbox records events added to outbox
+type recordingOutbox struct {
+	outbox.Repository
+
+	calls *[]string
+}
+
+func (r *recordingOutbox) Add(ctx context.Context, events ...*models.Event) error {
+	*r.calls = append(*r.calls, "add "+strconv.Itoa(len(events)))
+
+	return r.Repository.Add(ctx, events...)
+}
+
+// recordingStock records stock listeners notifications without sending them
+type recordingStock struct {
+	stock.Repository
+
+	calls *[]string
+}
+
+func (r *recordingStock) Notify(ctx context.Context, event *models.Event) error {
+	*r.calls = append(*r.calls, "notify "+event.Type)
+
+	return nil
+}
+
 func TestReservationsList(t *testing.T) {
 	db, cleanup, err := repository.ProvideDatabaseConnection(&cfg)
 	if err != nil {
@@ -23,7 +56,13 @@ func TestReservationsList(t *testing.T) {
 
 	defer cleanup()
 
-	reservationsInteractor := interactors.NewReservationInteractor(slog.Default(), reservations.NewRepository(db))
+	reservationsRepository := reservations.NewRepository(
+		db,
+		nil,
+		outbox.NewRepository(db),
+		stock.NewRepository(db, repository.DSN(&cfg, cfg.DatabaseHost)),
+	)
+	reservationsInteractor := interactors.NewReservationInteractor(slog.Default(), reservationsRepository)
 
 	t.Log("Test: reservations fetching\n")
 
@@ -104,13 +143,147 @@ func TestReservationsList(t *testing.T) {
 				t.Fatal("error fetch product reservation", err)
 			}
 
-			if len(reservations) == 0 {
+			if len(reservations.Items) == 0 {
 				t.Fatal("error empty product reservation", err)
 			}
 
-			if reservations[0].Reserved != reserve {
+			if reservations.Items[0].Reserved != reserve {
 				t.Fatal("error invalid product reservation reserved value", err)
 			}
+
+			var events int
+
+			err = db.QueryRowContext(
+				ctx,
+				"select count(*) from outbox where event_type = $1 and payload->>'shipping_id' = $2",
+				models.EventReservationCreated,
+				shippingId.String(),
+			).Scan(&events)
+			if err != nil {
+				t.Fatal("error fetch outbox events", err)
+			}
+
+			if events != 1 {
+				t.Fatal("error reservation event is not written to outbox", events)
+			}
+		},
+		"Units are counted on commit": func(t *testing.T) {
+			productId := uuid.New()
+
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			err = insertProducts(ctx, db, insertProductsParams{
+				storageId:   storageId,
+				productId:   productId,
+				productName: gofakeit.ProductName(),
+				size:        rand.Int63n(250),
+				amount:      amount,
+				reserved:    reserved,
+				available:   available,
+			})
+			if err != nil {
+				t.Fatal("error add product", err)
+			}
+
+			reserve := func(ctx context.Context) error {
+				return reservationsRepository.Reserve(ctx, reservations.ReserveParams{
+					ProductIds: uuid.UUIDs{productId},
+					StorageId:  storageId,
+					ShippingId: uuid.New(),
+					Amount:     2,
+				})
+			}
+
+			before := scrapeMetric(t, reservedSeries(storageId))
+
+			// The reservation joins an outer transaction, which rolls back
+			errRollback := errors.New("rollback")
+
+			err = sqltools.Transaction(ctx, db, func(ctx context.Context) error {
+				if err := reserve(ctx); err != nil {
+					return err
+				}
+
+				if counted := scrapeMetric(t, reservedSeries(storageId)); counted != before {
+					t.Fatal("error units counted before commit", counted-before)
+				}
+
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				t.Fatal("error reserve in outer transaction", err)
+			}
+
+			if counted := scrapeMetric(t, reservedSeries(storageId)); counted != before {
+				t.Fatal("error rolled back units counted", counted-before)
+			}
+
+			if err = reserve(ctx); err != nil {
+				t.Fatal("error reserve product", err)
+			}
+
+			if counted := scrapeMetric(t, reservedSeries(storageId)); counted != before+2 {
+				t.Fatal("error wrong counted units", counted-before)
+			}
+		},
+		"Events are added once per change": func(t *testing.T) {
+			productIds := uuid.UUIDs{uuid.New(), uuid.New()}
+
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			for _, productId := range productIds {
+				err = insertProducts(ctx, db, insertProductsParams{
+					storageId:   storageId,
+					productId:   productId,
+					productName: gofakeit.ProductName(),
+					size:        rand.Int63n(250),
+					amount:      amount,
+					reserved:    reserved,
+					available:   available,
+				})
+				if err != nil {
+					t.Fatal("error add product", err)
+				}
+			}
+
+			var calls []string
+
+			repository := reservations.NewRepository(
+				db, nil, &recordingOutbox{outbox.NewRepository(db), &calls}, &recordingStock{calls: &calls},
+			)
+
+			shippingId := uuid.New()
+
+			err = repository.Reserve(ctx, reservations.ReserveParams{
+				ProductIds: productIds,
+				StorageId:  storageId,
+				ShippingId: shippingId,
+				Amount:     1,
+			})
+			if err != nil {
+				t.Fatal("error reserve products", err)
+			}
+
+			// Reservation and level change events of both products, then listeners notified of levels
+			levelChanged := "notify " + models.EventStockLevelChanged
+			expected := []string{"add 4", levelChanged, levelChanged}
+
+			if !slices.Equal(calls, expected) {
+				t.Fatal("error wrong outbox calls", calls)
+			}
+
+			calls = nil
+
+			err = repository.Cancel(ctx, reservations.CancelParams{ProductIds: productIds, ShippingId: shippingId})
+			if err != nil {
+				t.Fatal("error cancel reservations", err)
+			}
+
+			if !slices.Equal(calls, expected) {
+				t.Fatal("error wrong outbox calls", calls)
+			}
 		},
 		"Not enough products case": func(t *testing.T) {
 			productId := uuid.New()
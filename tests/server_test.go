@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/config"
+	"cernunnos/internal/pkg/health"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/server"
+	"cernunnos/internal/server/interface/controllers"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// heldStorageInteractor holds storages requests until released
+type heldStorageInteractor struct {
+	interactors.StorageInteractor
+
+	started     chan struct{} // Closed when the first request is held
+	startedOnce sync.Once
+	release     chan struct{}
+}
+
+func (i *heldStorageInteractor) Storages(
+	ctx context.Context,
+	params interactors.StoragesParams,
+) (*models.Page[*models.Storage], error) {
+	i.startedOnce.Do(func() { close(i.started) })
+
+	<-i.release
+
+	return &models.Page[*models.Storage]{}, nil
+}
+
+// stoppedWorker runs until stopped and reports when it stops
+type stoppedWorker struct {
+	stopped chan struct{}
+}
+
+func (w *stoppedWorker) Run(ctx context.Context) error {
+	<-ctx.Done()
+	close(w.stopped)
+
+	return ctx.Err()
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	t.Log("Test: server shutdown\n")
+
+	// start serves HTTP API on a free port until ctx is cancelled. Returns the API URL and a channel
+	// receiving the Start result.
+	start := func(
+		t *testing.T,
+		ctx context.Context,
+		timeouts config.Timeouts,
+		storageInteractor interactors.StorageInteractor,
+		workers ...server.Worker,
+	) (string, <-chan error) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal("error find free port", err)
+		}
+
+		address := listener.Addr().String()
+		listener.Close()
+
+		cfg := config.Default()
+		cfg.Address = address
+		cfg.Timeouts = timeouts
+		log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		s := server.New(
+			&cfg,
+			log,
+			controllers.NewRootController(
+				nil,
+				nil,
+				controllers.NewStorageController(log, storageInteractor, presenters.NewStoragePresenter()),
+				nil,
+				nil,
+				nil,
+				nil,
+			),
+			health.NewChecker("test"),
+			nil,
+			nil,
+			workers,
+		)
+
+		done := make(chan error, 1)
+
+		go func() { done <- s.Start(ctx) }()
+
+		for deadline := time.Now().Add(time.Second); ; {
+			conn, err := net.Dial("tcp", address)
+			if err == nil {
+				conn.Close()
+
+				break
+			}
+
+			if time.Now().After(deadline) {
+				t.Fatal("error server is not started", err)
+			}
+
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		return "http://" + address, done
+	}
+
+	timeouts := config.Default().Timeouts
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"In-flight request is drained before workers stop": func(t *testing.T) {
+			interactor := &heldStorageInteractor{started: make(chan struct{}), release: make(chan struct{})}
+			worker := &stoppedWorker{stopped: make(chan struct{})}
+
+			ctx, shutdown := context.WithCancel(context.Background())
+			defer shutdown()
+
+			url, done := start(t, ctx, timeouts, interactor, worker)
+
+			codes := make(chan int, 1)
+
+			go func() {
+				response, err := http.Get(url + "/storages")
+				if err != nil {
+					codes <- 0
+
+					return
+				}
+
+				response.Body.Close()
+				codes <- response.StatusCode
+			}()
+
+			<-interactor.started
+			shutdown()
+
+			select {
+			case <-worker.stopped:
+				t.Fatal("error worker stopped before in-flight request is handled")
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			close(interactor.release)
+
+			if code := <-codes; code != http.StatusOK {
+				t.Fatal("error in-flight request is not handled", code)
+			}
+
+			if err := <-done; err != nil {
+				t.Fatal("error shut down", err)
+			}
+
+			select {
+			case <-worker.stopped:
+			default:
+				t.Fatal("error worker is not stopped")
+			}
+		},
+		"Draining is limited with shutdown timeout": func(t *testing.T) {
+			interactor := &heldStorageInteractor{started: make(chan struct{}), release: make(chan struct{})}
+			defer close(interactor.release)
+
+			ctx, shutdown := context.WithCancel(context.Background())
+			defer shutdown()
+
+			short := timeouts
+			short.Shutdown = 50 * time.Millisecond
+
+			url, done := start(t, ctx, short, interactor)
+
+			go func() {
+				if response, err := http.Get(url + "/storages"); err == nil {
+					response.Body.Close()
+				}
+			}()
+
+			<-interactor.started
+			shutdown()
+
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Fatal("error held request drained")
+				}
+			case <-time.After(time.Second):
+				t.Fatal("error shutdown exceeds timeout")
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
@@ -0,0 +1,360 @@
+package tests
+
+import (
+	"cernunnos/internal/middleware"
+	errs "cernunnos/internal/pkg/errors"
+	"cernunnos/internal/pkg/metrics"
+	"cernunnos/internal/pkg/sqltools"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	sq "github.com/Masterminds/squirrel"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Rows returned and affected by statements of stub databases
+const stubRowsCount = 3
+
+var errorStubQuery = errors.New("stub query failed")
+
+func init() {
+	sql.Register("stub", stubDriver{})
+}
+
+// stubDriver serves statements without a database. Selects return stubRowsCount rows with the
+// name of the database, so tests tell which database served a query. Statements mentioning
+// "fail" fail.
+type stubDriver struct{}
+
+func (stubDriver) Open(name string) (driver.Conn, error) {
+	return &stubConn{name: name}, nil
+}
+
+type stubConn struct {
+	name string
+}
+
+func (c *stubConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *stubConn) Close() error {
+	return nil
+}
+
+func (c *stubConn) Begin() (driver.Tx, error) {
+	return stubTx{}, nil
+}
+
+func (c *stubConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return stubTx{}, nil
+}
+
+func (c *stubConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errorStubQuery
+	}
+
+	return &stubRows{name: c.name, left: stubRowsCount}, nil
+}
+
+func (c *stubConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "fail") {
+		return nil, errorStubQuery
+	}
+
+	return driver.RowsAffected(stubRowsCount), nil
+}
+
+type stubTx struct{}
+
+func (stubTx) Commit() error {
+	return nil
+}
+
+func (stubTx) Rollback() error {
+	return nil
+}
+
+type stubRows struct {
+	name string
+	left int
+}
+
+func (r *stubRows) Columns() []string {
+	return []string{"db"}
+}
+
+func (r *stubRows) Close() error {
+	return nil
+}
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+
+	r.left--
+	dest[0] = r.name
+
+	return nil
+}
+
+// openStubDB opens a stub database named name
+func openStubDB(t *testing.T, name string) *sql.DB {
+	db, err := sql.Open("stub", name)
+	if err != nil {
+		t.Fatal("error open stub database", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// recordSpans installs a tracer provider recording ended spans until the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+
+	return recorder
+}
+
+// spanAttribute finds an attribute of an ended span
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestQueryTracing(t *testing.T) {
+	t.Log("Test: query spans\n")
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Select span ends when rows are closed": func(t *testing.T) {
+			recorder := recordSpans(t)
+			db := openStubDB(t, "primary")
+
+			query := sq.Select("id").From("products").Where(sq.Eq{"id": 1}).PlaceholderFormat(sq.Dollar)
+
+			rows, err := sqltools.Query(context.Background(), db, query)
+			if err != nil {
+				t.Fatal("error query", err)
+			}
+
+			for rows.Next() {
+				var name string
+
+				if err = rows.Scan(&name); err != nil {
+					t.Fatal("error scan row", err)
+				}
+			}
+
+			if len(recorder.Ended()) != 0 {
+				t.Fatal("error span ended before rows are closed")
+			}
+
+			if err = rows.Close(); err != nil {
+				t.Fatal("error close rows", err)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 || spans[0].Name() != "sql SELECT" {
+				t.Fatal("error wrong spans", spans)
+			}
+
+			statement, _ := spanAttribute(spans[0], "db.statement")
+			returned, _ := spanAttribute(spans[0], "db.rows_returned")
+
+			if statement.AsString() != "SELECT id FROM products WHERE id = $1" || returned.AsInt64() != stubRowsCount {
+				t.Fatal("error wrong span attributes", spans[0].Attributes())
+			}
+		},
+		"Exec span counts affected rows": func(t *testing.T) {
+			recorder := recordSpans(t)
+			db := openStubDB(t, "primary")
+
+			query := sq.Update("products").Set("size", 1).PlaceholderFormat(sq.Dollar)
+
+			if _, err := sqltools.Exec(context.Background(), db, query); err != nil {
+				t.Fatal("error exec", err)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 || spans[0].Name() != "sql UPDATE" {
+				t.Fatal("error wrong spans", spans)
+			}
+
+			operation, _ := spanAttribute(spans[0], "db.operation")
+			affected, _ := spanAttribute(spans[0], "db.rows_affected")
+
+			if operation.AsString() != "UPDATE" || affected.AsInt64() != stubRowsCount {
+				t.Fatal("error wrong span attributes", spans[0].Attributes())
+			}
+		},
+		"Failed query span records error": func(t *testing.T) {
+			recorder := recordSpans(t)
+			db := openStubDB(t, "primary")
+
+			var name string
+
+			err := sqltools.QueryRow(context.Background(), db, sq.Select("fail"), &name)
+			if !errors.Is(err, errorStubQuery) {
+				t.Fatal("error query failure expected", err)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 1 || spans[0].Status().Code != codes.Error || len(spans[0].Events()) == 0 {
+				t.Fatal("error failure is not recorded", spans)
+			}
+		},
+		"Query spans are children of transaction span": func(t *testing.T) {
+			recorder := recordSpans(t)
+			db := openStubDB(t, "primary")
+
+			err := sqltools.Transaction(context.Background(), db, func(ctx context.Context) error {
+				var name string
+
+				return sqltools.QueryRow(ctx, sqltools.Conn(ctx, db), sq.Select("id").From("products"), &name)
+			})
+			if err != nil {
+				t.Fatal("error run transaction", err)
+			}
+
+			spans := recorder.Ended()
+			if len(spans) != 2 || spans[0].Name() != "sql SELECT" || spans[1].Name() != "sql transaction" {
+				t.Fatal("error wrong spans", spans)
+			}
+
+			if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+				t.Fatal("error query span is not a child of transaction span")
+			}
+
+			outcome, _ := spanAttribute(spans[1], "db.transaction.outcome")
+			if outcome.AsString() != metrics.TransactionCommitted {
+				t.Fatal("error wrong transaction outcome", outcome.AsString())
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
+
+func TestReadTransaction(t *testing.T) {
+	t.Log("Test: read transaction routing\n")
+
+	primary := openStubDB(t, "primary")
+	replica := openStubDB(t, "replica")
+
+	// servedBy runs a read transaction and returns the name of the database serving it
+	servedBy := func(t *testing.T, ctx context.Context, replica *sql.DB) (name string, onReplica bool) {
+		err := sqltools.ReadTransaction(ctx, primary, replica, func(ctx context.Context) error {
+			onReplica = sqltools.OnReplica(ctx)
+
+			return sqltools.QueryRow(ctx, sqltools.Conn(ctx, primary), sq.Select("id").From("products"), &name)
+		})
+		if err != nil {
+			t.Fatal("error run read transaction", err)
+		}
+
+		return name, onReplica
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Replica by default": func(t *testing.T) {
+			recorder := recordSpans(t)
+
+			if name, onReplica := servedBy(t, context.Background(), replica); name != "replica" || !onReplica {
+				t.Fatal("error read is not routed to replica", name, onReplica)
+			}
+
+			spans := recorder.Ended()
+			attr, _ := spanAttribute(spans[len(spans)-1], "db.replica")
+
+			if !attr.AsBool() {
+				t.Fatal("error transaction span is not marked as replica")
+			}
+		},
+		"Primary required": func(t *testing.T) {
+			name, onReplica := servedBy(t, sqltools.WithPrimary(context.Background()), replica)
+			if name != "primary" || onReplica {
+				t.Fatal("error read is not routed to primary", name, onReplica)
+			}
+		},
+		"No replica": func(t *testing.T) {
+			if name, onReplica := servedBy(t, context.Background(), nil); name != "primary" || onReplica {
+				t.Fatal("error read is not routed to primary", name, onReplica)
+			}
+		},
+		"External transaction is joined": func(t *testing.T) {
+			err := sqltools.Transaction(context.Background(), primary, func(ctx context.Context) error {
+				if name, onReplica := servedBy(t, ctx, replica); name != "primary" || onReplica {
+					t.Fatal("error read left external transaction", name, onReplica)
+				}
+
+				return nil
+			})
+			if err != nil {
+				t.Fatal("error run transaction", err)
+			}
+		},
+		"Strong consistency header": func(t *testing.T) {
+			builder := middleware.NewMiddlewareBuilder(
+				slog.New(slog.NewTextHandler(io.Discard, nil)), errs.NewErrorHandler(), nil, nil, 0,
+			)
+
+			var served string
+
+			handler := builder.Consistency(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				served, _ = servedBy(t, r.Context(), replica)
+			}))
+
+			headers := map[string]string{"": "replica", "eventual": "replica", "strong": "primary", "Strong": "primary"}
+
+			for header, expected := range headers {
+				request := httptest.NewRequest(http.MethodGet, "/products", nil)
+				request.Header.Set(middleware.ConsistencyHeader, header)
+
+				handler.ServeHTTP(httptest.NewRecorder(), request)
+
+				if served != expected {
+					t.Fatal("error wrong database for consistency", header, served)
+				}
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/dto"
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/server/interface/presenters"
+	"cernunnos/internal/usecase/interactors"
+	outboxRepo "cernunnos/internal/usecase/repository/outbox"
+	"cernunnos/internal/usecase/workers"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// eventsRepository serves stock level changed events it holds
+type eventsRepository struct {
+	outboxRepo.Repository
+
+	events []*models.Event
+}
+
+func (r *eventsRepository) Events(ctx context.Context, params outboxRepo.EventsParams) ([]*models.Event, error) {
+	var events []*models.Event
+
+	for _, event := range r.events {
+		if event.Position > params.After && uint64(len(events)) < params.Limit {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// notificationsRepository passes scripted notifications to the listener. nil is a reconnection
+type notificationsRepository struct {
+	notifications []*models.Event
+}
+
+func (r *notificationsRepository) Notify(ctx context.Context, event *models.Event) error {
+	return nil
+}
+
+func (r *notificationsRepository) Listen(
+	ctx context.Context,
+	handle func(ctx context.Context, event *models.Event),
+) error {
+	for _, event := range r.notifications {
+		handle(ctx, event)
+	}
+
+	return nil
+}
+
+func TestStockStream(t *testing.T) {
+	t.Log("Test: stock changes stream\n")
+
+	productId := uuid.New()
+	storageId := uuid.New()
+
+	levelChanged := func(t *testing.T, position int64, storageId uuid.UUID) *models.Event {
+		event, err := models.NewEvent(models.EventStockLevelChanged, dto.StockLevelEvent{
+			ProductId: productId.String(),
+			StorageId: storageId.String(),
+			Amount:    10,
+			Reserved:  position,
+			Available: 10 - position,
+		})
+		if err != nil {
+			t.Fatal("error create event", err)
+		}
+
+		event.Position = position
+
+		return event
+	}
+
+	receive := func(t *testing.T, changes <-chan *models.StockChange) *models.StockChange {
+		select {
+		case change := <-changes:
+			return change
+		case <-time.After(time.Second):
+			t.Fatal("error no stock change received")
+		}
+
+		return nil
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Live changes of the storage": func(t *testing.T) {
+			interactor := interactors.NewStockInteractor(slog.Default(), &eventsRepository{})
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			changes, err := interactor.Changes(ctx, interactors.StockChangesParams{StorageId: storageId.String()})
+			if err != nil {
+				t.Fatal("error subscribe to stock changes", err)
+			}
+
+			for _, event := range []*models.Event{levelChanged(t, 1, uuid.New()), levelChanged(t, 2, storageId)} {
+				if err = interactor.Broadcast(ctx, event); err != nil {
+					t.Fatal("error broadcast stock change", err)
+				}
+			}
+
+			if change := receive(t, changes); change.Position != 2 || change.StorageId != storageId {
+				t.Fatal("error wrong stock change received", change)
+			}
+
+			cancel()
+
+			if _, ok := <-changes; ok {
+				t.Fatal("error stream is not closed when ctx is done")
+			}
+		},
+		"Resume after last event id": func(t *testing.T) {
+			missed := []*models.Event{levelChanged(t, 1, storageId), levelChanged(t, 2, storageId)}
+			interactor := interactors.NewStockInteractor(slog.Default(), &eventsRepository{events: missed})
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			changes, err := interactor.Changes(ctx, interactors.StockChangesParams{LastEventId: "1"})
+			if err != nil {
+				t.Fatal("error subscribe to stock changes", err)
+			}
+
+			if change := receive(t, changes); change.Position != 2 {
+				t.Fatal("error missed stock change is not resumed", change.Position)
+			}
+
+			// Committed while resuming, so broadcast after it was resumed
+			if err = interactor.Broadcast(ctx, missed[1]); err != nil {
+				t.Fatal("error broadcast stock change", err)
+			}
+
+			if err = interactor.Broadcast(ctx, levelChanged(t, 3, storageId)); err != nil {
+				t.Fatal("error broadcast stock change", err)
+			}
+
+			if change := receive(t, changes); change.Position != 3 {
+				t.Fatal("error resumed stock change is repeated", change.Position)
+			}
+		},
+		"Listener resyncs missed changes once": func(t *testing.T) {
+			events := make([]*models.Event, 0, 4)
+			for position := range int64(4) {
+				events = append(events, levelChanged(t, position+1, storageId))
+			}
+
+			outbox := &eventsRepository{events: events}
+			interactor := interactors.NewStockInteractor(slog.Default(), outbox)
+
+			ctx, cancel := context.WithCancel(context.TODO())
+			defer cancel()
+
+			changes, err := interactor.Changes(ctx, interactors.StockChangesParams{})
+			if err != nil {
+				t.Fatal("error subscribe to stock changes", err)
+			}
+
+			// 2 and 3 are committed while the connection is lost, 3 is notified after reconnection
+			listener := workers.NewStockListener(slog.Default(), &notificationsRepository{
+				notifications: []*models.Event{nil, events[0], nil, events[2], events[3]},
+			}, outbox, interactor)
+
+			if err = listener.Run(ctx); err != nil {
+				t.Fatal("error run listener", err)
+			}
+
+			for position := range int64(4) {
+				if change := receive(t, changes); change.Position != position+1 {
+					t.Fatal("error wrong stock change", position+1, change.Position)
+				}
+			}
+
+			select {
+			case change := <-changes:
+				t.Fatal("error stock change repeated", change.Position)
+			case <-time.After(50 * time.Millisecond):
+			}
+		},
+		"Invalid last event id": func(t *testing.T) {
+			interactor := interactors.NewStockInteractor(slog.Default(), &eventsRepository{})
+
+			_, err := interactor.Changes(context.TODO(), interactors.StockChangesParams{LastEventId: "abc"})
+			if !errors.Is(err, interactors.ErrorInvalidField) {
+				t.Fatal("error invalid last event id is accepted", err)
+			}
+		},
+		"Server-Sent Event format": func(t *testing.T) {
+			event, err := presenters.NewStockPresenter().ResponseStockChange(&models.StockChange{
+				Position:  42,
+				ProductId: productId,
+				StorageId: storageId,
+				ChangedAt: time.Now(),
+			})
+			if err != nil {
+				t.Fatal("error build stock change event", err)
+			}
+
+			frame := string(event)
+
+			if !strings.HasPrefix(frame, "id: 42\nevent: stock\ndata: {") || !strings.HasSuffix(frame, "}\n\n") {
+				t.Fatal("error malformed stock change event", frame)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
@@ -0,0 +1,267 @@
+package tests
+
+import (
+	"cernunnos/internal/pkg/models"
+	"cernunnos/internal/pkg/publisher"
+	"cernunnos/internal/pkg/sqltools"
+	"cernunnos/internal/usecase/repository"
+	webhooksRepo "cernunnos/internal/usecase/repository/webhooks"
+	"cernunnos/internal/usecase/workers"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// deliveriesRepository attempts deliveries it holds, while they are pending
+type deliveriesRepository struct {
+	webhooksRepo.Repository
+
+	deliveries []*models.WebhookDelivery
+}
+
+func (r *deliveriesRepository) Attempt(
+	ctx context.Context,
+	limit uint64,
+	attempt func(ctx context.Context, delivery *models.WebhookDelivery) error,
+) (int, error) {
+	var attempted int
+
+	for _, delivery := range r.deliveries {
+		if delivery.Status != models.DeliveryPending || attempted == int(limit) {
+			continue
+		}
+
+		if err := attempt(ctx, delivery); err != nil {
+			return attempted, err
+		}
+
+		attempted++
+	}
+
+	return attempted, nil
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Log("Test: webhook deliveries\n")
+
+	body := []byte(`{"id":"event"}`)
+
+	newDelivery := func(url string, attempts int) *models.WebhookDelivery {
+		return &models.WebhookDelivery{
+			Id:             uuid.New(),
+			SubscriptionId: uuid.New(),
+			URL:            url,
+			Secret:         "whsec_test",
+			EventId:        uuid.New(),
+			EventType:      models.EventStockDepleted,
+			Payload:        body,
+			Status:         models.DeliveryPending,
+			Attempts:       attempts,
+		}
+	}
+
+	send := func(t *testing.T, deliveries ...*models.WebhookDelivery) {
+		sender := workers.NewWebhookSender(
+			slog.Default(),
+			&deliveriesRepository{deliveries: deliveries},
+			&http.Client{Timeout: time.Second},
+			workers.WebhookSenderOptions{
+				PollInterval: time.Hour,
+				BatchSize:    10,
+				MaxAttempts:  3,
+				BackoffBase:  time.Second,
+				BackoffMax:   time.Minute,
+			},
+		)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), 500*time.Millisecond)
+		defer cancel()
+
+		if err := sender.Run(ctx); err != nil {
+			t.Fatal("error run webhook sender", err)
+		}
+	}
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Signature verification": func(t *testing.T) {
+			signature := publisher.Sign("secret", 1700000000, body)
+
+			if !publisher.Verify("secret", 1700000000, body, signature) {
+				t.Fatal("error valid signature is rejected", signature)
+			}
+
+			if publisher.Verify("other", 1700000000, body, signature) {
+				t.Fatal("error signature with another secret is accepted")
+			}
+
+			if publisher.Verify("secret", 1700000001, body, signature) {
+				t.Fatal("error signature with another timestamp is accepted")
+			}
+		},
+		"Exponential backoff": func(t *testing.T) {
+			expected := map[int]time.Duration{
+				1:  10 * time.Second,
+				2:  20 * time.Second,
+				4:  80 * time.Second,
+				20: time.Hour,
+			}
+
+			for attempts, delay := range expected {
+				if got := workers.Backoff(attempts, 10*time.Second, time.Hour); got != delay {
+					t.Fatal("error wrong backoff after", attempts, "attempts", got)
+				}
+			}
+		},
+		"Signed delivery": func(t *testing.T) {
+			var verified bool
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received, _ := io.ReadAll(r.Body)
+				timestamp, _ := strconv.ParseInt(r.Header.Get(publisher.TimestampHeader), 10, 64)
+
+				verified = publisher.Verify("whsec_test", timestamp, received, r.Header.Get(publisher.SignatureHeader))
+			}))
+			defer server.Close()
+
+			delivery := newDelivery(server.URL, 0)
+			send(t, delivery)
+
+			if !verified {
+				t.Fatal("error delivery signature is not verified")
+			}
+
+			if delivery.Status != models.DeliveryDelivered || delivery.DeliveredAt == nil {
+				t.Fatal("error delivery is not marked delivered", delivery.Status)
+			}
+		},
+		"Failed delivery retry and dead letter": func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			retried := newDelivery(server.URL, 0)
+			dead := newDelivery(server.URL, 2)
+
+			send(t, retried, dead)
+
+			if retried.Status != models.DeliveryPending || retried.Attempts != 1 {
+				t.Fatal("error failed delivery is not retried", retried.Status, retried.Attempts)
+			}
+
+			if retried.NextAttemptAt.Before(time.Now()) || retried.LastStatusCode != http.StatusServiceUnavailable {
+				t.Fatal("error failed delivery retry is not delayed", retried.NextAttemptAt)
+			}
+
+			if dead.Status != models.DeliveryDead || dead.Attempts != 3 {
+				t.Fatal("error exhausted delivery is not dead", dead.Status, dead.Attempts)
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}
+
+func TestWebhookDeliveriesList(t *testing.T) {
+	db, cleanup, err := repository.ProvideDatabaseConnection(&cfg)
+	if err != nil {
+		t.Fatal("error connect to database", err)
+	}
+
+	defer cleanup()
+
+	webhooks := webhooksRepo.NewRepository(db)
+
+	t.Log("Test: webhook deliveries fetching\n")
+
+	var cases map[string]Testcase = map[string]Testcase{
+		"Pages walk all deliveries": func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+			defer cancel()
+
+			productId := uuid.New()
+
+			subscription, err := webhooks.Create(ctx, webhooksRepo.CreateParams{
+				URL:       "http://localhost/hook",
+				Secret:    "secret",
+				ProductId: productId,
+			})
+			if err != nil {
+				t.Fatal("error create subscription", err)
+			}
+
+			defer func() {
+				if err := webhooks.Delete(context.Background(), subscription.Id); err != nil {
+					t.Error("error delete subscription", err)
+				}
+			}()
+
+			enqueued := make([]uuid.UUID, 5)
+
+			for i := range enqueued {
+				enqueued[i] = uuid.New()
+
+				_, err = webhooks.Enqueue(ctx, webhooksRepo.EnqueueParams{
+					EventId:   enqueued[i],
+					EventType: "stock.changed",
+					ProductId: productId,
+					Payload:   []byte(`{}`),
+				})
+				if err != nil {
+					t.Fatal("error enqueue delivery", err)
+				}
+			}
+
+			for _, limit := range []uint64{1, 2} {
+				var (
+					events []uuid.UUID
+					cursor *sqltools.Cursor
+				)
+
+				for pages := 0; pages <= len(enqueued); pages++ {
+					page, err := webhooks.Deliveries(ctx, webhooksRepo.DeliveriesParams{
+						SubscriptionId: subscription.Id,
+						Pagination:     sqltools.Pagination{Cursor: cursor, Limit: limit},
+					})
+					if err != nil {
+						t.Fatal("error fetch deliveries", err)
+					}
+
+					for _, delivery := range page.Items {
+						events = append(events, delivery.EventId)
+					}
+
+					if page.NextCursor == "" {
+						break
+					}
+
+					if cursor, err = sqltools.ParseCursor(page.NextCursor); err != nil {
+						t.Fatal("error parse cursor", err)
+					}
+				}
+
+				if !slices.Equal(events, enqueued) {
+					t.Fatal("error wrong deliveries with limit", limit, events)
+				}
+			}
+		},
+	}
+
+	for desc, test := range cases {
+		t.Log(desc + "\n")
+
+		test(t)
+	}
+}